@@ -0,0 +1,82 @@
+package main
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/mail"
+	"context"
+	"log"
+	"time"
+)
+
+// alertSubjects holds the subject of the mail of each alert stage
+var alertSubjects = map[string]string{
+	database.AlertExpiring: "Expiring Soon | Cat Clerk",
+	database.AlertExpired:  "Expired | Cat Clerk",
+	database.AlertLowStock: "Running Low | Cat Clerk",
+}
+
+// raiseAlerts raises, every interval until ctx is done, the alerts of the storage items expiring soon or expired,
+// then mails the alerts not delivered yet, the low stock ones raised by the writes included
+func raiseAlerts(ctx context.Context, store database.Store, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		raised, err := store.RaiseExpirationAlerts(ctx, time.Now().UTC())
+		if err != nil {
+			log.Printf("expiration alerts failed: %v", err)
+		} else if raised > 0 {
+			log.Printf("expiration alerts raised %d alerts", raised)
+		}
+
+		deliverAlerts(ctx, store)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverAlerts mails the alerts not delivered yet, leaving those it fails to send to the next call
+func deliverAlerts(ctx context.Context, store database.Store) {
+	if mail.GmailService == nil {
+		return
+	}
+
+	after := 0
+	for {
+		deliveries, err := store.GetPendingAlertDeliveries(ctx, after, 100)
+		if err != nil {
+			log.Printf("alert delivery failed: %v", err)
+			return
+		}
+
+		if len(deliveries) == 0 {
+			return
+		}
+
+		for _, delivery := range deliveries {
+			after = delivery.ID
+
+			if err := mail.SendEmailOAUTH2(
+				delivery.Email,
+				alertSubjects[delivery.Stage],
+				delivery,
+				"item-alert.gohtml",
+			); err != nil {
+				log.Printf("alert delivery %d to %s failed: %v", delivery.ID, delivery.Username, err)
+				continue
+			}
+
+			if err := store.MarkAlertDelivered(ctx, delivery.ID); err != nil {
+				log.Printf("alert delivery %d to %s failed: %v", delivery.ID, delivery.Username, err)
+			}
+		}
+	}
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"cat-clerk-api/auth"
+	"cat-clerk-api/database"
 	"cat-clerk-api/util"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -45,19 +47,19 @@ func (api *API) createAccount(w http.ResponseWriter, r *http.Request) {
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password+salt), bcrypt.DefaultCost)
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
-	result, err := api.DB.CreateAccount(request.Username, request.Email, string(hashedPassword), salt)
+	result, err := api.DB.CreateAccount(r.Context(), request.Username, request.Email, string(hashedPassword), salt)
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
 	rowsAff, err := result.RowsAffected()
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -90,9 +92,13 @@ func (api *API) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	account, err := api.DB.CheckAccountCredentials(request.Username, request.Email)
+	account, err := api.DB.CheckAccountCredentials(r.Context(), request.Username, request.Email)
 	if err != nil {
-		util.WriteJSON(util.Error("wrong login or password"), http.StatusUnauthorized, w)
+		if errors.Is(err, database.ErrNotFound) {
+			util.WriteJSON(util.Error("wrong login or password"), http.StatusUnauthorized, w)
+			return
+		}
+		writeError(err, w, r)
 		return
 	}
 
@@ -147,13 +153,9 @@ func (api *API) login(w http.ResponseWriter, r *http.Request) {
 func (api *API) getAccount(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetAccount(username)
+	payload, err := api.DB.GetAccount(r.Context(), username)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -163,13 +165,9 @@ func (api *API) getAccount(w http.ResponseWriter, r *http.Request) {
 func (api *API) getAccountEmail(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetAccountEmail(username)
+	payload, err := api.DB.GetAccountEmail(r.Context(), username)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -191,13 +189,9 @@ func (api *API) emailExists(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := api.DB.EmailExists(request.Email)
+	_, err := api.DB.EmailExists(r.Context(), request.Email)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -238,9 +232,9 @@ func (api *API) updateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accounts, err := api.DB.GetAccounts()
+	accounts, err := api.DB.GetAccounts(r.Context())
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -255,6 +249,7 @@ func (api *API) updateAccount(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if err := api.DB.UpdateAccount(
+		r.Context(),
 		username,
 		request.Username,
 		request.Password,
@@ -262,7 +257,7 @@ func (api *API) updateAccount(w http.ResponseWriter, r *http.Request) {
 		request.DarkTheme,
 		request.Notifications,
 	); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -279,9 +274,9 @@ func (api *API) updateAccountEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accounts, err := api.DB.GetAccounts()
+	accounts, err := api.DB.GetAccounts(r.Context())
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -293,10 +288,11 @@ func (api *API) updateAccountEmail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := api.DB.UpdateAccountEmail(
+		r.Context(),
 		username,
 		email,
 	); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -309,9 +305,9 @@ func (api *API) updateAccountUsername(w http.ResponseWriter, r *http.Request) {
 	username := vars["username"]
 	newUsername := vars["new_username"]
 
-	accounts, err := api.DB.GetAccounts()
+	accounts, err := api.DB.GetAccounts(r.Context())
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -323,10 +319,11 @@ func (api *API) updateAccountUsername(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := api.DB.UpdateAccountUsername(
+		r.Context(),
 		username,
 		newUsername,
 	); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -359,16 +356,17 @@ func (api *API) updateAccountPassword(w http.ResponseWriter, r *http.Request) {
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password+salt), bcrypt.DefaultCost)
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
 	if err := api.DB.UpdateAccountPassword(
+		r.Context(),
 		username,
 		string(hashedPassword),
 		salt,
 	); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -378,12 +376,8 @@ func (api *API) updateAccountPassword(w http.ResponseWriter, r *http.Request) {
 func (api *API) deleteAccount(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	if err := api.DB.DeleteAccount(username); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	if err := api.DB.DeleteAccount(r.Context(), username); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
@@ -3,6 +3,7 @@ package api
 import (
 	"cat-clerk-api/auth"
 	"cat-clerk-api/database"
+	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -13,12 +14,14 @@ const path = "/api/v1/"
 // API structure
 type API struct {
 	Router *mux.Router
-	DB     *database.Handler
+	DB     database.Store
 	Auth   *auth.Auth
+	// Timeouts bounds the time given to each request, zero for no bound
+	Timeouts Timeouts
 }
 
 // Init initializes the API package dependencies.
-func Init(router *mux.Router, db *database.Handler, auth *auth.Auth) *API {
+func Init(router *mux.Router, db database.Store, auth *auth.Auth) *API {
 	return &API{
 		Router: router,
 		DB:     db,
@@ -28,205 +31,383 @@ func Init(router *mux.Router, db *database.Handler, auth *auth.Auth) *API {
 
 // Handlers initializes all API handlers.
 func (api *API) Handlers() *mux.Router {
+	api.Router.Use(api.timeout, api.actor)
+
 	api.Router.Methods(http.MethodGet).
 		Path(path + "ping").
+		Name("ping").
 		Handler(http.HandlerFunc(api.ping))
 
+	api.Router.Methods(http.MethodGet).
+		Path(path + "health").
+		Name("health").
+		Handler(http.HandlerFunc(api.health))
+
+	api.Router.Methods(http.MethodGet).
+		Path(path + "metrics").
+		Name("metrics").
+		Handler(http.HandlerFunc(api.metrics))
+
 	api.Router.Methods(http.MethodPost).
 		Path(path + "login").
+		Name("login").
 		Handler(http.HandlerFunc(api.login))
 
 	api.Router.Methods(http.MethodPost).
 		Path(path + "sign-up").
+		Name("createAccount").
 		Handler(http.HandlerFunc(api.createAccount))
 
 	api.Router.Methods(http.MethodPost).
 		Path(path + "forgotten-password/{email}").
+		Name("sendForgottenPasswordMail").
 		Handler(http.HandlerFunc(api.sendForgottenPasswordMail))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/foods").
+		Name("getFoods").
 		Handler(http.HandlerFunc(api.getFoods))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}").
+		Name("getAccount").
 		Handler(http.HandlerFunc(api.getAccount))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/email").
+		Name("getAccountEmail").
 		Handler(http.HandlerFunc(api.getAccountEmail))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/email/{email}").
+		Name("updateAccountEmail").
 		Handler(http.HandlerFunc(api.updateAccountEmail))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/username/{new_username}").
+		Name("updateAccountUsername").
 		Handler(http.HandlerFunc(api.updateAccountUsername))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/password").
+		Name("updateAccountPassword").
 		Handler(http.HandlerFunc(api.updateAccountPassword))
 
 	api.Router.Methods(http.MethodPut).
 		Path(path + "accounts/{username}").
+		Name("updateAccount").
 		Handler(http.HandlerFunc(api.updateAccount))
 
 	api.Router.Methods(http.MethodDelete).
 		Path(path + "accounts/{username}").
+		Name("deleteAccount").
 		Handler(http.HandlerFunc(api.deleteAccount))
 
 	api.Router.Methods(http.MethodPost).
 		Path(path + "accounts/{username}/share_requests").
+		Name("createShareRequest").
 		Handler(http.HandlerFunc(api.createShareRequest))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/share_requests").
+		Name("getShareRequests").
 		Handler(http.HandlerFunc(api.getShareRequests))
 
 	api.Router.Methods(http.MethodDelete).
 		Path(path + "accounts/{username}/share_requests/{share_id}").
+		Name("deleteShareRequest").
 		Handler(http.HandlerFunc(api.deleteShareRequest))
 
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/share_requests/{share_id}/accept").
+		Name("acceptShareRequest").
+		Handler(http.HandlerFunc(api.acceptShareRequest))
+
 	api.Router.Methods(http.MethodPost).
 		Path(path + "accounts/{username}/storages").
+		Name("createStorage").
 		Handler(http.HandlerFunc(api.createStorage))
 
 	api.Router.Methods(http.MethodPost).
 		Path(path + "accounts/{username}/storages/{storage_id}/share/{username_request}").
+		Name("shareStorage").
 		Handler(http.HandlerFunc(api.shareStorage))
 
 	api.Router.Methods(http.MethodDelete).
 		Path(path + "accounts/{username}/storages/{storage_id}/share/{username_request}").
+		Name("removeShareStorageFolder").
 		Handler(http.HandlerFunc(api.removeShareStorageFolder))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/storages/{storage_id}/owner/{owner}").
+		Name("getStorageOwner").
 		Handler(http.HandlerFunc(api.getStorageOwner))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/storages").
+		Name("getStorages").
 		Handler(http.HandlerFunc(api.getStorages))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/storages/count").
+		Name("getStoragesCount").
 		Handler(http.HandlerFunc(api.getStoragesCount))
 
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/storages/{storage_id:[0-9]+}").
+		Name("getStorage").
+		Handler(http.HandlerFunc(api.getStorage))
+
 	api.Router.Methods(http.MethodPut).
 		Path(path + "accounts/{username}/storages/{storage_id}").
+		Name("updateStorage").
 		Handler(http.HandlerFunc(api.updateStorage))
 
 	api.Router.Methods(http.MethodDelete).
 		Path(path + "accounts/{username}/storages/{storage_id}").
+		Name("deleteStorage").
 		Handler(http.HandlerFunc(api.deleteStorage))
 
 	api.Router.Methods(http.MethodPost).
 		Path(path + "accounts/{username}/storages/{storage_id}/items").
+		Name("createStorageItem").
 		Handler(http.HandlerFunc(api.createStorageItem))
 
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/storages/{storage_id}/items/batch").
+		Name("batchStorageItems").
+		Handler(http.HandlerFunc(api.batchStorageItems))
+
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/storages/{storage_id}/items/csv").
+		Name("exportStorageItemsCSV").
+		Handler(http.HandlerFunc(api.exportStorageItemsCSV))
+
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/storages/{storage_id}/items/csv").
+		Name("importStorageItemsCSV").
+		Handler(http.HandlerFunc(api.importStorageItemsCSV))
+
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/storages/{storage_id}/items").
+		Name("getStorageItems").
 		Handler(http.HandlerFunc(api.getStorageItems))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/storages/{storage_id}/items/{item_id}").
+		Name("getStorageItem").
 		Handler(http.HandlerFunc(api.getStorageItem))
 
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/storages/{storage_id}/items/{item_id}/history").
+		Name("getStorageItemHistory").
+		Handler(http.HandlerFunc(api.getStorageItemHistory))
+
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/storages/items/count").
+		Name("getStorageItemsCount").
 		Handler(http.HandlerFunc(api.getStorageItemsCount))
 
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/storages/items/totals").
+		Name("getStorageItemTotals").
+		Handler(http.HandlerFunc(api.getStorageItemTotals))
+
 	api.Router.Methods(http.MethodPut).
 		Path(path + "accounts/{username}/storages/{storage_id}/items/{item_id}").
+		Name("updateStorageItem").
 		Handler(http.HandlerFunc(api.updateStorageItem))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/storages/{storage_id}/items/{item_id}/quantity/decrement").
+		Name("decrementStorageItemQuantity").
 		Handler(http.HandlerFunc(api.decrementStorageItemQuantity))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/storages/{storage_id}/items/{item_id}/quantity/increment").
+		Name("incrementStorageItemQuantity").
 		Handler(http.HandlerFunc(api.incrementStorageItemQuantity))
 
 	api.Router.Methods(http.MethodDelete).
 		Path(path + "accounts/{username}/storages/{storage_id}/items/{item_id}").
+		Name("deleteStorageItem").
 		Handler(http.HandlerFunc(api.deleteStorageItem))
 
 	api.Router.Methods(http.MethodPost).
 		Path(path + "accounts/{username}/shopping-lists").
+		Name("createShoppingList").
 		Handler(http.HandlerFunc(api.createShoppingList))
 
 	api.Router.Methods(http.MethodPost).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/share/{username_request}").
+		Name("shareShoppingList").
 		Handler(http.HandlerFunc(api.shareShoppingList))
 
 	api.Router.Methods(http.MethodDelete).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/share/{username_request}").
+		Name("removeShareShoppingList").
 		Handler(http.HandlerFunc(api.removeShareShoppingList))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/owner/{owner}").
+		Name("getShoppingListOwner").
 		Handler(http.HandlerFunc(api.getShoppingListOwner))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/shopping-lists").
+		Name("getShoppingLists").
 		Handler(http.HandlerFunc(api.getShoppingLists))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/shopping-lists/count").
+		Name("getShoppingListsCount").
 		Handler(http.HandlerFunc(api.getShoppingListsCount))
 
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id:[0-9]+}").
+		Name("getShoppingList").
+		Handler(http.HandlerFunc(api.getShoppingList))
+
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/title/{title}").
+		Name("updateShoppingListTitle").
 		Handler(http.HandlerFunc(api.updateShoppingListTitle))
 
 	api.Router.Methods(http.MethodDelete).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}").
+		Name("deleteShoppingList").
 		Handler(http.HandlerFunc(api.deleteShoppingList))
 
 	api.Router.Methods(http.MethodPost).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items").
+		Name("createShoppingListItem").
 		Handler(http.HandlerFunc(api.createShoppingListItem))
 
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items/batch").
+		Name("batchShoppingListItems").
+		Handler(http.HandlerFunc(api.batchShoppingListItems))
+
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items").
+		Name("getShoppingListItems").
 		Handler(http.HandlerFunc(api.getShoppingListItems))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items/{shopping_list_item_id}").
+		Name("getShoppingListItem").
 		Handler(http.HandlerFunc(api.getShoppingListItem))
 
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/shopping-lists/items/count").
+		Name("getShoppingListItemsCount").
 		Handler(http.HandlerFunc(api.getShoppingListItemsCount))
 
 	api.Router.Methods(http.MethodPut).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items/{shopping_list_item_id}").
+		Name("updateShoppingListItem").
 		Handler(http.HandlerFunc(api.updateShoppingListItem))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items/{shopping_list_item_id}/title/{title}").
+		Name("updateShoppingListItemTitle").
 		Handler(http.HandlerFunc(api.updateShoppingListItemTitle))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items/{item_id}/quantity/decrement").
+		Name("decrementShoppingListItemQuantity").
 		Handler(http.HandlerFunc(api.decrementShoppingListItemQuantity))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items/{item_id}/quantity/increment").
+		Name("incrementShoppingListItemQuantity").
 		Handler(http.HandlerFunc(api.incrementShoppingListItemQuantity))
 
 	api.Router.Methods(http.MethodDelete).
 		Path(path + "accounts/{username}/shopping-lists/{shopping_list_id}/items/{shopping_list_item_id}").
+		Name("deleteShoppingListItem").
 		Handler(http.HandlerFunc(api.deleteShoppingListItem))
 
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/search").
+		Name("search").
+		Handler(http.HandlerFunc(api.search))
+
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/changes").
+		Name("getChanges").
+		Handler(http.HandlerFunc(api.getChanges))
+
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/changes").
+		Name("pushChanges").
+		Handler(http.HandlerFunc(api.pushChanges))
+
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/export").
+		Name("exportAccount").
+		Handler(http.HandlerFunc(api.exportAccount))
+
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/import").
+		Name("importAccount").
+		Handler(http.HandlerFunc(api.importAccount))
+
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/trash").
+		Name("getTrash").
+		Handler(http.HandlerFunc(api.getTrash))
+
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/trash/storages/{storage_id}/restore").
+		Name("restoreStorage").
+		Handler(http.HandlerFunc(api.restoreStorage))
+
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/trash/storages/{storage_id}/items/{item_id}/restore").
+		Name("restoreStorageItem").
+		Handler(http.HandlerFunc(api.restoreStorageItem))
+
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/trash/shopping-lists/{shopping_list_id}/restore").
+		Name("restoreShoppingList").
+		Handler(http.HandlerFunc(api.restoreShoppingList))
+
+	api.Router.Methods(http.MethodPost).
+		Path(path + "accounts/{username}/trash/shopping-lists/{shopping_list_id}/items/{shopping_list_item_id}/restore").
+		Name("restoreShoppingListItem").
+		Handler(http.HandlerFunc(api.restoreShoppingListItem))
+
 	api.Router.Methods(http.MethodGet).
 		Path(path + "accounts/{username}/settings/notifications").
+		Name("getNotificatiosSetting").
 		Handler(http.HandlerFunc(api.getNotificatiosSetting))
 
 	api.Router.Methods(http.MethodPatch).
 		Path(path + "accounts/{username}/settings/notifications").
+		Name("toggleNotificationSetting").
 		Handler(http.HandlerFunc(api.toggleNotificationSetting))
 
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/settings/units").
+		Name("getUnitSetting").
+		Handler(http.HandlerFunc(api.getUnitSetting))
+
+	api.Router.Methods(http.MethodPut).
+		Path(path + "accounts/{username}/settings/units").
+		Name("updateUnitSetting").
+		Handler(http.HandlerFunc(api.updateUnitSetting))
+
+	api.Router.Methods(http.MethodGet).
+		Path(path + "accounts/{username}/units/convert").
+		Name("convertQuantity").
+		Handler(http.HandlerFunc(api.convertQuantity))
+
+	for name := range api.Timeouts.Routes {
+		if api.Router.Get(name) == nil {
+			log.Printf("route_timeouts: unknown route %s", name)
+		}
+	}
+
 	return api.Router
 }
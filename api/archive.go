@@ -0,0 +1,43 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (api *API) exportAccount(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	payload, err := api.DB.ExportAccount(r.Context(), username)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-export.json"`, payload.Account.Username))
+	util.WriteJSON(payload, http.StatusOK, w)
+}
+
+func (api *API) importAccount(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	request := database.Archive{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		util.WriteJSON(util.Error(err.Error()), http.StatusUnprocessableEntity, w)
+		return
+	}
+
+	payload, err := api.DB.ImportAccount(r.Context(), username, request)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
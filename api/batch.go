@@ -0,0 +1,49 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"log"
+	"net/http"
+)
+
+// BatchRequest is the body of a batch on the items of a storage or shopping list.
+// The operations run atomically unless BestEffort is set.
+type BatchRequest struct {
+	BestEffort bool                     `json:"bestEffort"`
+	Operations []database.ItemOperation `json:"operations"`
+}
+
+// BatchResponse holds the outcome of each operation of a batch, in order
+type BatchResponse struct {
+	Results []BatchEntryResponse `json:"results"`
+}
+
+// BatchEntryResponse is the outcome of an operation of a batch, with the error it failed with
+type BatchEntryResponse struct {
+	database.BatchEntry
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+// writeBatch responds with the outcome of each operation of a batch. A failed atomic batch is answered
+// with the status of the error of the failed operation, a best effort batch always 200.
+func writeBatch(result database.BatchResult, bestEffort bool, w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	payload := BatchResponse{Results: []BatchEntryResponse{}}
+
+	for _, entry := range result.Results {
+		response := BatchEntryResponse{BatchEntry: entry}
+		if entry.Err != nil {
+			_, errResponse := errorResponse(entry.Err)
+			response.Error = &errResponse
+		}
+		payload.Results = append(payload.Results, response)
+	}
+
+	if failed := result.Failed(); failed != nil && !bestEffort {
+		status, _ = errorResponse(failed.Err)
+		log.Printf("%s %s: %d %v", r.Method, r.URL.Path, status, failed.Err)
+	}
+
+	util.WriteJSON(payload, status, w)
+}
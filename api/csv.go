@@ -0,0 +1,305 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// csvColumns are the columns of a storage items CSV, in the order they are exported
+var csvColumns = []string{"title", "quantity", "quantity_type", "quantity_threshold", "expiration_threshold", "expiration_date"}
+
+// maxCSVRows is the most rows a CSV upload takes, besides its header
+const maxCSVRows = 1000
+
+// maxCSVBytes is the largest CSV upload
+const maxCSVBytes = 1 << 20
+
+// errCSVDryRun rolls back the writes of a dry run
+var errCSVDryRun = errors.New("dry run")
+
+// Statuses of a CSVRow
+const (
+	// CSVRowImported marks a row created as a storage item
+	CSVRowImported = "imported"
+	// CSVRowValid marks a row a dry run would import
+	CSVRowValid = "valid"
+	// CSVRowInvalid marks a row left out for the errors it holds
+	CSVRowInvalid = "invalid"
+)
+
+// CSVImportResponse reports a CSV upload row by row
+type CSVImportResponse struct {
+	DryRun bool `json:"dryRun"`
+	// Imported is the number of items created, 0 for a dry run
+	Imported int      `json:"imported"`
+	Invalid  int      `json:"invalid"`
+	Rows     []CSVRow `json:"rows"`
+}
+
+// CSVRow is the outcome of a row of a CSV upload, with the item read from it
+type CSVRow struct {
+	// Row numbers the rows after the header from 1
+	Row    int             `json:"row"`
+	Status string          `json:"status"`
+	ID     int             `json:"id,omitempty"`
+	Item   ItemRequest     `json:"item"`
+	Errors []ErrorResponse `json:"errors,omitempty"`
+}
+
+func (api *API) exportStorageItemsCSV(w http.ResponseWriter, r *http.Request) {
+	storageIDString := mux.Vars(r)["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+
+	// Every page is read before answering, so that an error is still answered as one
+	items := []database.Item{}
+	options := database.ListOptions{Limit: 200}
+	for {
+		page, err := api.DB.GetStorageItems(r.Context(), storageID, options)
+		if err != nil {
+			writeError(err, w, r)
+			return
+		}
+		items = append(items, page.Items...)
+
+		if page.NextCursor == "" {
+			break
+		}
+		options.Cursor = page.NextCursor
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="storage-%d-items.csv"`, storageID))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write(csvColumns)
+	for _, item := range items {
+		writer.Write([]string{
+			item.Title,
+			strconv.FormatFloat(item.Quantity, 'f', -1, 64),
+			item.QuantityType,
+			strconv.FormatFloat(item.QuantityThreshold, 'f', -1, 64),
+			strconv.Itoa(item.ExpirationThreshold),
+			csvDate(item.ExpirationDate),
+		})
+	}
+	writer.Flush()
+}
+
+// csvDate writes an expiration date as a date when it is at midnight UTC, and no date as an empty cell
+func csvDate(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	switch {
+	case err != nil:
+		return value
+	case t.IsZero():
+		return ""
+	case t.Equal(t.Truncate(24 * time.Hour)):
+		return t.Format("2006-01-02")
+	default:
+		return value
+	}
+}
+
+func (api *API) importStorageItemsCSV(w http.ResponseWriter, r *http.Request) {
+	storageIDString := mux.Vars(r)["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+
+	invalid := func(field string, err error) {
+		util.WriteJSON(ErrorResponse{Error: err.Error(), Code: CodeInvalidValue, Field: field}, http.StatusUnprocessableEntity, w)
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			util.WriteJSON(ErrorResponse{Error: "dry_run must be a boolean", Code: CodeInvalidValue, Field: "dry_run"}, http.StatusBadRequest, w)
+			return
+		}
+	}
+
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, maxCSVBytes))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		invalid("header", errors.New("the file must start with a header row"))
+		return
+	}
+
+	columns, err := csvMapping(header, r.URL.Query()["map"])
+	if err != nil {
+		invalid("map", err)
+		return
+	}
+	if columns["title"] < 0 {
+		invalid("header", errors.New("the header must have a title column"))
+		return
+	}
+
+	response := CSVImportResponse{DryRun: dryRun, Rows: []CSVRow{}}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			invalid("file", err)
+			return
+		}
+
+		if len(response.Rows) == maxCSVRows {
+			invalid("file", fmt.Errorf("the file must have at most %d rows", maxCSVRows))
+			return
+		}
+
+		row := csvRow(record, columns)
+		row.Row = len(response.Rows) + 1
+
+		if row.Status == CSVRowInvalid {
+			response.Invalid++
+		}
+		response.Rows = append(response.Rows, row)
+	}
+
+	// A dry run makes the same writes as the import and rolls them back, so that it fails where the import would
+	if err := api.DB.WithTx(r.Context(), func(tx database.Store) error {
+		for i, row := range response.Rows {
+			if row.Status != CSVRowValid {
+				continue
+			}
+
+			id, err := tx.CreateStorageItem(r.Context(), storageID, row.Item.Title, row.Item.Quantity, row.Item.QuantityType,
+				row.Item.QuantityThreshold, row.Item.ExpirationThreshold, row.Item.ExpirationDate)
+			if err != nil {
+				return err
+			}
+
+			if !dryRun {
+				response.Rows[i].Status = CSVRowImported
+				response.Rows[i].ID = int(id)
+				response.Imported++
+			}
+		}
+
+		if dryRun {
+			return errCSVDryRun
+		}
+		return nil
+	}); err != nil && err != errCSVDryRun {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(response, http.StatusOK, w)
+}
+
+// csvMapping returns the index of the header cell of each column, -1 for the columns the file lacks.
+// A column is found by its name, regardless of case, spaces, dashes and underscores, unless mappings
+// name its header cell as column:header.
+func csvMapping(header []string, mappings []string) (map[string]int, error) {
+	normalize := func(name string) string {
+		return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+	}
+
+	names := map[string]string{}
+	for _, column := range csvColumns {
+		names[column] = normalize(column)
+	}
+
+	for _, mapping := range mappings {
+		parts := strings.SplitN(mapping, ":", 2)
+		if _, ok := names[parts[0]]; !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("map must be a column, one of %s, and a header cell as column:header", strings.Join(csvColumns, ", "))
+		}
+		names[parts[0]] = normalize(parts[1])
+	}
+
+	columns := map[string]int{}
+	for _, column := range csvColumns {
+		columns[column] = -1
+		for i, cell := range header {
+			if normalize(cell) == names[column] {
+				columns[column] = i
+				break
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+// csvRow reads the item of record and checks it as an ItemRequest. A missing quantity type is pieces.
+func csvRow(record []string, columns map[string]int) CSVRow {
+	row := CSVRow{Status: CSVRowValid}
+
+	cell := func(column string) string {
+		if i := columns[column]; i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	number := func(column string) int {
+		value := cell(column)
+		if value == "" {
+			return 0
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			row.Errors = append(row.Errors, ErrorResponse{Error: column + " must be a whole number", Code: CodeInvalidValue, Field: column})
+		}
+		return n
+	}
+
+	decimal := func(column string) float64 {
+		value := cell(column)
+		if value == "" {
+			return 0
+		}
+
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			row.Errors = append(row.Errors, ErrorResponse{Error: column + " must be a number", Code: CodeInvalidValue, Field: column})
+			return 0
+		}
+		return f
+	}
+
+	row.Item = ItemRequest{
+		Title:               cell("title"),
+		Quantity:            decimal("quantity"),
+		QuantityType:        cell("quantity_type"),
+		QuantityThreshold:   decimal("quantity_threshold"),
+		ExpirationThreshold: number("expiration_threshold"),
+		ExpirationDate:      cell("expiration_date"),
+	}
+	if row.Item.QuantityType == "" {
+		row.Item.QuantityType = "pieces"
+	}
+
+	if err := row.Item.validate(); err != nil {
+		_, response := errorResponse(err)
+		row.Errors = append(row.Errors, response)
+	}
+
+	if len(row.Errors) > 0 {
+		row.Status = CSVRowInvalid
+	}
+
+	return row
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// ErrorResponse is the body of the responses to failed requests.
+// Code is stable and meant for clients to branch on, Error is a human readable message.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	Field string `json:"field,omitempty"`
+	// Current is the current representation of the resource a stale write was rejected for
+	Current interface{} `json:"current,omitempty"`
+}
+
+// Error codes of ErrorResponse
+const (
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeInvalidReference = "invalid_reference"
+	CodeInvalidValue     = "invalid_value"
+	CodeStale            = "stale"
+	CodeCursorExpired    = "cursor_expired"
+	CodeTimeout          = "timeout"
+	CodeCanceled         = "canceled"
+	CodeInternal         = "internal_error"
+)
+
+// statusClientClosedRequest is the non standard status logged for requests whose client went away
+const statusClientClosedRequest = 499
+
+// writeError responds to a failed request with the status and code matching err.
+// err itself is only logged, as it may hold SQL or driver details.
+func writeError(err error, w http.ResponseWriter, r *http.Request) {
+	status, response := errorResponse(err)
+
+	log.Printf("%s %s: %d %v", r.Method, r.URL.Path, status, err)
+
+	util.WriteJSON(response, status, w)
+}
+
+// errorResponse returns the status and body matching err, without its details
+func errorResponse(err error) (int, ErrorResponse) {
+	status := http.StatusInternalServerError
+	response := ErrorResponse{Error: "internal server error", Code: CodeInternal}
+
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		status = http.StatusNotFound
+		response = ErrorResponse{Error: "not found", Code: CodeNotFound}
+	case errors.Is(err, database.ErrConflict):
+		status = http.StatusConflict
+		response = ErrorResponse{Error: "conflicts with existing data", Code: CodeConflict}
+	case errors.Is(err, database.ErrForeignKey):
+		status = http.StatusNotFound
+		response = ErrorResponse{Error: "must be an already existing item", Code: CodeInvalidReference}
+	case errors.Is(err, database.ErrValidation):
+		status = http.StatusUnprocessableEntity
+		response = ErrorResponse{Error: "invalid value", Code: CodeInvalidValue}
+	case errors.Is(err, database.ErrStale):
+		status = http.StatusPreconditionFailed
+		response = ErrorResponse{Error: "modified since the given version", Code: CodeStale}
+	case errors.Is(err, database.ErrCursorExpired):
+		status = http.StatusGone
+		response = ErrorResponse{Error: "the cursor is older than the changes kept, sync again without it", Code: CodeCursorExpired}
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+		response = ErrorResponse{Error: "the request timed out", Code: CodeTimeout}
+	case errors.Is(err, context.Canceled):
+		status = statusClientClosedRequest
+		response = ErrorResponse{Error: "the request was canceled", Code: CodeCanceled}
+	}
+
+	dbErr := &database.Error{}
+	if errors.As(err, &dbErr) {
+		response.Field = dbErr.Field
+	}
+
+	return status, response
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// staleVersion is expected by writes whose If-Match holds a weak tag, which never matches a stored version
+const staleVersion = -1
+
+// etag returns the entity tag of a row at version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag sets the ETag header of the response to the entity tag of version
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// ifMatch returns the version a write must apply to according to the If-Match header.
+// It is database.AnyVersion when the header is absent or "*".
+func ifMatch(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+
+	switch {
+	case value == "" || value == "*":
+		return database.AnyVersion, nil
+	case strings.Contains(value, ","):
+		return 0, fmt.Errorf("If-Match must hold a single entity tag")
+	case strings.HasPrefix(value, "W/"):
+		// If-Match compares tags strongly, so a weak tag matches nothing
+		return staleVersion, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil || version <= 0 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return 0, fmt.Errorf("invalid If-Match entity tag %s", value)
+	}
+
+	return version, nil
+}
+
+// versionOrError reads the If-Match version of the request, or responds 400 and returns false
+func versionOrError(w http.ResponseWriter, r *http.Request) (int, bool) {
+	version, err := ifMatch(r)
+	if err != nil {
+		util.WriteJSON(ErrorResponse{Error: err.Error(), Code: CodeInvalidValue, Field: "If-Match"}, http.StatusBadRequest, w)
+		return 0, false
+	}
+	return version, true
+}
+
+// writeWriteError is writeError for the writes conditioned by If-Match.
+// A stale write is answered 412 along with the current representation returned by current, and its ETag.
+func writeWriteError(err error, w http.ResponseWriter, r *http.Request, current func() (interface{}, int, error)) {
+	if !errors.Is(err, database.ErrStale) {
+		writeError(err, w, r)
+		return
+	}
+
+	payload, version, currentErr := current()
+	if currentErr != nil {
+		writeError(currentErr, w, r)
+		return
+	}
+
+	log.Printf("%s %s: %d %v", r.Method, r.URL.Path, http.StatusPreconditionFailed, err)
+
+	setETag(w, version)
+	util.WriteJSON(ErrorResponse{
+		Error:   "modified since the given version",
+		Code:    CodeStale,
+		Field:   "version",
+		Current: payload,
+	}, http.StatusPreconditionFailed, w)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HealthResponse structure
+type HealthResponse struct {
+	Status   string          `json:"status"`
+	Database database.Health `json:"database"`
+}
+
+// health answers 200 when the database is up and 503 otherwise, with the pool statistics
+func (api *API) health(w http.ResponseWriter, r *http.Request) {
+	dbHealth := api.DB.Health(r.Context())
+
+	if !dbHealth.Up {
+		util.WriteJSON(HealthResponse{Status: "down", Database: dbHealth}, http.StatusServiceUnavailable, w)
+		return
+	}
+
+	util.WriteJSON(HealthResponse{Status: "up", Database: dbHealth}, http.StatusOK, w)
+}
+
+// metrics writes the database health and pool statistics in the Prometheus text format
+func (api *API) metrics(w http.ResponseWriter, r *http.Request) {
+	dbHealth := api.DB.Health(r.Context())
+	pool := dbHealth.Pool
+
+	up := 0
+	if dbHealth.Up {
+		up = 1
+	}
+
+	out := strings.Builder{}
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+
+	metric("catclerk_db_up", "gauge", "Whether the last database health probe succeeded.", up)
+	metric("catclerk_db_max_open_connections", "gauge", "Maximum number of open connections to the database.", pool.MaxOpenConnections)
+	metric("catclerk_db_open_connections", "gauge", "Number of established connections, in use and idle.", pool.OpenConnections)
+	metric("catclerk_db_in_use_connections", "gauge", "Number of connections currently in use.", pool.InUse)
+	metric("catclerk_db_idle_connections", "gauge", "Number of idle connections.", pool.Idle)
+	metric("catclerk_db_wait_count_total", "counter", "Number of connections waited for.", pool.WaitCount)
+	metric("catclerk_db_wait_duration_seconds_total", "counter", "Time blocked waiting for a new connection.", pool.WaitDuration.Seconds())
+	metric("catclerk_db_max_idle_closed_total", "counter", "Connections closed due to the idle connections limit.", pool.MaxIdleClosed)
+	metric("catclerk_db_max_idle_time_closed_total", "counter", "Connections closed due to the connection idle time.", pool.MaxIdleTimeClosed)
+	metric("catclerk_db_max_lifetime_closed_total", "counter", "Connections closed due to the connection lifetime.", pool.MaxLifetimeClosed)
+
+	if cache := dbHealth.Cache; cache != nil {
+		metric("catclerk_cache_entries", "gauge", "Number of entries in the cache.", cache.Entries)
+		metric("catclerk_cache_hits_total", "counter", "Reads served by the cache.", cache.Hits)
+		metric("catclerk_cache_misses_total", "counter", "Reads passed on to the database.", cache.Misses)
+		metric("catclerk_cache_hit_ratio", "gauge", "Share of the reads served by the cache.", cache.HitRate())
+		metric("catclerk_cache_evictions_total", "counter", "Entries evicted to make room for new ones.", cache.Evictions)
+		metric("catclerk_cache_expirations_total", "counter", "Entries found past their TTL.", cache.Expirations)
+		metric("catclerk_cache_invalidations_total", "counter", "Entries dropped by writes.", cache.Invalidations)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(out.String()))
+}
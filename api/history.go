@@ -0,0 +1,67 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// actor records the username of the request path as the author of the writes made by the request.
+// The auth middleware has checked it is the username of the token.
+func (api *API) actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, ok := mux.Vars(r)["username"]; ok {
+			r = r.WithContext(database.WithActor(r.Context(), username))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// queryInt reads the non negative integer query parameter name, 0 when absent
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non negative integer", name)
+	}
+
+	return n, nil
+}
+
+func (api *API) getStorageItemHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	storageIDString := vars["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+	itemIDstring := vars["item_id"]
+	itemID, _ := strconv.Atoi(itemIDstring)
+
+	cursor, err := queryInt(r, "cursor")
+	if err != nil {
+		util.WriteJSON(ErrorResponse{Error: err.Error(), Code: CodeInvalidValue, Field: "cursor"}, http.StatusBadRequest, w)
+		return
+	}
+
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		util.WriteJSON(ErrorResponse{Error: err.Error(), Code: CodeInvalidValue, Field: "limit"}, http.StatusBadRequest, w)
+		return
+	}
+
+	payload, err := api.DB.GetStorageItemHistory(r.Context(), storageID, itemID, cursor, limit)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// listOptions reads the paging, sorting and filtering query parameters of the list endpoints.
+// A malformed parameter is answered 400 and ok is false. Sort keys and cursors are checked by the Store.
+func listOptions(w http.ResponseWriter, r *http.Request) (options database.ListOptions, ok bool) {
+	query := r.URL.Query()
+
+	invalid := func(field string, err error) (database.ListOptions, bool) {
+		util.WriteJSON(ErrorResponse{Error: err.Error(), Code: CodeInvalidValue, Field: field}, http.StatusBadRequest, w)
+		return options, false
+	}
+
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		return invalid("limit", err)
+	}
+
+	options.Cursor = query.Get("cursor")
+	options.Limit = limit
+	options.Sort = query.Get("sort")
+	options.QuantityType = query.Get("quantity_type")
+	options.Q = query.Get("q")
+
+	if value := query.Get("expiring_before"); value != "" {
+		expiringBefore, err := queryTime(value)
+		if err != nil {
+			return invalid("expiring_before", err)
+		}
+		options.ExpiringBefore = &expiringBefore
+	}
+
+	if value := query.Get("below_threshold"); value != "" {
+		belowThreshold, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalid("below_threshold", fmt.Errorf("below_threshold must be a boolean"))
+		}
+		options.BelowThreshold = belowThreshold
+	}
+
+	return options, true
+}
+
+// queryTime parses a date, taken as midnight UTC, or an RFC 3339 time
+func queryTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("must be a date or an RFC 3339 time")
+	}
+
+	return t, nil
+}
@@ -3,7 +3,6 @@ package api
 import (
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"cat-clerk-api/mail"
@@ -15,13 +14,9 @@ import (
 func (api *API) sendForgottenPasswordMail(w http.ResponseWriter, r *http.Request) {
 	email := mux.Vars(r)["email"]
 
-	acc, err := api.DB.EmailExists(email)
+	acc, err := api.DB.EmailExists(r.Context(), email)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -55,7 +50,7 @@ func (api *API) sendForgottenPasswordMail(w http.ResponseWriter, r *http.Request
 		data,
 		"forgotten-password.gohtml",
 	); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -14,20 +14,32 @@ type FoodsResponse struct {
 	Name string `json:"name"`
 }
 
+// FoodsPageResponse is a page of FoodsResponse, see database.FoodPage
+type FoodsPageResponse struct {
+	Items      []FoodsResponse `json:"items"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+	Total      int             `json:"total"`
+}
+
 func (api *API) getFoods(w http.ResponseWriter, r *http.Request) {
-	payload, err := api.DB.GetFoods()
+	options, ok := listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := api.DB.GetFoods(r.Context(), options)
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
 	names := []FoodsResponse{}
 
-	for _, p := range payload {
+	for _, p := range payload.Items {
 		name := FoodsResponse{}
 		name.Name = p.Name
 		names = append(names, name)
 	}
 
-	util.WriteJSON(names, http.StatusOK, w)
+	util.WriteJSON(FoodsPageResponse{Items: names, NextCursor: payload.NextCursor, Total: payload.Total}, http.StatusOK, w)
 }
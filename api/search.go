@@ -0,0 +1,33 @@
+package api
+
+import (
+	"cat-clerk-api/util"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func (api *API) search(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		util.WriteJSON(ErrorResponse{Error: "q is required", Code: CodeInvalidValue, Field: "q"}, http.StatusBadRequest, w)
+		return
+	}
+
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		util.WriteJSON(ErrorResponse{Error: err.Error(), Code: CodeInvalidValue, Field: "limit"}, http.StatusBadRequest, w)
+		return
+	}
+
+	payload, err := api.DB.Search(r.Context(), username, q, limit)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
@@ -1,9 +1,10 @@
 package api
 
 import (
+	"cat-clerk-api/database"
 	"cat-clerk-api/util"
+	"encoding/json"
 	"net/http"
-	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -11,13 +12,9 @@ import (
 func (api *API) getNotificatiosSetting(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetNotificationSetting(username)
+	payload, err := api.DB.GetNotificationSetting(r.Context(), username)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -28,9 +25,44 @@ func (api *API) toggleNotificationSetting(w http.ResponseWriter, r *http.Request
 	username := mux.Vars(r)["username"]
 
 	if err := api.DB.ToggleNotificationSetting(
+		r.Context(),
 		username,
 	); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(nil, http.StatusNoContent, w)
+}
+
+func (api *API) getUnitSetting(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	payload, err := api.DB.GetUnitSetting(r.Context(), username)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
+
+func (api *API) updateUnitSetting(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	request := database.UnitSettings{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		util.WriteJSON(util.Error(err.Error()), http.StatusUnprocessableEntity, w)
+		return
+	}
+
+	if err := api.DB.UpdateUnitSetting(
+		r.Context(),
+		username,
+		request.UnitSystem,
+	); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -27,9 +26,9 @@ func (api *API) createShareRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accounts, err := api.DB.GetAccounts()
+	accounts, err := api.DB.GetAccounts(r.Context())
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusNotFound, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -46,9 +45,9 @@ func (api *API) createShareRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	currentShareRequests, err := api.DB.GetShareRequests(request.ToUsername)
+	currentShareRequests, err := api.DB.GetShareRequests(r.Context(), request.ToUsername)
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -64,8 +63,8 @@ func (api *API) createShareRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.DB.CreateShareRequest(username, request.ToUsername, request.ShareType, request.Title, request.IDRequest); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	if err := api.DB.CreateShareRequest(r.Context(), username, request.ToUsername, request.ShareType, request.Title, request.IDRequest); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
@@ -75,13 +74,9 @@ func (api *API) createShareRequest(w http.ResponseWriter, r *http.Request) {
 func (api *API) getShareRequests(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetShareRequests(username)
+	payload, err := api.DB.GetShareRequests(r.Context(), username)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -93,8 +88,24 @@ func (api *API) deleteShareRequest(w http.ResponseWriter, r *http.Request) {
 
 	shareID, _ := strconv.Atoi(shareIDstring)
 
-	if err := api.DB.DeleteShareRequest(shareID); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	if err := api.DB.DeleteShareRequest(r.Context(), shareID); err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(nil, http.StatusNoContent, w)
+}
+
+func (api *API) acceptShareRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	username := vars["username"]
+
+	shareIDstring := vars["share_id"]
+	shareID, _ := strconv.Atoi(shareIDstring)
+
+	if err := api.DB.AcceptShareRequest(r.Context(), username, shareID); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
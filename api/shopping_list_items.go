@@ -5,16 +5,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gorilla/mux"
 )
 
 // ShoppingListItemRequest structure
 type ShoppingListItemRequest struct {
-	Title        string `json:"title"`
-	Quantity     int    `json:"quantity"`
-	QuantityType string `json:"quantityType"`
+	Title        string  `json:"title"`
+	Quantity     float64 `json:"quantity"`
+	QuantityType string  `json:"quantityType"`
 }
 
 func (api *API) createShoppingListItem(w http.ResponseWriter, r *http.Request) {
@@ -28,21 +27,14 @@ func (api *API) createShoppingListItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.DB.CreateShoppingListItem(
+	if _, err := api.DB.CreateShoppingListItem(
+		r.Context(),
 		shoppingListID,
 		request.Title,
 		request.Quantity,
 		request.QuantityType,
 	); err != nil {
-		if strings.Contains(err.Error(), "Error 1452") {
-			util.WriteJSON(util.Error("must be an already existing shopping list"), http.StatusNotFound, w)
-			return
-		}
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -53,13 +45,14 @@ func (api *API) getShoppingListItems(w http.ResponseWriter, r *http.Request) {
 	shoppingListIDString := mux.Vars(r)["shopping_list_id"]
 	shoppingListID, _ := strconv.Atoi(shoppingListIDString)
 
-	payload, err := api.DB.GetShoppingListItems(shoppingListID)
+	options, ok := listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := api.DB.GetShoppingListItems(r.Context(), shoppingListID, options)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -70,29 +63,30 @@ func (api *API) getShoppingListItem(w http.ResponseWriter, r *http.Request) {
 	itemIDString := mux.Vars(r)["shopping_list_item_id"]
 	itemID, _ := strconv.Atoi(itemIDString)
 
-	payload, err := api.DB.GetShoppingListItem(itemID)
+	payload, err := api.DB.GetShoppingListItem(r.Context(), itemID)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
+	setETag(w, payload.Version)
 	util.WriteJSON(payload, http.StatusOK, w)
 }
 
+// currentShoppingListItem reads the shopping list item a stale write is answered with
+func (api *API) currentShoppingListItem(r *http.Request, itemID int) func() (interface{}, int, error) {
+	return func() (interface{}, int, error) {
+		item, err := api.DB.GetShoppingListItem(r.Context(), itemID)
+		return item, item.Version, err
+	}
+}
+
 func (api *API) getShoppingListItemsCount(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetShoppingListItemsCount(username)
+	payload, err := api.DB.GetShoppingListItemsCount(r.Context(), username)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -112,17 +106,20 @@ func (api *API) updateShoppingListItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
 	if err := api.DB.UpdateShoppingListItem(
+		r.Context(),
 		request.Title,
 		request.Quantity,
 		request.QuantityType,
 		itemID,
+		version,
 	); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeWriteError(err, w, r, api.currentShoppingListItem(r, itemID))
 		return
 	}
 
@@ -135,15 +132,18 @@ func (api *API) updateShoppingListItemTitle(w http.ResponseWriter, r *http.Reque
 	itemIDstring := vars["shopping_list_item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
 	if err := api.DB.UpdateShoppingListItemTitle(
+		r.Context(),
 		title,
 		itemID,
+		version,
 	); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeWriteError(err, w, r, api.currentShoppingListItem(r, itemID))
 		return
 	}
 
@@ -154,14 +154,23 @@ func (api *API) decrementShoppingListItemQuantity(w http.ResponseWriter, r *http
 	itemIDstring := mux.Vars(r)["item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	by, ok := stepOrError(w, r)
+	if !ok {
+		return
+	}
+
 	if err := api.DB.DecrementShoppingListItemQuantity(
+		r.Context(),
+		by,
 		itemID,
+		version,
 	); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeWriteError(err, w, r, api.currentShoppingListItem(r, itemID))
 		return
 	}
 
@@ -172,14 +181,23 @@ func (api *API) incrementShoppingListItemQuantity(w http.ResponseWriter, r *http
 	itemIDstring := mux.Vars(r)["item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	by, ok := stepOrError(w, r)
+	if !ok {
+		return
+	}
+
 	if err := api.DB.IncrementShoppingListItemQuantity(
+		r.Context(),
+		by,
 		itemID,
+		version,
 	); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeWriteError(err, w, r, api.currentShoppingListItem(r, itemID))
 		return
 	}
 
@@ -190,14 +208,35 @@ func (api *API) deleteShoppingListItem(w http.ResponseWriter, r *http.Request) {
 	itemIDstring := mux.Vars(r)["shopping_list_item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
-	if err := api.DB.DeleteShoppingListItem(itemID); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.DB.DeleteShoppingListItem(r.Context(), itemID, version); err != nil {
+		writeWriteError(err, w, r, api.currentShoppingListItem(r, itemID))
 		return
 	}
 
 	util.WriteJSON(nil, http.StatusNoContent, w)
 }
+
+func (api *API) batchShoppingListItems(w http.ResponseWriter, r *http.Request) {
+	shoppingListIDString := mux.Vars(r)["shopping_list_id"]
+	shoppingListID, _ := strconv.Atoi(shoppingListIDString)
+
+	request := BatchRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		util.WriteJSON(util.Error(err.Error()), http.StatusUnprocessableEntity, w)
+		return
+	}
+
+	result, err := api.DB.BatchShoppingListItems(r.Context(), shoppingListID, request.Operations, request.BestEffort)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	writeBatch(result, request.BestEffort, w, r)
+}
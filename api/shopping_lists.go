@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -19,8 +18,8 @@ func (api *API) createShoppingList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.DB.CreateShoppingList(username, request.Title, request.Owner); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	if _, err := api.DB.CreateShoppingList(r.Context(), username, request.Title, request.Owner); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
@@ -30,13 +29,14 @@ func (api *API) createShoppingList(w http.ResponseWriter, r *http.Request) {
 func (api *API) getShoppingLists(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetShoppingLists(username)
+	options, ok := listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := api.DB.GetShoppingLists(r.Context(), username, options)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -46,31 +46,50 @@ func (api *API) getShoppingLists(w http.ResponseWriter, r *http.Request) {
 func (api *API) getShoppingListsCount(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetShoppingListsCount(username)
+	payload, err := api.DB.GetShoppingListsCount(r.Context(), username)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
+
+func (api *API) getShoppingList(w http.ResponseWriter, r *http.Request) {
+	shoppingListIDString := mux.Vars(r)["shopping_list_id"]
+	shoppingListID, _ := strconv.Atoi(shoppingListIDString)
+
+	payload, err := api.DB.GetShoppingList(r.Context(), shoppingListID)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
+	setETag(w, payload.Version)
 	util.WriteJSON(payload, http.StatusOK, w)
 }
 
+// currentShoppingList reads the shopping list a stale write is answered with
+func (api *API) currentShoppingList(r *http.Request, shoppingListID int) func() (interface{}, int, error) {
+	return func() (interface{}, int, error) {
+		sl, err := api.DB.GetShoppingList(r.Context(), shoppingListID)
+		return sl, sl.Version, err
+	}
+}
+
 func (api *API) updateShoppingListTitle(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	title := vars["title"]
 	shoppingListIDString := vars["shopping_list_id"]
 	shoppingListID, _ := strconv.Atoi(shoppingListIDString)
 
-	if err := api.DB.UpdateShoppingListTitle(title, shoppingListID); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.DB.UpdateShoppingListTitle(r.Context(), title, shoppingListID, version); err != nil {
+		writeWriteError(err, w, r, api.currentShoppingList(r, shoppingListID))
 		return
 	}
 
@@ -81,12 +100,13 @@ func (api *API) deleteShoppingList(w http.ResponseWriter, r *http.Request) {
 	shoppingListIDString := mux.Vars(r)["shopping_list_id"]
 	shoppingListID, _ := strconv.Atoi(shoppingListIDString)
 
-	if err := api.DB.DeleteShoppingList(shoppingListID); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.DB.DeleteShoppingList(r.Context(), shoppingListID, version); err != nil {
+		writeWriteError(err, w, r, api.currentShoppingList(r, shoppingListID))
 		return
 	}
 
@@ -101,8 +121,8 @@ func (api *API) shareShoppingList(w http.ResponseWriter, r *http.Request) {
 
 	usernameRequest := vars["username_request"]
 
-	if err := api.DB.ShareShoppingList(usernameRequest, shoppingListID); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	if err := api.DB.ShareShoppingList(r.Context(), usernameRequest, shoppingListID); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
@@ -117,13 +137,9 @@ func (api *API) getShoppingListOwner(w http.ResponseWriter, r *http.Request) {
 
 	owner := vars["owner"]
 
-	payload, err := api.DB.GetShoppingListOwner(owner, shoppingListID)
+	payload, err := api.DB.GetShoppingListOwner(r.Context(), owner, shoppingListID)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -138,8 +154,8 @@ func (api *API) removeShareShoppingList(w http.ResponseWriter, r *http.Request)
 
 	usernameRequest := vars["username_request"]
 
-	if err := api.DB.RemoveShareShoppingList(usernameRequest, shoppingListID); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	if err := api.DB.RemoveShareShoppingList(r.Context(), usernameRequest, shoppingListID); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
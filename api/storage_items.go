@@ -1,24 +1,36 @@
 package api
 
 import (
+	"cat-clerk-api/database"
 	"cat-clerk-api/util"
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gorilla/mux"
 )
 
 // ItemRequest structure
 type ItemRequest struct {
-	Title               string `json:"title"`
-	Image               string `json:"image"`
-	Quantity            int    `json:"quantity"`
-	QuantityType        string `json:"quantityType"`
-	QuantityThreshold   int    `json:"quantityThreshold"`
-	ExpirationThreshold int    `json:"expirationThreshold"`
-	ExpirationDate      string `json:"expirationDate"`
+	Title               string  `json:"title"`
+	Image               string  `json:"image"`
+	Quantity            float64 `json:"quantity"`
+	QuantityType        string  `json:"quantityType"`
+	QuantityThreshold   float64 `json:"quantityThreshold"`
+	ExpirationThreshold int     `json:"expirationThreshold"`
+	ExpirationDate      string  `json:"expirationDate"`
+}
+
+// validate checks the request with database.ValidateItem
+func (request ItemRequest) validate() error {
+	return database.ValidateItem(database.Item{
+		Title:               request.Title,
+		Quantity:            request.Quantity,
+		QuantityType:        request.QuantityType,
+		QuantityThreshold:   request.QuantityThreshold,
+		ExpirationThreshold: request.ExpirationThreshold,
+		ExpirationDate:      request.ExpirationDate,
+	})
 }
 
 func (api *API) createStorageItem(w http.ResponseWriter, r *http.Request) {
@@ -32,7 +44,13 @@ func (api *API) createStorageItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.DB.CreateStorageItem(
+	if err := request.validate(); err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	if _, err := api.DB.CreateStorageItem(
+		r.Context(),
 		storageID,
 		request.Title,
 		request.Quantity,
@@ -41,15 +59,7 @@ func (api *API) createStorageItem(w http.ResponseWriter, r *http.Request) {
 		request.ExpirationThreshold,
 		request.ExpirationDate,
 	); err != nil {
-		if strings.Contains(err.Error(), "Error 1452") {
-			util.WriteJSON(util.Error("must be an already existing item"), http.StatusNotFound, w)
-			return
-		}
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -60,13 +70,14 @@ func (api *API) getStorageItems(w http.ResponseWriter, r *http.Request) {
 	storageIDString := mux.Vars(r)["storage_id"]
 	storageID, _ := strconv.Atoi(storageIDString)
 
-	payload, err := api.DB.GetStorageItems(storageID)
+	options, ok := listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := api.DB.GetStorageItems(r.Context(), storageID, options)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -81,29 +92,30 @@ func (api *API) getStorageItem(w http.ResponseWriter, r *http.Request) {
 	itemIDstring := vars["item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
-	payload, err := api.DB.GetStorageItem(storageID, itemID)
+	payload, err := api.DB.GetStorageItem(r.Context(), storageID, itemID)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
+	setETag(w, payload.Version)
 	util.WriteJSON(payload, http.StatusOK, w)
 }
 
+// currentStorageItem reads the storage item a stale write is answered with
+func (api *API) currentStorageItem(r *http.Request, storageID, itemID int) func() (interface{}, int, error) {
+	return func() (interface{}, int, error) {
+		item, err := api.DB.GetStorageItem(r.Context(), storageID, itemID)
+		return item, item.Version, err
+	}
+}
+
 func (api *API) getStorageItemsCount(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetStorageItemsCount(username)
+	payload, err := api.DB.GetStorageItemsCount(r.Context(), username)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -111,7 +123,11 @@ func (api *API) getStorageItemsCount(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) updateStorageItem(w http.ResponseWriter, r *http.Request) {
-	itemIDstring := mux.Vars(r)["item_id"]
+	vars := mux.Vars(r)
+
+	storageIDString := vars["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+	itemIDstring := vars["item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
 	request := ItemRequest{}
@@ -121,7 +137,18 @@ func (api *API) updateStorageItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := request.validate(); err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
 	if err := api.DB.UpdateStorageItem(
+		r.Context(),
 		request.Title,
 		request.Image,
 		request.Quantity,
@@ -130,12 +157,9 @@ func (api *API) updateStorageItem(w http.ResponseWriter, r *http.Request) {
 		request.ExpirationThreshold,
 		request.ExpirationDate,
 		itemID,
+		version,
 	); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeWriteError(err, w, r, api.currentStorageItem(r, storageID, itemID))
 		return
 	}
 
@@ -143,17 +167,30 @@ func (api *API) updateStorageItem(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) decrementStorageItemQuantity(w http.ResponseWriter, r *http.Request) {
-	itemIDstring := mux.Vars(r)["item_id"]
+	vars := mux.Vars(r)
+
+	storageIDString := vars["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+	itemIDstring := vars["item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	by, ok := stepOrError(w, r)
+	if !ok {
+		return
+	}
+
 	if err := api.DB.DecrementStorageItemQuantity(
+		r.Context(),
+		by,
 		itemID,
+		version,
 	); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeWriteError(err, w, r, api.currentStorageItem(r, storageID, itemID))
 		return
 	}
 
@@ -161,17 +198,30 @@ func (api *API) decrementStorageItemQuantity(w http.ResponseWriter, r *http.Requ
 }
 
 func (api *API) incrementStorageItemQuantity(w http.ResponseWriter, r *http.Request) {
-	itemIDstring := mux.Vars(r)["item_id"]
+	vars := mux.Vars(r)
+
+	storageIDString := vars["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+	itemIDstring := vars["item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	by, ok := stepOrError(w, r)
+	if !ok {
+		return
+	}
+
 	if err := api.DB.IncrementStorageItemQuantity(
+		r.Context(),
+		by,
 		itemID,
+		version,
 	); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeWriteError(err, w, r, api.currentStorageItem(r, storageID, itemID))
 		return
 	}
 
@@ -179,17 +229,42 @@ func (api *API) incrementStorageItemQuantity(w http.ResponseWriter, r *http.Requ
 }
 
 func (api *API) deleteStorageItem(w http.ResponseWriter, r *http.Request) {
-	itemIDstring := mux.Vars(r)["item_id"]
+	vars := mux.Vars(r)
+
+	storageIDString := vars["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+	itemIDstring := vars["item_id"]
 	itemID, _ := strconv.Atoi(itemIDstring)
 
-	if err := api.DB.DeleteStorageItem(itemID); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.DB.DeleteStorageItem(r.Context(), itemID, version); err != nil {
+		writeWriteError(err, w, r, api.currentStorageItem(r, storageID, itemID))
 		return
 	}
 
 	util.WriteJSON(nil, http.StatusNoContent, w)
 }
+
+func (api *API) batchStorageItems(w http.ResponseWriter, r *http.Request) {
+	storageIDString := mux.Vars(r)["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+
+	request := BatchRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		util.WriteJSON(util.Error(err.Error()), http.StatusUnprocessableEntity, w)
+		return
+	}
+
+	result, err := api.DB.BatchStorageItems(r.Context(), storageID, request.Operations, request.BestEffort)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	writeBatch(result, request.BestEffort, w, r)
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -25,9 +24,9 @@ func (api *API) createStorage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	payload, err := api.DB.CreateStorage(username, request.Title, request.Owner)
+	payload, err := api.DB.CreateStorage(r.Context(), username, request.Title, request.Owner)
 	if err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -37,13 +36,14 @@ func (api *API) createStorage(w http.ResponseWriter, r *http.Request) {
 func (api *API) getStorages(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetStorages(username)
+	options, ok := listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := api.DB.GetStorages(r.Context(), username, options)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -53,19 +53,37 @@ func (api *API) getStorages(w http.ResponseWriter, r *http.Request) {
 func (api *API) getStoragesCount(w http.ResponseWriter, r *http.Request) {
 	username := mux.Vars(r)["username"]
 
-	payload, err := api.DB.GetStoragesCount(username)
+	payload, err := api.DB.GetStoragesCount(r.Context(), username)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
+
+func (api *API) getStorage(w http.ResponseWriter, r *http.Request) {
+	storageIDString := mux.Vars(r)["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+
+	payload, err := api.DB.GetStorage(r.Context(), storageID)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
+	setETag(w, payload.Version)
 	util.WriteJSON(payload, http.StatusOK, w)
 }
 
+// currentStorage reads the storage a stale write is answered with
+func (api *API) currentStorage(r *http.Request, storageID int) func() (interface{}, int, error) {
+	return func() (interface{}, int, error) {
+		folder, err := api.DB.GetStorage(r.Context(), storageID)
+		return folder, folder.Version, err
+	}
+}
+
 func (api *API) updateStorage(w http.ResponseWriter, r *http.Request) {
 	storageIDString := mux.Vars(r)["storage_id"]
 	storageID, _ := strconv.Atoi(storageIDString)
@@ -77,12 +95,13 @@ func (api *API) updateStorage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.DB.UpdateStorage(request.Title, storageID); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.DB.UpdateStorage(r.Context(), request.Title, storageID, version); err != nil {
+		writeWriteError(err, w, r, api.currentStorage(r, storageID))
 		return
 	}
 
@@ -93,12 +112,13 @@ func (api *API) deleteStorage(w http.ResponseWriter, r *http.Request) {
 	storageIDString := mux.Vars(r)["storage_id"]
 	storageID, _ := strconv.Atoi(storageIDString)
 
-	if err := api.DB.DeleteStorage(storageID); err != nil {
-		if strings.Contains(err.Error(), "no rows affected") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	version, ok := versionOrError(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.DB.DeleteStorage(r.Context(), storageID, version); err != nil {
+		writeWriteError(err, w, r, api.currentStorage(r, storageID))
 		return
 	}
 
@@ -113,8 +133,8 @@ func (api *API) shareStorage(w http.ResponseWriter, r *http.Request) {
 
 	usernameRequest := vars["username_request"]
 
-	if err := api.DB.ShareStorage(usernameRequest, storageID); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	if err := api.DB.ShareStorage(r.Context(), usernameRequest, storageID); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
@@ -129,13 +149,9 @@ func (api *API) getStorageOwner(w http.ResponseWriter, r *http.Request) {
 
 	owner := vars["owner"]
 
-	payload, err := api.DB.GetStorageOwner(owner, storageID)
+	payload, err := api.DB.GetStorageOwner(r.Context(), owner, storageID)
 	if err != nil {
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			util.WriteJSON(nil, http.StatusNotFound, w)
-			return
-		}
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+		writeError(err, w, r)
 		return
 	}
 
@@ -150,8 +166,8 @@ func (api *API) removeShareStorageFolder(w http.ResponseWriter, r *http.Request)
 
 	usernameRequest := vars["username_request"]
 
-	if err := api.DB.RemoveShareStorage(usernameRequest, storageID); err != nil {
-		util.WriteJSON(util.Error(err.Error()), http.StatusInternalServerError, w)
+	if err := api.DB.RemoveShareStorage(r.Context(), usernameRequest, storageID); err != nil {
+		writeError(err, w, r)
 		return
 	}
 
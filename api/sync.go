@@ -0,0 +1,67 @@
+package api
+
+import (
+	"cat-clerk-api/database"
+	"cat-clerk-api/util"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// PushRequest is the body of a push: the mutations a client made offline, oldest first
+type PushRequest struct {
+	Mutations []database.Mutation `json:"mutations"`
+}
+
+// PushResponse holds the outcome of each pushed mutation, in order
+type PushResponse struct {
+	Results []MutationResponse `json:"results"`
+}
+
+// MutationResponse is the outcome of a pushed mutation, with the error it was rejected for
+type MutationResponse struct {
+	database.MutationResult
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+func (api *API) getChanges(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	payload, err := api.DB.GetChanges(r.Context(), username, r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
+
+func (api *API) pushChanges(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	request := PushRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		util.WriteJSON(util.Error(err.Error()), http.StatusUnprocessableEntity, w)
+		return
+	}
+
+	result, err := api.DB.PushChanges(r.Context(), username, request.Mutations)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	payload := PushResponse{Results: []MutationResponse{}}
+	for _, mutationResult := range result.Results {
+		response := MutationResponse{MutationResult: mutationResult}
+		if mutationResult.Err != nil {
+			_, errResponse := errorResponse(mutationResult.Err)
+			response.Error = &errResponse
+		}
+		payload.Results = append(payload.Results, response)
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Timeouts holds the time given to a request before its database queries are cancelled.
+// Routes are keyed by route name, which is the name of the route's handler, e.g. getStorageItems.
+type Timeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// For returns the timeout of the route named name
+func (timeouts Timeouts) For(name string) time.Duration {
+	if timeout, ok := timeouts.Routes[name]; ok {
+		return timeout
+	}
+	return timeouts.Default
+}
+
+// ParseRouteTimeouts parses a comma separated list of route=duration pairs, e.g. getStorageItems=2s,deleteAccount=30s
+func ParseRouteTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+
+	if strings.TrimSpace(value) == "" {
+		return timeouts, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid route timeout %q, expected route=duration", pair)
+		}
+
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid duration of route %s: %q", parts[0], parts[1])
+		}
+
+		timeouts[parts[0]] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// timeout bounds the context of each request by the timeout of its route.
+// The context is also cancelled when the client goes away, cancelling the queries made with it.
+func (api *API) timeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			name = route.GetName()
+		}
+
+		timeout := api.Timeouts.For(name)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
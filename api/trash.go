@@ -0,0 +1,69 @@
+package api
+
+import (
+	"cat-clerk-api/util"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (api *API) getTrash(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	payload, err := api.DB.GetTrash(r.Context(), username)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
+
+func (api *API) restoreStorage(w http.ResponseWriter, r *http.Request) {
+	storageIDString := mux.Vars(r)["storage_id"]
+	storageID, _ := strconv.Atoi(storageIDString)
+
+	if err := api.DB.RestoreStorage(r.Context(), storageID); err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(nil, http.StatusNoContent, w)
+}
+
+func (api *API) restoreStorageItem(w http.ResponseWriter, r *http.Request) {
+	itemIDString := mux.Vars(r)["item_id"]
+	itemID, _ := strconv.Atoi(itemIDString)
+
+	if err := api.DB.RestoreStorageItem(r.Context(), itemID); err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(nil, http.StatusNoContent, w)
+}
+
+func (api *API) restoreShoppingList(w http.ResponseWriter, r *http.Request) {
+	shoppingListIDString := mux.Vars(r)["shopping_list_id"]
+	shoppingListID, _ := strconv.Atoi(shoppingListIDString)
+
+	if err := api.DB.RestoreShoppingList(r.Context(), shoppingListID); err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(nil, http.StatusNoContent, w)
+}
+
+func (api *API) restoreShoppingListItem(w http.ResponseWriter, r *http.Request) {
+	itemIDString := mux.Vars(r)["shopping_list_item_id"]
+	itemID, _ := strconv.Atoi(itemIDString)
+
+	if err := api.DB.RestoreShoppingListItem(r.Context(), itemID); err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	util.WriteJSON(nil, http.StatusNoContent, w)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"cat-clerk-api/units"
+	"cat-clerk-api/util"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// convertQuantity converts quantity of the unit from to the unit to, or when to is empty to the unit it reads best in
+// with the unit system of the account
+func (api *API) convertQuantity(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	query := r.URL.Query()
+
+	invalid := func(field string, err error) {
+		util.WriteJSON(ErrorResponse{Error: err.Error(), Code: CodeInvalidValue, Field: field}, http.StatusBadRequest, w)
+	}
+
+	amount, err := strconv.ParseFloat(query.Get("quantity"), 64)
+	if err != nil {
+		invalid("quantity", err)
+		return
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		invalid("quantity", errors.New("quantity must be a finite number"))
+		return
+	}
+
+	q := units.Quantity{Amount: amount, Unit: query.Get("from")}
+	if !units.Known(q.Unit) {
+		invalid("from", units.ErrUnknownUnit)
+		return
+	}
+
+	to := query.Get("to")
+	if to == "" {
+		settings, err := api.DB.GetUnitSetting(r.Context(), username)
+		if err != nil {
+			writeError(err, w, r)
+			return
+		}
+
+		util.WriteJSON(units.Display(q, settings.UnitSystem), http.StatusOK, w)
+		return
+	}
+
+	converted, err := units.Convert(q, to)
+	if err != nil {
+		if errors.Is(err, units.ErrIncompatible) {
+			util.WriteJSON(ErrorResponse{Error: err.Error(), Code: CodeInvalidValue, Field: "to"}, http.StatusUnprocessableEntity, w)
+			return
+		}
+
+		invalid("to", err)
+		return
+	}
+
+	util.WriteJSON(converted, http.StatusOK, w)
+}
+
+// getStorageItemTotals sums the storage items of an account by title and dimension, each total also displayed
+// in the unit system of the account
+func (api *API) getStorageItemTotals(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	settings, err := api.DB.GetUnitSetting(r.Context(), username)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	payload, err := api.DB.GetStorageItemTotals(r.Context(), username)
+	if err != nil {
+		writeError(err, w, r)
+		return
+	}
+
+	for i, total := range payload {
+		display := units.Display(units.Quantity{Amount: total.Quantity, Unit: total.QuantityType}, settings.UnitSystem)
+		payload[i].Display = &display
+	}
+
+	util.WriteJSON(payload, http.StatusOK, w)
+}
+
+// stepOrError reads the by query parameter of the increments and decrements, 1 when unset, or responds 400 and returns false.
+// Whether it fits the quantity type of the item is checked by the Store.
+func stepOrError(w http.ResponseWriter, r *http.Request) (float64, bool) {
+	value := r.URL.Query().Get("by")
+	if value == "" {
+		return 1, true
+	}
+
+	by, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(by > 0) || math.IsInf(by, 0) {
+		util.WriteJSON(ErrorResponse{Error: "by must be a positive number", Code: CodeInvalidValue, Field: "by"}, http.StatusBadRequest, w)
+		return 0, false
+	}
+	return by, true
+}
@@ -37,7 +37,7 @@ func (auth *Auth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	whiteList := []string{"ping", "sign-up", "login", "forgotten-password", "email-exists"}
+	whiteList := []string{"ping", "health", "sign-up", "login", "forgotten-password", "email-exists"}
 	for _, wl := range whiteList {
 		if strings.Contains(r.URL.Path, path+wl) {
 			auth.handler.ServeHTTP(w, r)
@@ -62,6 +62,12 @@ func (auth *Auth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The metrics of the instance are served to any signed in account
+	if r.URL.Path == path+"metrics" {
+		auth.handler.ServeHTTP(w, r)
+		return
+	}
+
 	pathPrefixes := []string{path + "accounts/"} // Add more in this array if you need to whitelist more paths
 	usernamePath, err := getUsernameFromPathPrefixes(r, pathPrefixes)
 
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"cat-clerk-api/database"
+	"context"
+	"fmt"
+	"os"
+)
+
+// backup runs the backup subcommand: backup [file], writing to stdout without a file
+func backup(ctx context.Context, db *database.Handler, args []string) error {
+	if len(args) == 0 {
+		return db.Backup(ctx, os.Stdout)
+	}
+
+	file, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := db.Backup(ctx, file); err != nil {
+		file.Close()
+		os.Remove(args[0])
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("backup written to %s\n", args[0])
+
+	return nil
+}
+
+// restore runs the restore subcommand: restore [file], reading from stdin without a file
+func restore(ctx context.Context, db *database.Handler, args []string) error {
+	input := os.Stdin
+	if len(args) > 0 {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		input = file
+	}
+
+	if err := db.Restore(ctx, input); err != nil {
+		return err
+	}
+
+	version, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("backup restored at schema version %d\n", version)
+
+	return nil
+}
@@ -1,19 +1,49 @@
 package main
 
 import (
+	"cat-clerk-api/database"
 	"flag"
+	"time"
 )
 
 type config struct {
 	APIHost string
 	APIPort int
 
+	RequestTimeout time.Duration
+	RouteTimeouts  string
+
+	DBDriver    string
+	DBPath      string
+	AutoMigrate bool
+
 	DBUser string
 	DBPass string
 	DBName string
 	DBHost string
 	DBPort int
 
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+	DBConnectTimeout  time.Duration
+	DBReadTimeout     time.Duration
+	DBWriteTimeout    time.Duration
+	DBTLS             string
+	DBConnectRetries  int
+	DBRetryDelay      time.Duration
+	DBRetryMaxDelay   time.Duration
+	DBHealthInterval  time.Duration
+
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	AlertInterval time.Duration
+
+	CacheSize int
+	CacheTTL  time.Duration
+
 	HMAC string
 
 	Salt string
@@ -30,12 +60,42 @@ func newConfig() *config {
 	flag.StringVar(&c.APIHost, "api_host", "127.0.0.1", "The API's host.")
 	flag.IntVar(&c.APIPort, "api_port", 80, "The API's port.")
 
+	flag.DurationVar(&c.RequestTimeout, "request_timeout", 10*time.Second, "The time given to a request before its database queries are cancelled, 0 for none.")
+	flag.StringVar(&c.RouteTimeouts, "route_timeouts", "", "Timeouts overriding request_timeout by route name, e.g. getStorageItems=2s,deleteAccount=30s")
+
+	flag.StringVar(&c.DBDriver, "db_driver", "mysql", "The database driver: mysql, postgres or sqlite.")
+	flag.StringVar(&c.DBPath, "db_path", "cat-clerk.db", "The SQLite database file, when db_driver is sqlite.")
+	flag.BoolVar(&c.AutoMigrate, "auto_migrate", false, "Apply pending schema migrations on startup.")
+
 	flag.StringVar(&c.DBUser, "db_user", "root", "The database's username.")
 	flag.StringVar(&c.DBPass, "db_pass", "root", "The database's password.")
 	flag.StringVar(&c.DBName, "db_name", "database", "The database's name.")
 	flag.StringVar(&c.DBHost, "db_host", "127.0.0.1", "The database's host.")
 	flag.IntVar(&c.DBPort, "db_port", 3306, "The database's port.")
 
+	defaults := database.DefaultOptions()
+
+	flag.IntVar(&c.DBMaxOpenConns, "db_max_open_conns", defaults.MaxOpenConns, "The maximum number of open database connections, 0 for no limit.")
+	flag.IntVar(&c.DBMaxIdleConns, "db_max_idle_conns", defaults.MaxIdleConns, "The maximum number of idle database connections.")
+	flag.DurationVar(&c.DBConnMaxLifetime, "db_conn_max_lifetime", defaults.ConnMaxLifetime, "The time after which a database connection is closed, 0 to keep it.")
+	flag.DurationVar(&c.DBConnMaxIdleTime, "db_conn_max_idle_time", defaults.ConnMaxIdleTime, "The idle time after which a database connection is closed, 0 to keep it.")
+	flag.DurationVar(&c.DBConnectTimeout, "db_connect_timeout", defaults.ConnectTimeout, "The time given to connect to the database.")
+	flag.DurationVar(&c.DBReadTimeout, "db_read_timeout", defaults.ReadTimeout, "The I/O read timeout of the database connections, MySQL only.")
+	flag.DurationVar(&c.DBWriteTimeout, "db_write_timeout", defaults.WriteTimeout, "The I/O write timeout of the database connections, MySQL only.")
+	flag.StringVar(&c.DBTLS, "db_tls", defaults.TLS, "The database TLS mode: disable, require or verify.")
+	flag.IntVar(&c.DBConnectRetries, "db_connect_retries", defaults.ConnectRetries, "The number of times to retry connecting to the database on startup.")
+	flag.DurationVar(&c.DBRetryDelay, "db_retry_delay", defaults.RetryDelay, "The delay before the first connection retry, doubled on each retry.")
+	flag.DurationVar(&c.DBRetryMaxDelay, "db_retry_max_delay", defaults.RetryMaxDelay, "The maximum delay between connection retries.")
+	flag.DurationVar(&c.DBHealthInterval, "db_health_interval", defaults.HealthInterval, "The period of the database health probe, 0 to probe on each health request.")
+
+	flag.DurationVar(&c.TrashRetention, "trash_retention", 30*24*time.Hour, "The time deleted storages, shopping lists and items stay in the trash before being purged, 0 to keep them.")
+	flag.DurationVar(&c.TrashPurgeInterval, "trash_purge_interval", time.Hour, "The period of the trash purge.")
+
+	flag.DurationVar(&c.AlertInterval, "alert_interval", 15*time.Minute, "The period of the expiration alerts and of their delivery, 0 to disable them.")
+
+	flag.IntVar(&c.CacheSize, "cache_size", 10000, "The number of foods catalog and container listing pages kept in memory, 0 to disable the cache.")
+	flag.DurationVar(&c.CacheTTL, "cache_ttl", time.Minute, "The time a cached page is served.")
+
 	flag.StringVar(&c.HMAC, "hmac", "", "HMAC secret")
 
 	flag.StringVar(&c.Salt, "salt", "", "Password salt")
@@ -49,3 +109,21 @@ func newConfig() *config {
 
 	return c
 }
+
+// dbOptions returns the database options set by the flags
+func (c *config) dbOptions() database.Options {
+	return database.Options{
+		MaxOpenConns:    c.DBMaxOpenConns,
+		MaxIdleConns:    c.DBMaxIdleConns,
+		ConnMaxLifetime: c.DBConnMaxLifetime,
+		ConnMaxIdleTime: c.DBConnMaxIdleTime,
+		ConnectTimeout:  c.DBConnectTimeout,
+		ReadTimeout:     c.DBReadTimeout,
+		WriteTimeout:    c.DBWriteTimeout,
+		TLS:             c.DBTLS,
+		ConnectRetries:  c.DBConnectRetries,
+		RetryDelay:      c.DBRetryDelay,
+		RetryMaxDelay:   c.DBRetryMaxDelay,
+		HealthInterval:  c.DBHealthInterval,
+	}
+}
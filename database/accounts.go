@@ -1,9 +1,9 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"strings"
+	"errors"
 	"time"
 )
 
@@ -16,60 +16,63 @@ type Account struct {
 	Email         string    `json:"email"`
 	DarkTheme     bool      `json:"datkTheme"`
 	Notifications bool      `json:"notifications"`
+	UnitSystem    string    `json:"unitSystem"`
 	LastLogin     time.Time `json:"lastLogin"`
 	UpdatedAt     time.Time `json:"updatedAt"`
 	CreatedAt     time.Time `json:"createdAt"`
 }
 
+// accountColumns lists the accounts columns in the order scanAccount expects them
+const accountColumns = `id, username, password, salt, email, dark_theme, notifications, unit_system, last_login, updated_at, created_at`
+
+// scanAccount scans a row selected with accountColumns
+func scanAccount(row scanner, acc *Account) error {
+	return row.Scan(
+		&acc.ID,
+		&acc.Username,
+		&acc.Password,
+		&acc.Salt,
+		&acc.Email,
+		&acc.DarkTheme,
+		&acc.Notifications,
+		&acc.UnitSystem,
+		&acc.LastLogin,
+		&acc.UpdatedAt,
+		&acc.CreatedAt,
+	)
+}
+
 // CreateAccount creates a new account in the database
-func (handler *Handler) CreateAccount(username, email, password, salt string) (result sql.Result, err error) {
-	stmt, err := handler.DB.Prepare(`INSERT INTO accounts SET username=?, email=?, password=?, salt=?`)
+func (handler *Handler) CreateAccount(ctx context.Context, username, email, password, salt string) (result sql.Result, err error) {
+	stmt, err := handler.prepare(ctx, `
+		INSERT INTO accounts(username, email, password, salt)
+		VALUES(?, ?, ?, ?)
+	`)
 	if err != nil {
 		return result, err
 	}
 
-	result, err = stmt.Exec(username, email, password, salt)
-	if err != nil {
-		switch {
-		case strings.Contains(err.Error(), "for key 'username'"):
-			return result, fmt.Errorf("username already taken")
-		case strings.Contains(err.Error(), "for key 'email'"):
-			return result, fmt.Errorf("email already taken")
-		default:
-			return result, err
-		}
-	}
+	defer stmt.Close()
 
-	return result, nil
+	return handler.exec(ctx, stmt, username, email, password, salt)
 }
 
 // GetAccount gets the account from the database by username
-func (handler *Handler) GetAccount(username string) (Account, error) {
+func (handler *Handler) GetAccount(ctx context.Context, username string) (Account, error) {
 	acc := Account{}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		SELECT * FROM accounts
-		WHERE username="%s"	
-	`, username))
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+accountColumns+` FROM accounts
+		WHERE username = ?
+	`)
 	if err != nil {
 		return acc, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
-		&acc.ID,
-		&acc.Username,
-		&acc.Password,
-		&acc.Salt,
-		&acc.Email,
-		&acc.DarkTheme,
-		&acc.Notifications,
-		&acc.LastLogin,
-		&acc.UpdatedAt,
-		&acc.CreatedAt,
-	); err != nil {
-		return acc, err
+	if err := scanAccount(stmt.QueryRowContext(ctx, username), &acc); err != nil {
+		return acc, rowError(err)
 	}
 
 	return acc, err
@@ -81,23 +84,23 @@ type Email struct {
 }
 
 // GetAccountEmail gets the account's email from the database by username
-func (handler *Handler) GetAccountEmail(username string) (Email, error) {
+func (handler *Handler) GetAccountEmail(ctx context.Context, username string) (Email, error) {
 	payload := Email{}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+	stmt, err := handler.prepare(ctx, `
 		SELECT email FROM accounts
-		WHERE username="%s"	
-	`, username))
+		WHERE username = ?
+	`)
 	if err != nil {
 		return payload, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
+	if err := stmt.QueryRowContext(ctx, username).Scan(
 		&payload.Email,
 	); err != nil {
-		return payload, err
+		return payload, rowError(err)
 	}
 
 	return payload, err
@@ -110,35 +113,36 @@ type UsernameEmail struct {
 }
 
 // EmailExists gets the account's email from the database by username
-func (handler *Handler) EmailExists(email string) (UsernameEmail, error) {
+func (handler *Handler) EmailExists(ctx context.Context, email string) (UsernameEmail, error) {
 	payload := UsernameEmail{}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+	stmt, err := handler.prepare(ctx, `
 		SELECT username, email FROM accounts
-		WHERE email="%s"	
-	`, email))
+		WHERE email = ?
+	`)
 	if err != nil {
 		return payload, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
+	if err := stmt.QueryRowContext(ctx, email).Scan(
 		&payload.Username,
 		&payload.Email,
 	); err != nil {
-		return payload, err
+		return payload, rowError(err)
 	}
 
 	return payload, err
 }
 
 // GetAccounts gets all accounts from the database by username
-func (handler *Handler) GetAccounts() ([]Account, error) {
+func (handler *Handler) GetAccounts(ctx context.Context) ([]Account, error) {
 	accounts := []Account{}
 
-	stmt, err := handler.DB.Prepare(`
-		SELECT * FROM accounts
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+accountColumns+` FROM accounts
+		ORDER BY id
 	`)
 	if err != nil {
 		return accounts, err
@@ -146,26 +150,17 @@ func (handler *Handler) GetAccounts() ([]Account, error) {
 
 	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
 		return accounts, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		acc := Account{}
 
-		if err := rows.Scan(
-			&acc.ID,
-			&acc.Username,
-			&acc.Password,
-			&acc.Salt,
-			&acc.Email,
-			&acc.DarkTheme,
-			&acc.Notifications,
-			&acc.LastLogin,
-			&acc.UpdatedAt,
-			&acc.CreatedAt,
-		); err != nil {
+		if err := scanAccount(rows, &acc); err != nil {
 			return accounts, err
 		}
 
@@ -180,56 +175,46 @@ func (handler *Handler) GetAccounts() ([]Account, error) {
 }
 
 // CheckAccountCredentials verifies the accounts credentials by username or email
-func (handler *Handler) CheckAccountCredentials(username, email string) (Account, error) {
+func (handler *Handler) CheckAccountCredentials(ctx context.Context, username, email string) (Account, error) {
 	login := Account{}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		SELECT * FROM accounts 
-		WHERE (username="%s" OR email="%s")	
-	`, username, email))
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+accountColumns+` FROM accounts
+		WHERE (username = ? OR email = ?)
+		ORDER BY id
+	`)
 	if err != nil {
 		return login, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
-		&login.ID,
-		&login.Username,
-		&login.Password,
-		&login.Salt,
-		&login.Email,
-		&login.DarkTheme,
-		&login.Notifications,
-		&login.LastLogin,
-		&login.UpdatedAt,
-		&login.CreatedAt,
-	); err != nil {
-		return login, err
+	if err := scanAccount(stmt.QueryRowContext(ctx, username, email), &login); err != nil {
+		return login, rowError(err)
 	}
 
 	return login, nil
 }
 
 // UpdateAccount updates all account data fields in the database by username
-func (handler *Handler) UpdateAccount(username, newUsername, password, email string, darkTheme, notifications bool) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		UPDATE accounts AS a
-		SET 
-			a.username = "%s",
-			a.password = "%s",
-			a.email = "%s",
-			a.dark_theme = %t,
-			a.notifications = %t
-		WHERE a.username = "%s";
-	`, newUsername, password, email, darkTheme, notifications, username))
+func (handler *Handler) UpdateAccount(ctx context.Context, username, newUsername, password, email string, darkTheme, notifications bool) error {
+	stmt, err := handler.prepare(ctx, `
+		UPDATE accounts
+		SET
+			username = ?,
+			password = ?,
+			email = ?,
+			dark_theme = ?,
+			notifications = ?
+		WHERE username = ?
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, newUsername, password, email, darkTheme, notifications, username)
 	if err != nil {
 		return err
 	}
@@ -240,26 +225,26 @@ func (handler *Handler) UpdateAccount(username, newUsername, password, email str
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return errNoRowsAffected
 	}
 
 	return err
 }
 
 // UpdateAccountUsername updates the accounts username by current username
-func (handler *Handler) UpdateAccountUsername(username, newUsername string) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+func (handler *Handler) UpdateAccountUsername(ctx context.Context, username, newUsername string) error {
+	stmt, err := handler.prepare(ctx, `
 		UPDATE accounts
-		SET username = "%s"
-		WHERE username = "%s";
-	`, newUsername, username))
+		SET username = ?
+		WHERE username = ?
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, newUsername, username)
 	if err != nil {
 		return err
 	}
@@ -270,27 +255,26 @@ func (handler *Handler) UpdateAccountUsername(username, newUsername string) erro
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return errNoRowsAffected
 	}
 
 	return err
 }
 
 // UpdateAccountEmail updates the accounts email by username
-func (handler *Handler) UpdateAccountEmail(username, email string) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+func (handler *Handler) UpdateAccountEmail(ctx context.Context, username, email string) error {
+	stmt, err := handler.prepare(ctx, `
 		UPDATE accounts
-		SET 
-			email = "%s"
-		WHERE username = "%s";
-	`, email, username))
+		SET email = ?
+		WHERE username = ?
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, email, username)
 	if err != nil {
 		return err
 	}
@@ -301,28 +285,28 @@ func (handler *Handler) UpdateAccountEmail(username, email string) error {
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return errNoRowsAffected
 	}
 
 	return err
 }
 
 // UpdateAccountPassword updates the accounts password by username
-func (handler *Handler) UpdateAccountPassword(username, password, salt string) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+func (handler *Handler) UpdateAccountPassword(ctx context.Context, username, password, salt string) error {
+	stmt, err := handler.prepare(ctx, `
 		UPDATE accounts
-		SET 
-			password = "%s",
-			salt = "%s"
-		WHERE username = "%s";
-	`, password, salt, username))
+		SET
+			password = ?,
+			salt = ?
+		WHERE username = ?
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, password, salt, username)
 	if err != nil {
 		return err
 	}
@@ -333,37 +317,227 @@ func (handler *Handler) UpdateAccountPassword(username, password, salt string) e
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return errNoRowsAffected
 	}
 
 	return err
 }
 
-// DeleteAccount deletes the account by username
-func (handler *Handler) DeleteAccount(username string) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	DELETE FROM accounts AS a
-	WHERE a.username = %s
-	`, username))
+// DeleteAccount deletes the account by username in one transaction, along with its share requests,
+// the storages and shopping lists it owns and its attachments to the ones shared with it
+func (handler *Handler) DeleteAccount(ctx context.Context, username string) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		storageIDs, err := tx.ownedIDs(ctx, `
+			SELECT storage_id FROM account_storage_binder
+			WHERE account_id = `+accountIDOf+` AND owner = ?
+		`, username)
+		if err != nil {
+			return err
+		}
+
+		shoppingListIDs, err := tx.ownedIDs(ctx, `
+			SELECT shopping_list_id FROM account_shopping_list_binder
+			WHERE account_id = `+accountIDOf+` AND owner = ?
+		`, username)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.recordAccountTombstones(ctx, username, storageIDs, shoppingListIDs); err != nil {
+			return err
+		}
+
+		if _, err := tx.run(ctx, `
+			DELETE FROM share_requests
+			WHERE from_account_id = `+accountIDOf+` OR to_account_id = `+accountIDOf+`
+		`, username, username); err != nil {
+			return err
+		}
+
+		// Storage items and binders are deleted along with their storage
+		for _, storageID := range storageIDs {
+			if _, err := tx.run(ctx, `
+				DELETE FROM storages
+				WHERE id = ?
+			`, storageID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.run(ctx, `
+			DELETE FROM account_storage_binder
+			WHERE account_id = `+accountIDOf+`
+		`, username); err != nil {
+			return err
+		}
+
+		for _, shoppingListID := range shoppingListIDs {
+			if _, err := tx.run(ctx, `
+				DELETE FROM shopping_list_items
+				WHERE shopping_list_id = ?
+			`, shoppingListID); err != nil {
+				return err
+			}
+
+			if _, err := tx.run(ctx, `
+				DELETE FROM shopping_lists
+				WHERE id = ?
+			`, shoppingListID); err != nil {
+				return err
+			}
+		}
+
+		// Settings, the remaining shopping list binders, tombstones and pushed mutations are deleted along with
+		// the account, and its item history events are left without an actor
+		result, err := tx.run(ctx, `
+			DELETE FROM accounts
+			WHERE username = ?
+		`, username)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected < 1 {
+			return errNoRowsAffected
+		}
+
+		return err
+	})
+}
+
+// recordAccountTombstones leaves the other accounts tombstones of what goes away with the account username:
+// the storages and shopping lists it owns and the share requests it sent
+func (handler *Handler) recordAccountTombstones(ctx context.Context, username string, storageIDs, shoppingListIDs []int) error {
+	for _, storageID := range storageIDs {
+		collaborators, err := handler.usernames(ctx, `
+			SELECT a.username
+			FROM account_storage_binder AS asb
+			INNER JOIN accounts AS a
+			ON a.id = asb.account_id
+			WHERE asb.storage_id = ? AND a.username <> ?
+		`, storageID, username)
+		if err != nil {
+			return err
+		}
+
+		if err := handler.recordTombstones(ctx, collaborators, KindStorage, storageID, TombstoneDeleted); err != nil {
+			return err
+		}
+	}
+
+	for _, shoppingListID := range shoppingListIDs {
+		collaborators, err := handler.usernames(ctx, `
+			SELECT a.username
+			FROM account_shopping_list_binder AS aslb
+			INNER JOIN accounts AS a
+			ON a.id = aslb.account_id
+			WHERE aslb.shopping_list_id = ? AND a.username <> ?
+		`, shoppingListID, username)
+		if err != nil {
+			return err
+		}
+
+		if err := handler.recordTombstones(ctx, collaborators, KindShoppingList, shoppingListID, TombstoneDeleted); err != nil {
+			return err
+		}
+	}
+
+	shareIDs, err := handler.ids(ctx, `
+		SELECT id
+		FROM share_requests
+		WHERE from_account_id = `+accountIDOf+` AND to_account_id <> from_account_id
+	`, username)
 	if err != nil {
 		return err
 	}
 
+	for _, shareID := range shareIDs {
+		recipients, err := handler.usernames(ctx, `
+			SELECT a.username
+			FROM share_requests AS sr
+			INNER JOIN accounts AS a
+			ON a.id = sr.to_account_id
+			WHERE sr.id = ?
+		`, shareID)
+		if err != nil {
+			return err
+		}
+
+		if err := handler.recordTombstones(ctx, recipients, KindShareRequest, shareID, TombstoneDeleted); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// accountIDOf is the subquery selecting the ID of the account whose username is its argument,
+// as the other tables refer to accounts by ID
+const accountIDOf = `(SELECT id FROM accounts WHERE username = ?)`
+
+// accountID returns the ID of the account username, a missing one failing the rows to refer to it
+func (handler *Handler) accountID(ctx context.Context, username string) (int, error) {
+	id := 0
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT id FROM accounts
+		WHERE username = ?
+	`)
+	if err != nil {
+		return id, err
+	}
+
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	if err := stmt.QueryRowContext(ctx, username).Scan(
+		&id,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return id, errForeignKeyChild
+		}
+		return id, err
+	}
+
+	return id, nil
+}
+
+// ownedIDs returns the container IDs selected by query from a binder table for username, restricted to owners
+func (handler *Handler) ownedIDs(ctx context.Context, query, username string) ([]int, error) {
+	return handler.ids(ctx, query, username, true)
+}
+
+// ids returns the IDs selected by query
+func (handler *Handler) ids(ctx context.Context, query string, args ...interface{}) ([]int, error) {
+	ids := []int{}
+
+	stmt, err := handler.prepare(ctx, query)
 	if err != nil {
-		return err
+		return ids, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
-		return err
+		return ids, err
 	}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+	defer rows.Close()
+
+	for rows.Next() {
+		id := 0
+
+		if err := rows.Scan(&id); err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, id)
 	}
 
-	return err
+	return ids, rows.Err()
 }
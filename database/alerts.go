@@ -0,0 +1,323 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// The stages of the alerts raised for a storage item, each once until the item leaves it
+const (
+	// AlertExpiring is raised once the item is within its expiration threshold, in days, of its expiration date
+	AlertExpiring = "expiring"
+	// AlertExpired is raised once the expiration date of the item is past
+	AlertExpired = "expired"
+	// AlertLowStock is raised once a write drops the quantity of the item to or below its quantity threshold,
+	// and again only after a restock above it
+	AlertLowStock = "low_stock"
+)
+
+// AlertDelivery is an alert to send to one of the accounts attached to the storage of its item
+type AlertDelivery struct {
+	ID             int     `json:"id"`
+	AlertID        int     `json:"alertID"`
+	Stage          string  `json:"stage"`
+	Username       string  `json:"username"`
+	Email          string  `json:"email"`
+	StorageID      int     `json:"storageID"`
+	StorageTitle   string  `json:"storageTitle"`
+	ItemID         int     `json:"itemID"`
+	ItemTitle      string  `json:"itemTitle"`
+	Quantity       float64 `json:"quantity"`
+	QuantityType   string  `json:"quantityType"`
+	ExpirationDate string  `json:"expirationDate"`
+}
+
+// itemAlert is an alert stage reached by a storage item
+type itemAlert struct {
+	itemID int
+	stage  string
+}
+
+// expirationStage returns the stage a storage item has reached at now and is still to be alerted of, if any.
+// An item found past its expiration date is only alerted of that, the stage before it is skipped.
+func expirationStage(expirationDate string, expirationThreshold int, expiringRaised bool, now time.Time) string {
+	date, ok := parseExpirationDate(expirationDate)
+
+	switch {
+	case !ok:
+		return ""
+	case !now.Before(date):
+		return AlertExpired
+	case !expiringRaised && expirationThreshold > 0 && !now.Before(date.AddDate(0, 0, -expirationThreshold)):
+		return AlertExpiring
+	}
+
+	return ""
+}
+
+// lowStock tells whether a write taking a storage item from before to after drops it to or below its quantity threshold,
+// which lowering its quantity does even when it was there already, or restocks it above.
+// The quantities are compared in the base unit, a write may change the quantity type.
+func lowStock(before, after *Item) (dropped, restocked bool) {
+	low := after.Quantity <= after.QuantityThreshold
+	wasLow := before.Quantity <= before.QuantityThreshold
+	lowered := baseQuantity(after.Quantity, after.QuantityType) < baseQuantity(before.Quantity, before.QuantityType)
+
+	return low && (!wasLow || lowered), !low
+}
+
+// RaiseExpirationAlerts raises the alerts of the storage items which reached a stage of their expiration at now,
+// out of the trash, and returns how many it raised. Each alert is delivered to the accounts attached
+// to the storage of its item which turned notifications on.
+func (handler *Handler) RaiseExpirationAlerts(ctx context.Context, now time.Time) (int, error) {
+	raised := 0
+
+	err := handler.withTx(ctx, func(tx *Handler) error {
+		due := []itemAlert{}
+
+		// Items without an expiration date are not after the zero time, see byExpirationDate
+		if err := tx.each(ctx, `
+			SELECT si.id, si.expiration_threshold, si.expiration_date, (
+				SELECT COUNT(*)
+				FROM item_alerts AS ia
+				WHERE ia.item_id = si.id AND ia.stage = ?
+			)
+			FROM storage_items AS si
+			INNER JOIN storages AS s
+			ON s.id = si.storage_id
+			WHERE si.deleted_at IS NULL AND s.deleted_at IS NULL AND si.expiration_date > ?
+			AND NOT EXISTS (
+				SELECT 1
+				FROM item_alerts AS ia
+				WHERE ia.item_id = si.id AND ia.stage = ?
+			)
+			ORDER BY si.id
+		`, []interface{}{AlertExpiring, time.Time{}, AlertExpired}, func(row scanner) error {
+			itemID, expirationThreshold, expiring := 0, 0, 0
+			expirationDate := sql.NullString{}
+
+			if err := row.Scan(
+				&itemID,
+				&expirationThreshold,
+				&expirationDate,
+				&expiring,
+			); err != nil {
+				return err
+			}
+
+			if stage := expirationStage(expirationDate.String, expirationThreshold, expiring > 0, now); stage != "" {
+				due = append(due, itemAlert{itemID: itemID, stage: stage})
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, alert := range due {
+			if err := tx.raiseAlert(ctx, alert.itemID, alert.stage); err != nil {
+				return err
+			}
+		}
+
+		raised = len(due)
+
+		return nil
+	})
+
+	return raised, err
+}
+
+// raiseAlert raises the alert stage of the storage item itemID for the accounts attached to its storage
+// which turned notifications on
+func (handler *Handler) raiseAlert(ctx context.Context, itemID int, stage string) error {
+	alertID, err := handler.insert(ctx, `
+		INSERT INTO item_alerts(item_id, stage)
+		VALUES(?, ?)
+	`, itemID, stage)
+	if err != nil {
+		return err
+	}
+
+	_, err = handler.run(ctx, `
+		INSERT INTO item_alert_deliveries(alert_id, account_id)
+		SELECT ?, a.id
+		FROM account_storage_binder AS asb
+		INNER JOIN storage_items AS si
+		ON si.storage_id = asb.storage_id
+		INNER JOIN accounts AS a
+		ON a.id = asb.account_id
+		WHERE si.id = ? AND a.notifications = ?
+	`, alertID, itemID, true)
+
+	return err
+}
+
+// checkStock raises the low stock alert of a storage item written from before to after when the write drops it
+// to or below its quantity threshold and the alert is not raised already, and clears the alert once it is restocked
+func (handler *Handler) checkStock(ctx context.Context, before, after *Item) error {
+	dropped, restocked := lowStock(before, after)
+
+	switch {
+	case restocked:
+		return handler.clearItemAlerts(ctx, after.ID, AlertLowStock)
+	case dropped:
+		raised, err := handler.itemAlertRaised(ctx, after.ID, AlertLowStock)
+		if err != nil || raised {
+			return err
+		}
+
+		return handler.raiseAlert(ctx, after.ID, AlertLowStock)
+	}
+
+	return nil
+}
+
+// itemAlertRaised reports whether the storage item itemID reached stage
+func (handler *Handler) itemAlertRaised(ctx context.Context, itemID int, stage string) (bool, error) {
+	stmt, err := handler.prepare(ctx, `
+		SELECT COUNT(*)
+		FROM item_alerts
+		WHERE item_id = ? AND stage = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	count := 0
+
+	if err := stmt.QueryRowContext(ctx, itemID, stage).Scan(
+		&count,
+	); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// clearItemAlerts drops the alerts of the storage item itemID at stages, along with their deliveries,
+// so that the item can reach them again
+func (handler *Handler) clearItemAlerts(ctx context.Context, itemID int, stages ...string) error {
+	for _, stage := range stages {
+		if _, err := handler.run(ctx, `
+			DELETE FROM item_alerts
+			WHERE item_id = ? AND stage = ?
+		`, itemID, stage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPendingAlertDeliveries gets the alert deliveries not delivered yet after the delivery ID after, oldest first.
+// The deliveries which can no longer be made, as their account turned notifications off, their item or its storage
+// is in the trash or the account left the storage, are marked as dropped instead.
+func (handler *Handler) GetPendingAlertDeliveries(ctx context.Context, after, limit int) ([]AlertDelivery, error) {
+	deliveries := []AlertDelivery{}
+
+	for {
+		dropped, last := []int{}, 0
+
+		if err := handler.each(ctx, `
+			SELECT iad.id, ia.id, ia.stage, a.username, a.email, s.id, s.title, si.id, si.title, si.quantity, si.quantity_type, si.expiration_date,
+				CASE WHEN a.notifications = ? AND si.deleted_at IS NULL AND s.deleted_at IS NULL AND EXISTS (
+					SELECT 1
+					FROM account_storage_binder AS asb
+					WHERE asb.account_id = iad.account_id AND asb.storage_id = s.id
+				) THEN 1 ELSE 0 END
+			FROM item_alert_deliveries AS iad
+			INNER JOIN item_alerts AS ia
+			ON ia.id = iad.alert_id
+			INNER JOIN accounts AS a
+			ON a.id = iad.account_id
+			INNER JOIN storage_items AS si
+			ON si.id = ia.item_id
+			INNER JOIN storages AS s
+			ON s.id = si.storage_id
+			WHERE iad.delivered_at IS NULL AND iad.dropped_at IS NULL AND iad.id > ?
+			ORDER BY iad.id
+			LIMIT ?
+		`, []interface{}{true, after, pageLimit(limit)}, func(row scanner) error {
+			delivery := AlertDelivery{}
+			expirationDate := sql.NullString{}
+			deliverable := 0
+
+			if err := row.Scan(
+				&delivery.ID,
+				&delivery.AlertID,
+				&delivery.Stage,
+				&delivery.Username,
+				&delivery.Email,
+				&delivery.StorageID,
+				&delivery.StorageTitle,
+				&delivery.ItemID,
+				&delivery.ItemTitle,
+				&delivery.Quantity,
+				&delivery.QuantityType,
+				&expirationDate,
+				&deliverable,
+			); err != nil {
+				return err
+			}
+
+			last = delivery.ID
+
+			if deliverable == 0 {
+				dropped = append(dropped, delivery.ID)
+				return nil
+			}
+
+			delivery.ExpirationDate = expirationDate.String
+
+			deliveries = append(deliveries, delivery)
+
+			return nil
+		}); err != nil {
+			return deliveries, err
+		}
+
+		for _, id := range dropped {
+			if _, err := handler.run(ctx, `
+				UPDATE item_alert_deliveries
+				SET dropped_at = CURRENT_TIMESTAMP
+				WHERE id = ?
+			`, id); err != nil {
+				return deliveries, err
+			}
+		}
+
+		// A page of dropped deliveries only is not the last one
+		if len(deliveries) > 0 || len(dropped) == 0 {
+			return deliveries, nil
+		}
+
+		after = last
+	}
+}
+
+// MarkAlertDelivered marks an alert delivery as delivered by ID
+func (handler *Handler) MarkAlertDelivered(ctx context.Context, deliveryID int) error {
+	result, err := handler.run(ctx, `
+		UPDATE item_alert_deliveries
+		SET delivered_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND delivered_at IS NULL
+	`, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected < 1 {
+		return errNoRowsAffected
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpirationStage(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                string
+		expirationDate      string
+		expirationThreshold int
+		expiringRaised      bool
+		want                string
+	}{
+		{name: "no date", expirationDate: "", expirationThreshold: 3, want: ""},
+		{name: "unreadable date", expirationDate: "soon", expirationThreshold: 3, want: ""},
+		{name: "far from its date", expirationDate: "2024-03-20", expirationThreshold: 3, want: ""},
+		{name: "within the threshold", expirationDate: "2024-03-12", expirationThreshold: 3, want: AlertExpiring},
+		{name: "on the first day of the threshold", expirationDate: "2024-03-13 12:00:00", expirationThreshold: 3, want: AlertExpiring},
+		{name: "before the first day of the threshold", expirationDate: "2024-03-13 12:00:01", expirationThreshold: 3, want: ""},
+		{name: "expiring already raised", expirationDate: "2024-03-12", expirationThreshold: 3, expiringRaised: true, want: ""},
+		{name: "no threshold", expirationDate: "2024-03-11", expirationThreshold: 0, want: ""},
+		{name: "on its date", expirationDate: "2024-03-10T12:00:00Z", expirationThreshold: 3, want: AlertExpired},
+		{name: "past its date", expirationDate: "2024-03-01", expirationThreshold: 3, want: AlertExpired},
+		{name: "past its date with expiring raised", expirationDate: "2024-03-01", expirationThreshold: 3, expiringRaised: true, want: AlertExpired},
+		{name: "past its date without threshold", expirationDate: "2024-03-01", expirationThreshold: 0, want: AlertExpired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := expirationStage(test.expirationDate, test.expirationThreshold, test.expiringRaised, now); got != test.want {
+				t.Errorf("expirationStage(%q, %d, %v) = %q, want %q", test.expirationDate, test.expirationThreshold, test.expiringRaised, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLowStock(t *testing.T) {
+	item := func(quantity float64, quantityType string, threshold float64) *Item {
+		return &Item{Quantity: quantity, QuantityType: quantityType, QuantityThreshold: threshold}
+	}
+
+	tests := []struct {
+		name          string
+		before, after *Item
+		wantDropped   bool
+		wantRestocked bool
+	}{
+		{name: "stays above", before: item(5, "pieces", 2), after: item(4, "pieces", 2), wantRestocked: true},
+		{name: "drops to the threshold", before: item(5, "pieces", 2), after: item(2, "pieces", 2), wantDropped: true},
+		{name: "drops below the threshold", before: item(5, "pieces", 2), after: item(1, "pieces", 2), wantDropped: true},
+		{name: "lowered while low", before: item(2, "pieces", 2), after: item(1, "pieces", 2), wantDropped: true},
+		{name: "raised while still low", before: item(1, "pieces", 2), after: item(2, "pieces", 2)},
+		{name: "unchanged while low", before: item(1, "pieces", 2), after: item(1, "pieces", 2)},
+		{name: "restocked", before: item(1, "pieces", 2), after: item(3, "pieces", 2), wantRestocked: true},
+		{name: "threshold raised above the quantity", before: item(3, "pieces", 2), after: item(3, "pieces", 4), wantDropped: true},
+		{name: "threshold lowered below the quantity", before: item(3, "pieces", 4), after: item(3, "pieces", 2), wantRestocked: true},
+		{name: "empty without threshold", before: item(1, "pieces", 0), after: item(0, "pieces", 0), wantDropped: true},
+		{name: "same quantity in a smaller unit", before: item(2, "kilos", 0.5), after: item(2000, "grams", 500), wantRestocked: true},
+		{name: "lowered in a smaller unit while low", before: item(0.5, "kilos", 0.5), after: item(400, "grams", 500), wantDropped: true},
+		{name: "same low quantity in a smaller unit", before: item(0.5, "kilos", 0.5), after: item(500, "grams", 500)},
+		{name: "fractional drop", before: item(0.75, "liters", 0.5), after: item(0.5, "liters", 0.5), wantDropped: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dropped, restocked := lowStock(test.before, test.after)
+			if dropped != test.wantDropped || restocked != test.wantRestocked {
+				t.Errorf("lowStock() = %v, %v, want %v, %v", dropped, restocked, test.wantDropped, test.wantRestocked)
+			}
+		})
+	}
+}
+
+func TestGetPendingAlertDeliveries(t *testing.T) {
+	for _, s := range testStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for _, username := range []string{"ann", "bob", "cid"} {
+				if _, err := s.store.CreateAccount(ctx, username, username+"@example.com", "password", "salt"); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.store.UpdateAccount(ctx, username, username, "password", username+"@example.com", false, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fridgeID, err := s.store.CreateStorage(ctx, "ann", "Fridge", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cellarID, err := s.store.CreateStorage(ctx, "ann", "Cellar", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, share := range []struct {
+				username  string
+				storageID int64
+			}{{"bob", fridgeID}, {"cid", fridgeID}, {"bob", cellarID}} {
+				if err := s.store.ShareStorage(ctx, share.username, int(share.storageID)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := s.store.CreateStorageItem(ctx, int(fridgeID), "Milk", 1, "pieces", 0, 0, "2020-01-01"); err != nil {
+				t.Fatal(err)
+			}
+			jamID, err := s.store.CreateStorageItem(ctx, int(cellarID), "Jam", 1, "pieces", 0, 0, "2020-01-01")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.store.RaiseExpirationAlerts(ctx, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+
+			pending := func(limit int) []string {
+				deliveries, err := s.store.GetPendingAlertDeliveries(ctx, 0, limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := []string{}
+				for _, delivery := range deliveries {
+					got = append(got, delivery.Username+" "+delivery.ItemTitle)
+				}
+				return got
+			}
+
+			if got, want := pending(10), []string{"ann Milk", "bob Milk", "cid Milk", "ann Jam", "bob Jam"}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("pending deliveries = %v, want %v", got, want)
+			}
+
+			if err := s.store.UpdateAccount(ctx, "ann", "ann", "password", "ann@example.com", false, false); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.store.RemoveShareStorage(ctx, "cid", int(fridgeID)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.store.DeleteStorageItem(ctx, int(jamID), AnyVersion); err != nil {
+				t.Fatal(err)
+			}
+
+			// The deliveries dropped first do not end the pages
+			if got, want := pending(1), []string{"bob Milk"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("first pending delivery = %v, want %v", got, want)
+			}
+			if got, want := pending(10), []string{"bob Milk"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("pending deliveries = %v, want %v", got, want)
+			}
+
+			// Dropped deliveries are not made once they could be again
+			if err := s.store.UpdateAccount(ctx, "ann", "ann", "password", "ann@example.com", false, true); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := pending(10), []string{"bob Milk"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("pending deliveries once notified again = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,633 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ArchiveVersion is the version of the Archive layout written by ExportAccount.
+// ImportAccount reads the archives of this version and the earlier ones.
+const ArchiveVersion = 1
+
+// archivePageSize is the page size an export reads the listings with
+const archivePageSize = 200
+
+// Statuses of an ImportedRow
+const (
+	// ImportCreated marks a row created by the import
+	ImportCreated = "created"
+	// ImportDuplicate marks a row the account already had, by title, which the import left as is
+	ImportDuplicate = "duplicate"
+	// ImportSkipped marks a storage or shopping list shared with the account, which belongs to its owner
+	ImportSkipped = "skipped"
+)
+
+// Archive is a copy of the data of an account, as exported
+type Archive struct {
+	Version       int                   `json:"version"`
+	ExportedAt    time.Time             `json:"exportedAt"`
+	Account       ArchiveAccount        `json:"account"`
+	Storages      []ArchiveStorage      `json:"storages"`
+	ShoppingLists []ArchiveShoppingList `json:"shoppingLists"`
+	// ShareRequests are the pending share requests sent to the account
+	ShareRequests []ShareRequest `json:"shareRequests"`
+}
+
+// ArchiveAccount is the profile and settings of an archived account, without its credentials
+type ArchiveAccount struct {
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	DarkTheme     bool      `json:"darkTheme"`
+	Notifications bool      `json:"notifications"`
+	UnitSystem    string    `json:"unitSystem,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// ArchiveStorage is an archived storage along with its items and the accounts it is shared with
+type ArchiveStorage struct {
+	Storage       Folder        `json:"storage"`
+	Owner         bool          `json:"owner"`
+	Collaborators []string      `json:"collaborators"`
+	Items         []ArchiveItem `json:"items"`
+}
+
+// ArchiveItem is an archived storage item along with its history, latest first
+type ArchiveItem struct {
+	Item    Item        `json:"item"`
+	History []ItemEvent `json:"history"`
+}
+
+// ArchiveShoppingList is an archived shopping list along with its items and the accounts it is shared with
+type ArchiveShoppingList struct {
+	ShoppingList  ShoppingList       `json:"shoppingList"`
+	Owner         bool               `json:"owner"`
+	Collaborators []string           `json:"collaborators"`
+	Items         []ShoppingListItem `json:"items"`
+}
+
+// ImportReport tells what an import made of each row of an archive
+type ImportReport struct {
+	Storages          []ImportedRow `json:"storages"`
+	StorageItems      []ImportedRow `json:"storageItems"`
+	ShoppingLists     []ImportedRow `json:"shoppingLists"`
+	ShoppingListItems []ImportedRow `json:"shoppingListItems"`
+	// ShareRequests is the number of share requests sent to the collaborators of the archive
+	ShareRequests int `json:"shareRequests"`
+}
+
+// ImportedRow maps the ID of a row of an archive to the ID of the row it was imported as,
+// the existing row for a duplicate and 0 when skipped
+type ImportedRow struct {
+	ID     int    `json:"id"`
+	NewID  int    `json:"newID"`
+	Status string `json:"status"`
+}
+
+// exportAccount reads the archive of username in one transaction, using only Store operations so every Store shares it
+func exportAccount(ctx context.Context, store Store, username string) (Archive, error) {
+	archive := Archive{
+		Version:       ArchiveVersion,
+		ExportedAt:    time.Now().UTC(),
+		Storages:      []ArchiveStorage{},
+		ShoppingLists: []ArchiveShoppingList{},
+		ShareRequests: []ShareRequest{},
+	}
+
+	err := store.WithTx(ctx, func(tx Store) error {
+		acc, err := tx.GetAccount(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		archive.Account = ArchiveAccount{
+			Username:      acc.Username,
+			Email:         acc.Email,
+			DarkTheme:     acc.DarkTheme,
+			Notifications: acc.Notifications,
+			UnitSystem:    acc.UnitSystem,
+			CreatedAt:     acc.CreatedAt,
+		}
+
+		storages, err := allStorages(ctx, tx, username)
+		if err != nil {
+			return err
+		}
+
+		for _, storage := range storages {
+			archived, err := exportStorage(ctx, tx, username, storage)
+			if err != nil {
+				return err
+			}
+			archive.Storages = append(archive.Storages, archived)
+		}
+
+		shoppingLists, err := allShoppingLists(ctx, tx, username)
+		if err != nil {
+			return err
+		}
+
+		for _, shoppingList := range shoppingLists {
+			archived, err := exportShoppingList(ctx, tx, username, shoppingList)
+			if err != nil {
+				return err
+			}
+			archive.ShoppingLists = append(archive.ShoppingLists, archived)
+		}
+
+		shareRequests, err := tx.GetShareRequests(ctx, username)
+		if err != nil {
+			return err
+		}
+		archive.ShareRequests = append(archive.ShareRequests, shareRequests...)
+
+		return nil
+	})
+
+	return archive, err
+}
+
+// exportStorage archives storage along with its items, their history and its collaborators
+func exportStorage(ctx context.Context, store Store, username string, storage Folder) (ArchiveStorage, error) {
+	archived := ArchiveStorage{Storage: storage, Items: []ArchiveItem{}}
+
+	share, err := store.GetStorageOwner(ctx, username, storage.ID)
+	if err != nil {
+		return archived, err
+	}
+	archived.Owner = share.Owner == 1
+
+	collaborators, err := store.GetStorageCollaborators(ctx, storage.ID)
+	if err != nil {
+		return archived, err
+	}
+	archived.Collaborators = others(collaborators, username)
+
+	items, err := allStorageItems(ctx, store, storage.ID)
+	if err != nil {
+		return archived, err
+	}
+
+	for _, item := range items {
+		archivedItem := ArchiveItem{Item: item, History: []ItemEvent{}}
+
+		for cursor := 0; ; {
+			history, err := store.GetStorageItemHistory(ctx, storage.ID, item.ID, cursor, archivePageSize)
+			if err != nil {
+				return archived, err
+			}
+			archivedItem.History = append(archivedItem.History, history.Events...)
+
+			if history.NextCursor == 0 {
+				break
+			}
+			cursor = history.NextCursor
+		}
+
+		archived.Items = append(archived.Items, archivedItem)
+	}
+
+	return archived, nil
+}
+
+// exportShoppingList archives shoppingList along with its items and its collaborators
+func exportShoppingList(ctx context.Context, store Store, username string, shoppingList ShoppingList) (ArchiveShoppingList, error) {
+	archived := ArchiveShoppingList{ShoppingList: shoppingList}
+
+	owner, err := store.GetShoppingListOwner(ctx, username, shoppingList.ID)
+	if err != nil {
+		return archived, err
+	}
+	archived.Owner = owner
+
+	collaborators, err := store.GetShoppingListCollaborators(ctx, shoppingList.ID)
+	if err != nil {
+		return archived, err
+	}
+	archived.Collaborators = others(collaborators, username)
+
+	archived.Items, err = allShoppingListItems(ctx, store, shoppingList.ID)
+
+	return archived, err
+}
+
+// others returns usernames without username
+func others(usernames []string, username string) []string {
+	kept := []string{}
+	for _, other := range usernames {
+		if !sameName(other, username) {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+// allStorages reads every page of the storages attached to username
+func allStorages(ctx context.Context, store Store, username string) ([]Folder, error) {
+	storages := []Folder{}
+	options := ListOptions{Limit: archivePageSize}
+
+	for {
+		page, err := store.GetStorages(ctx, username, options)
+		if err != nil {
+			return storages, err
+		}
+		storages = append(storages, page.Items...)
+
+		if page.NextCursor == "" {
+			return storages, nil
+		}
+		options.Cursor = page.NextCursor
+	}
+}
+
+// allStorageItems reads every page of the items of storageID
+func allStorageItems(ctx context.Context, store Store, storageID int) ([]Item, error) {
+	items := []Item{}
+	options := ListOptions{Limit: archivePageSize}
+
+	for {
+		page, err := store.GetStorageItems(ctx, storageID, options)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, page.Items...)
+
+		if page.NextCursor == "" {
+			return items, nil
+		}
+		options.Cursor = page.NextCursor
+	}
+}
+
+// allShoppingLists reads every page of the shopping lists attached to username
+func allShoppingLists(ctx context.Context, store Store, username string) ([]ShoppingList, error) {
+	shoppingLists := []ShoppingList{}
+	options := ListOptions{Limit: archivePageSize}
+
+	for {
+		page, err := store.GetShoppingLists(ctx, username, options)
+		if err != nil {
+			return shoppingLists, err
+		}
+		shoppingLists = append(shoppingLists, page.Items...)
+
+		if page.NextCursor == "" {
+			return shoppingLists, nil
+		}
+		options.Cursor = page.NextCursor
+	}
+}
+
+// allShoppingListItems reads every page of the items of shoppingListID
+func allShoppingListItems(ctx context.Context, store Store, shoppingListID int) ([]ShoppingListItem, error) {
+	items := []ShoppingListItem{}
+	options := ListOptions{Limit: archivePageSize}
+
+	for {
+		page, err := store.GetShoppingListItems(ctx, shoppingListID, options)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, page.Items...)
+
+		if page.NextCursor == "" {
+			return items, nil
+		}
+		options.Cursor = page.NextCursor
+	}
+}
+
+// importAccount recreates the storages and shopping lists archive holds under username in one transaction,
+// using only Store operations so every Store shares it, along with the history of the items when the Store
+// is a historyStore. The storages, shopping lists and items the account already has under the same title are
+// left as they are, and the collaborators of the archive having an account are sent share requests.
+func importAccount(ctx context.Context, store Store, username string, archive Archive) (ImportReport, error) {
+	report := ImportReport{
+		Storages:          []ImportedRow{},
+		StorageItems:      []ImportedRow{},
+		ShoppingLists:     []ImportedRow{},
+		ShoppingListItems: []ImportedRow{},
+	}
+
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return report, errDataTruncated("version", nil)
+	}
+
+	err := store.WithTx(ctx, func(tx Store) error {
+		acc, err := tx.GetAccount(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.UpdateAccount(ctx, acc.Username, acc.Username, acc.Password, acc.Email,
+			archive.Account.DarkTheme, archive.Account.Notifications); err != nil {
+			return err
+		}
+
+		if archive.Account.UnitSystem != "" {
+			if err := tx.UpdateUnitSetting(ctx, acc.Username, archive.Account.UnitSystem); err != nil {
+				return err
+			}
+		}
+
+		importer := &importer{store: tx, username: acc.Username, archivedUsername: archive.Account.Username, report: &report}
+
+		for _, archived := range archive.Storages {
+			if err := importer.storage(ctx, archived); err != nil {
+				return err
+			}
+		}
+
+		for _, archived := range archive.ShoppingLists {
+			if err := importer.shoppingList(ctx, archived); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return report, err
+}
+
+// importer is the state of an importAccount
+type importer struct {
+	store    Store
+	username string
+	// archivedUsername is the username of the archived account, whose events become the account's
+	archivedUsername string
+	report           *ImportReport
+}
+
+// titles maps the lower case titles of rows to their IDs, the first of each title
+type titles map[string]int
+
+func (t titles) add(title string, id int) {
+	if _, ok := t[strings.ToLower(title)]; !ok {
+		t[strings.ToLower(title)] = id
+	}
+}
+
+func (t titles) find(title string) (int, bool) {
+	id, ok := t[strings.ToLower(title)]
+	return id, ok
+}
+
+// storage imports archived along with its items, as a duplicate of the storage the account owns under its title if any
+func (i *importer) storage(ctx context.Context, archived ArchiveStorage) error {
+	row := ImportedRow{ID: archived.Storage.ID, Status: ImportSkipped}
+	if !archived.Owner {
+		i.report.Storages = append(i.report.Storages, row)
+		return nil
+	}
+
+	owned, err := i.ownedStorages(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := titles{}
+	if storageID, ok := owned.find(archived.Storage.Title); ok {
+		row.NewID, row.Status = storageID, ImportDuplicate
+
+		items, err := allStorageItems(ctx, i.store, storageID)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			existing.add(item.Title, item.ID)
+		}
+	} else {
+		id, err := i.store.CreateStorage(ctx, i.username, archived.Storage.Title, true)
+		if err != nil {
+			return err
+		}
+		row.NewID, row.Status = int(id), ImportCreated
+	}
+	i.report.Storages = append(i.report.Storages, row)
+
+	for _, archivedItem := range archived.Items {
+		item := archivedItem.Item
+		itemRow := ImportedRow{ID: item.ID, Status: ImportDuplicate}
+
+		if itemID, ok := existing.find(item.Title); ok {
+			itemRow.NewID = itemID
+		} else {
+			if err := ValidateItem(item); err != nil {
+				return err
+			}
+
+			id, err := i.store.CreateStorageItem(ctx, row.NewID, item.Title, item.Quantity, item.QuantityType,
+				item.QuantityThreshold, item.ExpirationThreshold, item.ExpirationDate)
+			if err != nil {
+				return err
+			}
+			itemRow.NewID, itemRow.Status = int(id), ImportCreated
+			existing.add(item.Title, itemRow.NewID)
+
+			// Creates take no image
+			if item.Image != "" {
+				if err := i.store.UpdateStorageItem(ctx, item.Title, item.Image, item.Quantity, item.QuantityType,
+					item.QuantityThreshold, item.ExpirationThreshold, item.ExpirationDate, itemRow.NewID, AnyVersion); err != nil {
+					return err
+				}
+			}
+
+			if err := i.history(ctx, itemRow.NewID, archivedItem.History); err != nil {
+				return err
+			}
+		}
+
+		i.report.StorageItems = append(i.report.StorageItems, itemRow)
+	}
+
+	return i.share(ctx, "storage", archived.Storage.Title, row.NewID, archived.Collaborators, func(collaborator string) error {
+		_, err := i.store.GetStorageOwner(ctx, collaborator, row.NewID)
+		return err
+	})
+}
+
+// shoppingList imports archived along with its items, as a duplicate of the shopping list the account owns
+// under its title if any
+func (i *importer) shoppingList(ctx context.Context, archived ArchiveShoppingList) error {
+	row := ImportedRow{ID: archived.ShoppingList.ID, Status: ImportSkipped}
+	if !archived.Owner {
+		i.report.ShoppingLists = append(i.report.ShoppingLists, row)
+		return nil
+	}
+
+	owned, err := i.ownedShoppingLists(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := titles{}
+	if shoppingListID, ok := owned.find(archived.ShoppingList.Title); ok {
+		row.NewID, row.Status = shoppingListID, ImportDuplicate
+
+		items, err := allShoppingListItems(ctx, i.store, shoppingListID)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			existing.add(item.Title, item.ID)
+		}
+	} else {
+		id, err := i.store.CreateShoppingList(ctx, i.username, archived.ShoppingList.Title, true)
+		if err != nil {
+			return err
+		}
+		row.NewID, row.Status = int(id), ImportCreated
+	}
+	i.report.ShoppingLists = append(i.report.ShoppingLists, row)
+
+	for _, item := range archived.Items {
+		itemRow := ImportedRow{ID: item.ID, Status: ImportDuplicate}
+
+		if itemID, ok := existing.find(item.Title); ok {
+			itemRow.NewID = itemID
+		} else {
+			if err := ValidateItem(Item{Title: item.Title, Quantity: item.Quantity, QuantityType: item.QuantityType}); err != nil {
+				return err
+			}
+
+			id, err := i.store.CreateShoppingListItem(ctx, row.NewID, item.Title, item.Quantity, item.QuantityType)
+			if err != nil {
+				return err
+			}
+			itemRow.NewID, itemRow.Status = int(id), ImportCreated
+			existing.add(item.Title, itemRow.NewID)
+		}
+
+		i.report.ShoppingListItems = append(i.report.ShoppingListItems, itemRow)
+	}
+
+	return i.share(ctx, "shopping_list", archived.ShoppingList.Title, row.NewID, archived.Collaborators, func(collaborator string) error {
+		_, err := i.store.GetShoppingListOwner(ctx, collaborator, row.NewID)
+		return err
+	})
+}
+
+// history replaces the events recorded by the import of the storage item itemID by history, the events
+// of the archived item latest first. The events of the archived account become the account's, the others
+// are made by nobody as their actors may not be the same people on this instance.
+func (i *importer) history(ctx context.Context, itemID int, history []ItemEvent) error {
+	store, ok := i.store.(historyStore)
+	if !ok || len(history) == 0 {
+		return nil
+	}
+
+	events := []ItemEvent{}
+	for j := len(history) - 1; j >= 0; j-- {
+		event := history[j]
+		if event.Actor != "" && sameName(event.Actor, i.archivedUsername) {
+			event.Actor = i.username
+		} else {
+			event.Actor = ""
+		}
+		events = append(events, event)
+	}
+
+	return store.replaceItemEvents(ctx, itemID, events)
+}
+
+// ownedStorages returns the titles of the storages the account owns
+func (i *importer) ownedStorages(ctx context.Context) (titles, error) {
+	owned := titles{}
+
+	storages, err := allStorages(ctx, i.store, i.username)
+	if err != nil {
+		return owned, err
+	}
+
+	for _, storage := range storages {
+		share, err := i.store.GetStorageOwner(ctx, i.username, storage.ID)
+		if err != nil {
+			return owned, err
+		}
+		if share.Owner == 1 {
+			owned.add(storage.Title, storage.ID)
+		}
+	}
+
+	return owned, nil
+}
+
+// ownedShoppingLists returns the titles of the shopping lists the account owns
+func (i *importer) ownedShoppingLists(ctx context.Context) (titles, error) {
+	owned := titles{}
+
+	shoppingLists, err := allShoppingLists(ctx, i.store, i.username)
+	if err != nil {
+		return owned, err
+	}
+
+	for _, shoppingList := range shoppingLists {
+		owner, err := i.store.GetShoppingListOwner(ctx, i.username, shoppingList.ID)
+		if err != nil {
+			return owned, err
+		}
+		if owner {
+			owned.add(shoppingList.Title, shoppingList.ID)
+		}
+	}
+
+	return owned, nil
+}
+
+// share sends the collaborators having an account a share request of the container id of shareType,
+// unless attached finds them attached to it already or they have one pending
+func (i *importer) share(ctx context.Context, shareType, title string, id int, collaborators []string, attached func(collaborator string) error) error {
+	for _, collaborator := range collaborators {
+		if sameName(collaborator, i.username) {
+			continue
+		}
+
+		if _, err := i.store.GetAccount(ctx, collaborator); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return err
+		}
+
+		if err := attached(collaborator); err == nil {
+			continue
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		pending, err := i.store.GetShareRequests(ctx, collaborator)
+		if err != nil {
+			return err
+		}
+
+		sent := false
+		for _, shareRequest := range pending {
+			if sameName(shareRequest.FromUsername, i.username) && shareRequest.ShareType == shareType && shareRequest.IDRequest == id {
+				sent = true
+			}
+		}
+		if sent {
+			continue
+		}
+
+		if err := i.store.CreateShareRequest(ctx, i.username, collaborator, shareType, title, id); err != nil {
+			return err
+		}
+		i.report.ShareRequests++
+	}
+
+	return nil
+}
+
+// ExportAccount reads the archive of the data of username
+func (handler *Handler) ExportAccount(ctx context.Context, username string) (Archive, error) {
+	return exportAccount(ctx, handler, username)
+}
+
+// ImportAccount recreates the storages and shopping lists of archive under username
+func (handler *Handler) ImportAccount(ctx context.Context, username string, archive Archive) (ImportReport, error) {
+	return importAccount(ctx, handler, username, archive)
+}
@@ -0,0 +1,413 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BackupVersion is the version of the backup format written by Backup
+const BackupVersion = 1
+
+// backupFormat names the format in the header of a backup, so that Restore tells a backup from another gzip file
+const backupFormat = "cat-clerk-backup"
+
+// backupTables are the tables a backup holds, each after the ones it refers to
+var backupTables = []string{
+	"accounts",
+	"settings",
+	"foods",
+	"storages",
+	"storage_items",
+	"storage_item_events",
+	"shopping_lists",
+	"shopping_list_items",
+	"account_storage_binder",
+	"account_shopping_list_binder",
+	"share_requests",
+	"sync_tombstones",
+	"sync_purges",
+	"pushed_mutations",
+	"item_alerts",
+	"item_alert_deliveries",
+}
+
+// backupTablesSince holds the schema version which created each of backupTables not created by the first one
+var backupTablesSince = map[string]int{
+	"storage_item_events":   6,
+	"sync_tombstones":       7,
+	"sync_purges":           7,
+	"pushed_mutations":      8,
+	"item_alerts":           10,
+	"item_alert_deliveries": 10,
+}
+
+// backupTablesAt returns the backupTables the schema has at version
+func backupTablesAt(version int) []string {
+	tables := []string{}
+	for _, table := range backupTables {
+		if backupTablesSince[table] <= version {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// backupColumn matches the column names Restore inserts, which are written into its statements
+var backupColumn = regexp.MustCompile(`^[a-z_]+$`)
+
+// BackupHeader opens a backup. A backup restores on the dialect and at the schema version it was taken at.
+type BackupHeader struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	Dialect       string    `json:"dialect"`
+	SchemaVersion int       `json:"schemaVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// backupLine is a line of a backup. After the header, each table starts with its columns followed by its rows,
+// and the trailer closes the backup with the number of rows and the SHA-256 of every line before it.
+type backupLine struct {
+	Table    string        `json:"table,omitempty"`
+	Columns  []string      `json:"columns,omitempty"`
+	Row      []interface{} `json:"row,omitempty"`
+	Rows     *int          `json:"rows,omitempty"`
+	Checksum string        `json:"checksum,omitempty"`
+}
+
+// backupTime holds a time value of a backup, as JSON does not tell it from a string
+type backupTime struct {
+	Time time.Time `json:"time"`
+}
+
+// Backup writes every table to w as a gzip compressed backup, read in one transaction so that it is consistent
+func (handler *Handler) Backup(ctx context.Context, w io.Writer) error {
+	version, err := handler.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := handler.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	zw := gzip.NewWriter(w)
+	checksum := sha256.New()
+	encoder := json.NewEncoder(io.MultiWriter(zw, checksum))
+
+	if err := encoder.Encode(BackupHeader{
+		Format:        backupFormat,
+		Version:       BackupVersion,
+		Dialect:       handler.dialect.name(),
+		SchemaVersion: version,
+		CreatedAt:     time.Now().UTC(),
+	}); err != nil {
+		return err
+	}
+
+	count := 0
+	for _, table := range backupTablesAt(version) {
+		n, err := backupTable(ctx, tx, table, encoder)
+		if err != nil {
+			return fmt.Errorf("backup of %s: %v", table, err)
+		}
+		count += n
+	}
+
+	// The trailer is written to zw alone, the checksum covers the lines before it
+	if err := json.NewEncoder(zw).Encode(backupLine{
+		Rows:     &count,
+		Checksum: hex.EncodeToString(checksum.Sum(nil)),
+	}); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// backupTable writes the columns and the rows of table, and returns the number of rows
+func backupTable(ctx context.Context, tx *sql.Tx, table string, encoder *json.Encoder) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT * FROM `+table+` ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := encoder.Encode(backupLine{Table: table, Columns: columns}); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return count, err
+		}
+
+		for i, value := range values {
+			switch value := value.(type) {
+			case []byte:
+				values[i] = string(value)
+			case time.Time:
+				values[i] = backupTime{Time: value.UTC()}
+			}
+		}
+
+		if err := encoder.Encode(backupLine{Row: values}); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+// Restore loads a backup written by Backup into an empty database of the same dialect.
+// An empty schema is first migrated to the version of the backup, otherwise the schema has to be at it.
+// The rows are inserted in one transaction, committed only once the checksum of the backup matches.
+func (handler *Handler) Restore(ctx context.Context, r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("not a backup: %v", err)
+	}
+
+	defer zr.Close()
+
+	reader := backupReader{reader: bufio.NewReader(zr), checksum: sha256.New()}
+
+	header := BackupHeader{}
+	if err := reader.next(&header); err != nil {
+		return fmt.Errorf("not a backup: %v", err)
+	}
+
+	switch {
+	case header.Format != backupFormat:
+		return fmt.Errorf("not a backup")
+	case header.Version > BackupVersion:
+		return fmt.Errorf("unknown backup version %d, the latest known is %d", header.Version, BackupVersion)
+	case header.Dialect != handler.dialect.name():
+		return fmt.Errorf("the backup is of a %s database, it cannot be restored to %s", header.Dialect, handler.dialect.name())
+	}
+
+	version, err := handler.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version == 0 {
+		if err := handler.Migrate(ctx, header.SchemaVersion); err != nil {
+			return err
+		}
+	} else if version != header.SchemaVersion {
+		return fmt.Errorf("the backup is at schema version %d but the database is at %d", header.SchemaVersion, version)
+	}
+
+	tables := backupTablesAt(header.SchemaVersion)
+
+	for _, table := range tables {
+		empty := false
+		if err := handler.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*) = 0
+			FROM `+table,
+		).Scan(
+			&empty,
+		); err != nil {
+			return err
+		}
+
+		if !empty {
+			return fmt.Errorf("%s is not empty, a backup only restores to an empty database", table)
+		}
+	}
+
+	conn, err := handler.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	restore, err := handler.dialect.suspendForeignKeys(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	defer restore()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var stmt *sql.Stmt
+	table := ""
+	count := 0
+
+	defer func() {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}()
+
+	for {
+		sum := hex.EncodeToString(reader.checksum.Sum(nil))
+
+		line := backupLine{}
+		if err := reader.next(&line); err != nil {
+			return fmt.Errorf("corrupt backup: %v", err)
+		}
+
+		switch {
+		case line.Checksum != "":
+			if line.Checksum != sum {
+				return fmt.Errorf("corrupt backup: the checksum does not match")
+			}
+			if line.Rows == nil || *line.Rows != count {
+				return fmt.Errorf("corrupt backup: the number of rows does not match")
+			}
+
+			if err := handler.dialect.checkForeignKeys(ctx, tx); err != nil {
+				return err
+			}
+
+			for _, name := range tables {
+				if err := handler.dialect.resetSequence(ctx, tx, name); err != nil {
+					return err
+				}
+			}
+
+			return tx.Commit()
+		case line.Table != "":
+			if !isBackupTable(tables, line.Table) {
+				return fmt.Errorf("corrupt backup: unknown table %q", line.Table)
+			}
+
+			for _, column := range line.Columns {
+				if !backupColumn.MatchString(column) {
+					return fmt.Errorf("corrupt backup: invalid column %q of %s", column, line.Table)
+				}
+			}
+
+			if stmt != nil {
+				stmt.Close()
+			}
+
+			table = line.Table
+			stmt, err = tx.PrepareContext(ctx, handler.dialect.rebind(`
+				INSERT INTO `+table+`(`+strings.Join(line.Columns, ", ")+`)
+				VALUES(`+strings.TrimSuffix(strings.Repeat("?, ", len(line.Columns)), ", ")+`)
+			`))
+			if err != nil {
+				return fmt.Errorf("restore of %s: %v", table, err)
+			}
+		case line.Row != nil:
+			if stmt == nil {
+				return fmt.Errorf("corrupt backup: a row comes before its table")
+			}
+
+			values, err := restoreValues(line.Row)
+			if err != nil {
+				return fmt.Errorf("corrupt backup: %v", err)
+			}
+
+			if _, err := stmt.ExecContext(ctx, values...); err != nil {
+				return fmt.Errorf("restore of %s: %v", table, err)
+			}
+			count++
+		default:
+			return fmt.Errorf("corrupt backup: unexpected line")
+		}
+	}
+}
+
+// isBackupTable returns whether table is one of tables
+func isBackupTable(tables []string, table string) bool {
+	for _, name := range tables {
+		if name == table {
+			return true
+		}
+	}
+	return false
+}
+
+// backupReader reads the lines of a backup and sums them, io.EOF until the trailer is an error
+type backupReader struct {
+	reader   *bufio.Reader
+	checksum hash.Hash
+}
+
+// next decodes the next line into v
+func (reader backupReader) next(v interface{}) error {
+	line, err := reader.reader.ReadBytes('\n')
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	reader.checksum.Write(line)
+
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
+
+// restoreValues turns the values of a row of a backup back into the ones scanned from the database
+func restoreValues(row []interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(row))
+
+	for i, value := range row {
+		switch value := value.(type) {
+		case json.Number:
+			if n, err := value.Int64(); err == nil {
+				values[i] = n
+			} else if values[i], err = value.Float64(); err != nil {
+				return nil, err
+			}
+		case map[string]interface{}:
+			s, ok := value["time"].(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected value %v", value)
+			}
+
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = t
+		default:
+			values[i] = value
+		}
+	}
+
+	return values, nil
+}
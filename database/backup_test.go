@@ -0,0 +1,149 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// seedBackup writes the same accounts, containers, items and history to store whatever its kind
+func seedBackup(t *testing.T, store Store) (storageID, itemID int) {
+	ctx := WithActor(context.Background(), "ann")
+
+	for _, username := range []string{"ann", "bob"} {
+		if _, err := store.CreateAccount(ctx, username, username+"@example.com", "password", "salt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	id, err := store.CreateStorage(ctx, "ann", "Fridge", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ShareStorage(ctx, "bob", int(id)); err != nil {
+		t.Fatal(err)
+	}
+
+	milkID, err := store.CreateStorageItem(ctx, int(id), "Milk", 2, "liters", 1, 3, "2030-01-02T05:00:00+09:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateStorageItem(ctx, "Oat milk", "", 2, "liters", 1, 3, "2030-01-02", int(milkID), AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	jamID, err := store.CreateStorageItem(ctx, int(id), "Jam", 1, "pieces", 0, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteStorageItem(ctx, int(jamID), AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	shoppingListID, err := store.CreateShoppingList(ctx, "ann", "Groceries", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateShoppingListItem(ctx, int(shoppingListID), "Bread", 1, "pieces"); err != nil {
+		t.Fatal(err)
+	}
+
+	return int(id), int(milkID)
+}
+
+// backupSnapshot reads what ann sees along with the history of an item, as JSON without the times,
+// which differ between stores
+func backupSnapshot(t *testing.T, store Store, storageID, itemID int) interface{} {
+	ctx := context.Background()
+
+	changes, err := store.GetChanges(ctx, "ann", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := store.GetStorageItemHistory(ctx, storageID, itemID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := json.Marshal([]interface{}{changes, history})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := []interface{}{}
+	if err := json.Unmarshal(encoded, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	return withoutTimes(snapshot)
+}
+
+// withoutTimes drops the times and cursors from a value decoded from JSON
+func withoutTimes(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if strings.HasSuffix(key, "At") || key == "cursor" {
+				delete(v, key)
+				continue
+			}
+			v[key] = withoutTimes(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = withoutTimes(value)
+		}
+	}
+	return v
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+
+	memory := NewMemory()
+	seedBackup(t, memory)
+
+	source := newTestSQLite(t)
+	storageID, itemID := seedBackup(t, source)
+
+	backup := bytes.Buffer{}
+	if err := source.Backup(ctx, &backup); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestSQLite(t)
+	if err := restored.Restore(ctx, bytes.NewReader(backup.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	want := backupSnapshot(t, memory, storageID, itemID)
+	for name, store := range map[string]Store{"sqlite": source, "restored": restored} {
+		if got := backupSnapshot(t, store, storageID, itemID); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s reads\n%v\nwant, as the memory store reads,\n%v", name, got, want)
+		}
+	}
+
+	// The times come back as they were backed up
+	sourceItem, err := source.GetStorageItem(ctx, storageID, itemID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restoredItem, err := restored.GetStorageItem(ctx, storageID, itemID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restoredItem, sourceItem) {
+		t.Errorf("restored item = %+v, want %+v", restoredItem, sourceItem)
+	}
+
+	// New rows take IDs past the restored ones
+	id, err := restored.CreateStorage(ctx, "bob", "Cellar", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(id) <= storageID {
+		t.Errorf("new storage ID = %d, want more than %d", id, storageID)
+	}
+}
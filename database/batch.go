@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+)
+
+// maxBatchOperations is the most operations a batch takes
+const maxBatchOperations = 100
+
+// Statuses of a BatchEntry
+const (
+	// BatchApplied marks an operation written by the batch
+	BatchApplied = "applied"
+	// BatchFailed marks the operation that failed, the only one of an atomic batch
+	BatchFailed = "failed"
+	// BatchRolledBack marks an operation undone as a later one of its atomic batch failed
+	BatchRolledBack = "rolled_back"
+	// BatchSkipped marks an operation not run as an earlier one of its atomic batch failed
+	BatchSkipped = "skipped"
+)
+
+// ItemOperation creates, updates or deletes a storage item or shopping list item in a batch.
+// An update sets every field, as a single update does. Version is 0 to write any version.
+type ItemOperation struct {
+	Op                  string  `json:"op"`
+	ID                  int     `json:"id,omitempty"`
+	Version             int     `json:"version,omitempty"`
+	Title               string  `json:"title"`
+	Image               string  `json:"image"`
+	Quantity            float64 `json:"quantity"`
+	QuantityType        string  `json:"quantityType"`
+	QuantityThreshold   float64 `json:"quantityThreshold"`
+	ExpirationThreshold int     `json:"expirationThreshold"`
+	ExpirationDate      string  `json:"expirationDate"`
+}
+
+// BatchEntry is the outcome of an ItemOperation
+type BatchEntry struct {
+	Op     string `json:"op"`
+	Status string `json:"status"`
+	// ID is the item written, assigned by the server for a create
+	ID int `json:"id,omitempty"`
+	// Err is why the operation failed
+	Err error `json:"-"`
+}
+
+// BatchResult holds the outcome of each operation of a batch, in order
+type BatchResult struct {
+	Results []BatchEntry `json:"results"`
+}
+
+// Failed returns the first failed operation of the batch, nil when none did
+func (result BatchResult) Failed() *BatchEntry {
+	for i := range result.Results {
+		if result.Results[i].Status == BatchFailed {
+			return &result.Results[i]
+		}
+	}
+	return nil
+}
+
+// runBatch runs apply on each of operations, using only Store operations so every Store shares it.
+// The operations run in one transaction that a failure rolls back, or each on its own when bestEffort.
+// The error is only for the failures of the batch itself, the ones of an operation are in its entry.
+func runBatch(ctx context.Context, store Store, operations []ItemOperation, bestEffort bool, apply func(store Store, operation ItemOperation) (int, error)) (BatchResult, error) {
+	result := BatchResult{Results: []BatchEntry{}}
+
+	if len(operations) > maxBatchOperations {
+		return result, errDataTruncated("operations", nil)
+	}
+
+	for _, operation := range operations {
+		result.Results = append(result.Results, BatchEntry{Op: operation.Op, Status: BatchSkipped})
+	}
+
+	if bestEffort {
+		for i, operation := range operations {
+			id, err := apply(store, operation)
+			result.Results[i].settle(id, err)
+		}
+		return result, nil
+	}
+
+	failed := -1
+
+	err := store.WithTx(ctx, func(tx Store) error {
+		for i, operation := range operations {
+			id, err := apply(tx, operation)
+			result.Results[i].settle(id, err)
+			if err != nil {
+				failed = i
+				return err
+			}
+		}
+		return nil
+	})
+	if failed < 0 {
+		return result, err
+	}
+
+	for i := 0; i < failed; i++ {
+		result.Results[i].Status = BatchRolledBack
+		result.Results[i].ID = 0
+	}
+
+	return result, nil
+}
+
+// settle records the outcome of the operation of entry
+func (entry *BatchEntry) settle(id int, err error) {
+	if err != nil {
+		entry.Status = BatchFailed
+		entry.Err = err
+		return
+	}
+
+	entry.Status = BatchApplied
+	entry.ID = id
+}
+
+// validateOperation checks a create or update with ValidateItem, as a single one is, so that it fails
+// before reaching the store
+func validateOperation(operation ItemOperation) error {
+	return ValidateItem(Item{
+		Title:               operation.Title,
+		Quantity:            operation.Quantity,
+		QuantityType:        operation.QuantityType,
+		QuantityThreshold:   operation.QuantityThreshold,
+		ExpirationThreshold: operation.ExpirationThreshold,
+		ExpirationDate:      operation.ExpirationDate,
+	})
+}
+
+// batchStorageItems runs operations on the items of storageID
+func batchStorageItems(ctx context.Context, store Store, storageID int, operations []ItemOperation, bestEffort bool) (BatchResult, error) {
+	return runBatch(ctx, store, operations, bestEffort, func(store Store, operation ItemOperation) (int, error) {
+		if operation.Op == OpCreate || operation.Op == OpUpdate {
+			if err := validateOperation(operation); err != nil {
+				return 0, err
+			}
+		}
+
+		if operation.Op == OpCreate {
+			id, err := store.CreateStorageItem(ctx, storageID, operation.Title, operation.Quantity, operation.QuantityType,
+				operation.QuantityThreshold, operation.ExpirationThreshold, operation.ExpirationDate)
+			return int(id), err
+		}
+
+		if operation.Op != OpUpdate && operation.Op != OpDelete {
+			return 0, errDataTruncated("op", nil)
+		}
+
+		// The item has to be one of the storage
+		if _, err := store.GetStorageItem(ctx, storageID, operation.ID); err != nil {
+			return 0, err
+		}
+
+		if operation.Op == OpDelete {
+			return operation.ID, store.DeleteStorageItem(ctx, operation.ID, operation.Version)
+		}
+
+		return operation.ID, store.UpdateStorageItem(ctx, operation.Title, operation.Image, operation.Quantity, operation.QuantityType,
+			operation.QuantityThreshold, operation.ExpirationThreshold, operation.ExpirationDate, operation.ID, operation.Version)
+	})
+}
+
+// batchShoppingListItems runs operations on the items of shoppingListID
+func batchShoppingListItems(ctx context.Context, store Store, shoppingListID int, operations []ItemOperation, bestEffort bool) (BatchResult, error) {
+	return runBatch(ctx, store, operations, bestEffort, func(store Store, operation ItemOperation) (int, error) {
+		// Shopping list items have no thresholds or expiration date
+		if operation.Op == OpCreate || operation.Op == OpUpdate {
+			if err := validateOperation(ItemOperation{Title: operation.Title, Quantity: operation.Quantity, QuantityType: operation.QuantityType}); err != nil {
+				return 0, err
+			}
+		}
+
+		if operation.Op == OpCreate {
+			id, err := store.CreateShoppingListItem(ctx, shoppingListID, operation.Title, operation.Quantity, operation.QuantityType)
+			return int(id), err
+		}
+
+		if operation.Op != OpUpdate && operation.Op != OpDelete {
+			return 0, errDataTruncated("op", nil)
+		}
+
+		// The item has to be one of the shopping list
+		item, err := store.GetShoppingListItem(ctx, operation.ID)
+		if err != nil {
+			return 0, err
+		}
+		if item.ShoppingListID != shoppingListID {
+			return 0, errNoRows
+		}
+
+		if operation.Op == OpDelete {
+			return operation.ID, store.DeleteShoppingListItem(ctx, operation.ID, operation.Version)
+		}
+
+		return operation.ID, store.UpdateShoppingListItem(ctx, operation.Title, operation.Quantity, operation.QuantityType,
+			operation.ID, operation.Version)
+	})
+}
+
+// BatchStorageItems creates, updates and deletes items of a storage in one call
+func (handler *Handler) BatchStorageItems(ctx context.Context, storageID int, operations []ItemOperation, bestEffort bool) (BatchResult, error) {
+	return batchStorageItems(ctx, handler, storageID, operations, bestEffort)
+}
+
+// BatchShoppingListItems creates, updates and deletes items of a shopping list in one call
+func (handler *Handler) BatchShoppingListItems(ctx context.Context, shoppingListID int, operations []ItemOperation, bestEffort bool) (BatchResult, error) {
+	return batchShoppingListItems(ctx, handler, shoppingListID, operations, bestEffort)
+}
@@ -0,0 +1,538 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures a Cache
+type CacheOptions struct {
+	// Size is the number of entries kept, the least recently used ones are evicted beyond it
+	Size int
+	// TTL is how long an entry is served after it was read from the store
+	TTL time.Duration
+}
+
+// CacheStats are the counters of a Cache since it was created
+type CacheStats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	// Evictions counts the entries evicted to make room for new ones
+	Evictions int64 `json:"evictions"`
+	// Expirations counts the entries found past their TTL
+	Expirations int64 `json:"expirations"`
+	// Invalidations counts the entries dropped by writes
+	Invalidations int64 `json:"invalidations"`
+}
+
+// HitRate is the share of the reads served by the cache, 0 before any read
+func (stats CacheStats) HitRate() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// Tags grouping the entries of the Cache, the listings being tagged per account
+const (
+	foodsTag         = "foods"
+	storagesTag      = "storages"
+	shoppingListsTag = "shopping_lists"
+)
+
+// accountTag is the tag of the listings of kind of an account, whose usernames compare regardless of case
+func accountTag(kind, username string) string {
+	return kind + "|" + strings.ToLower(username)
+}
+
+// lruEntry is a value of the lru
+type lruEntry struct {
+	key     string
+	tag     string
+	value   interface{}
+	expires time.Time
+}
+
+// lru is a bounded, least recently used map whose entries expire and are invalidated by tag
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries *list.List
+	keys    map[string]*list.Element
+	tags    map[string]map[string]bool
+	// generation changes on every invalidation, so that values read before it are not kept
+	generation uint64
+	stats      CacheStats
+}
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:    size,
+		ttl:     ttl,
+		entries: list.New(),
+		keys:    map[string]*list.Element{},
+		tags:    map[string]map[string]bool{},
+	}
+}
+
+// get returns the value of key unless it is missing or expired
+func (l *lru) get(key string) (interface{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.keys[key]
+	if !ok {
+		l.stats.Misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		l.remove(element)
+		l.stats.Expirations++
+		l.stats.Misses++
+		return nil, false
+	}
+
+	l.entries.MoveToFront(element)
+	l.stats.Hits++
+	return entry.value, true
+}
+
+// currentGeneration is the generation to pass to set for a value about to be read
+func (l *lru) currentGeneration() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.generation
+}
+
+// set keeps value under key and tag, unless an invalidation happened since generation
+func (l *lru) set(key, tag string, value interface{}, generation uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if generation != l.generation || l.size <= 0 {
+		return
+	}
+
+	if element, ok := l.keys[key]; ok {
+		l.remove(element)
+	}
+
+	l.keys[key] = l.entries.PushFront(&lruEntry{key: key, tag: tag, value: value, expires: time.Now().Add(l.ttl)})
+	if l.tags[tag] == nil {
+		l.tags[tag] = map[string]bool{}
+	}
+	l.tags[tag][key] = true
+
+	for l.entries.Len() > l.size {
+		l.remove(l.entries.Back())
+		l.stats.Evictions++
+	}
+}
+
+// invalidate drops the entries of tags, every entry when all is true
+func (l *lru) invalidate(all bool, tags ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.generation++
+
+	if all {
+		l.stats.Invalidations += int64(l.entries.Len())
+		l.entries.Init()
+		l.keys = map[string]*list.Element{}
+		l.tags = map[string]map[string]bool{}
+		return
+	}
+
+	for _, tag := range tags {
+		for key := range l.tags[tag] {
+			l.remove(l.keys[key])
+			l.stats.Invalidations++
+		}
+	}
+}
+
+// remove drops the entry of element
+func (l *lru) remove(element *list.Element) {
+	entry := l.entries.Remove(element).(*lruEntry)
+	delete(l.keys, entry.key)
+
+	delete(l.tags[entry.tag], entry.key)
+	if len(l.tags[entry.tag]) == 0 {
+		delete(l.tags, entry.tag)
+	}
+}
+
+func (l *lru) snapshot() CacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	stats.Entries = l.entries.Len()
+	return stats
+}
+
+// txInvalidations are the invalidations made in a transaction, made again once it ends
+type txInvalidations struct {
+	all  bool
+	tags []string
+}
+
+// Cache is a Store serving the foods catalog and the storage and shopping list listings of each account
+// from a bounded LRU with a TTL. Writes through the Cache drop the listings of every account attached
+// to the containers they change.
+// Cached pages are shared between callers, which must not modify them.
+type Cache struct {
+	Store
+	lru *lru
+	// tx is set on the Caches passed by WithTx, which read from their transaction only
+	tx *txInvalidations
+}
+
+// NewCache returns a Cache in front of store
+func NewCache(store Store, options CacheOptions) *Cache {
+	return &Cache{Store: store, lru: newLRU(options.Size, options.TTL)}
+}
+
+// Health reports the health of the store, along with the cache statistics
+func (c *Cache) Health(ctx context.Context) Health {
+	health := c.Store.Health(ctx)
+	stats := c.lru.snapshot()
+	health.Cache = &stats
+	return health
+}
+
+// WithTx runs fn with a Cache reading from and writing to a transaction of the store.
+// Its invalidations are made again once the transaction ends, so that no listing read meanwhile is kept.
+func (c *Cache) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	if c.tx != nil {
+		return c.Store.WithTx(ctx, func(tx Store) error {
+			return fn(&Cache{Store: tx, lru: c.lru, tx: c.tx})
+		})
+	}
+
+	invalidations := &txInvalidations{}
+
+	err := c.Store.WithTx(ctx, func(tx Store) error {
+		return fn(&Cache{Store: tx, lru: c.lru, tx: invalidations})
+	})
+
+	c.lru.invalidate(invalidations.all, invalidations.tags...)
+
+	return err
+}
+
+// cached returns the value of key, or loads it and keeps it under tag
+func (c *Cache) cached(key, tag string, load func() (interface{}, error)) (interface{}, error) {
+	// Transactions may read their own writes, which are not committed yet
+	if c.tx != nil {
+		return load()
+	}
+
+	if value, ok := c.lru.get(key); ok {
+		return value, nil
+	}
+
+	generation := c.lru.currentGeneration()
+
+	value, err := load()
+	if err == nil {
+		c.lru.set(key, tag, value, generation)
+	}
+
+	return value, err
+}
+
+// invalidate drops the listings of kind of the accounts usernames
+func (c *Cache) invalidate(kind string, usernames ...string) {
+	tags := []string{}
+	for _, username := range usernames {
+		tags = append(tags, accountTag(kind, username))
+	}
+
+	c.lru.invalidate(false, tags...)
+	if c.tx != nil {
+		c.tx.tags = append(c.tx.tags, tags...)
+	}
+}
+
+// invalidateAll drops every entry
+func (c *Cache) invalidateAll() {
+	c.lru.invalidate(true)
+	if c.tx != nil {
+		c.tx.all = true
+	}
+}
+
+// sharedWrite runs write on the container or item id, then drops the listings of kind of the accounts
+// collaborators finds attached to it beforehand, or every entry when they cannot be found
+func (c *Cache) sharedWrite(ctx context.Context, kind string, collaborators func(ctx context.Context, id int) ([]string, error), id int, write func() error) error {
+	usernames, lookupErr := collaborators(ctx, id)
+
+	err := write()
+
+	if lookupErr != nil {
+		c.invalidateAll()
+	} else {
+		c.invalidate(kind, usernames...)
+	}
+
+	return err
+}
+
+// listKey identifies the page read with options
+func listKey(options ListOptions) string {
+	expiringBefore := ""
+	if options.ExpiringBefore != nil {
+		expiringBefore = options.ExpiringBefore.UTC().Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprintf("%q|%d|%q|%s|%t|%q|%q", options.Cursor, options.Limit, options.Sort, expiringBefore, options.BelowThreshold, options.QuantityType, options.Q)
+}
+
+// GetFoods gets a page of the food varieties, cached
+func (c *Cache) GetFoods(ctx context.Context, options ListOptions) (FoodPage, error) {
+	value, err := c.cached(foodsTag+"|"+listKey(options), foodsTag, func() (interface{}, error) {
+		return c.Store.GetFoods(ctx, options)
+	})
+	return value.(FoodPage), err
+}
+
+// GetStorages gets a page of the storages attached to username, cached
+func (c *Cache) GetStorages(ctx context.Context, username string, options ListOptions) (FolderPage, error) {
+	tag := accountTag(storagesTag, username)
+	value, err := c.cached(tag+"|"+listKey(options), tag, func() (interface{}, error) {
+		return c.Store.GetStorages(ctx, username, options)
+	})
+	return value.(FolderPage), err
+}
+
+// GetShoppingLists gets a page of the shopping lists attached to username, cached
+func (c *Cache) GetShoppingLists(ctx context.Context, username string, options ListOptions) (ShoppingListPage, error) {
+	tag := accountTag(shoppingListsTag, username)
+	value, err := c.cached(tag+"|"+listKey(options), tag, func() (interface{}, error) {
+		return c.Store.GetShoppingLists(ctx, username, options)
+	})
+	return value.(ShoppingListPage), err
+}
+
+// UpdateAccount updates an account, dropping the listings of its old and new username
+func (c *Cache) UpdateAccount(ctx context.Context, username, newUsername, password, email string, darkTheme, notifications bool) error {
+	err := c.Store.UpdateAccount(ctx, username, newUsername, password, email, darkTheme, notifications)
+	c.invalidate(storagesTag, username, newUsername)
+	c.invalidate(shoppingListsTag, username, newUsername)
+	return err
+}
+
+// UpdateAccountUsername renames an account, dropping the listings of its old and new username
+func (c *Cache) UpdateAccountUsername(ctx context.Context, username, newUsername string) error {
+	err := c.Store.UpdateAccountUsername(ctx, username, newUsername)
+	c.invalidate(storagesTag, username, newUsername)
+	c.invalidate(shoppingListsTag, username, newUsername)
+	return err
+}
+
+// DeleteAccount deletes an account, dropping every entry as the containers it owns go with it
+func (c *Cache) DeleteAccount(ctx context.Context, username string) error {
+	err := c.Store.DeleteAccount(ctx, username)
+	c.invalidateAll()
+	return err
+}
+
+// AcceptShareRequest accepts a share request, dropping the listings of username
+func (c *Cache) AcceptShareRequest(ctx context.Context, username string, shareID int) error {
+	err := c.Store.AcceptShareRequest(ctx, username, shareID)
+	c.invalidate(storagesTag, username)
+	c.invalidate(shoppingListsTag, username)
+	return err
+}
+
+// CreateStorage creates a storage, dropping the storage listings of username
+func (c *Cache) CreateStorage(ctx context.Context, username, title string, owner bool) (int64, error) {
+	id, err := c.Store.CreateStorage(ctx, username, title, owner)
+	c.invalidate(storagesTag, username)
+	return id, err
+}
+
+// UpdateStorage updates a storage, dropping the storage listings of its collaborators
+func (c *Cache) UpdateStorage(ctx context.Context, title string, storageID, version int) error {
+	return c.sharedWrite(ctx, storagesTag, c.Store.GetStorageCollaborators, storageID, func() error {
+		return c.Store.UpdateStorage(ctx, title, storageID, version)
+	})
+}
+
+// DeleteStorage moves a storage to the trash, dropping the storage listings of its collaborators
+func (c *Cache) DeleteStorage(ctx context.Context, storageID, version int) error {
+	return c.sharedWrite(ctx, storagesTag, c.Store.GetStorageCollaborators, storageID, func() error {
+		return c.Store.DeleteStorage(ctx, storageID, version)
+	})
+}
+
+// ShareStorage attaches username to a storage, dropping its storage listings
+func (c *Cache) ShareStorage(ctx context.Context, username string, storageID int) error {
+	err := c.Store.ShareStorage(ctx, username, storageID)
+	c.invalidate(storagesTag, username)
+	return err
+}
+
+// RemoveShareStorage detaches usernameRequest from a storage, dropping the storage listings of its collaborators
+func (c *Cache) RemoveShareStorage(ctx context.Context, usernameRequest string, storageID int) error {
+	return c.sharedWrite(ctx, storagesTag, c.Store.GetStorageCollaborators, storageID, func() error {
+		return c.Store.RemoveShareStorage(ctx, usernameRequest, storageID)
+	})
+}
+
+// CreateStorageItem creates a storage item, dropping the storage listings of the collaborators, which count it
+func (c *Cache) CreateStorageItem(ctx context.Context, storageID int, title string, quantity float64, quantityType string, quantityThreshold float64, expirationThreshold int, expirationDate string) (int64, error) {
+	id := int64(0)
+	err := c.sharedWrite(ctx, storagesTag, c.Store.GetStorageCollaborators, storageID, func() (err error) {
+		id, err = c.Store.CreateStorageItem(ctx, storageID, title, quantity, quantityType, quantityThreshold, expirationThreshold, expirationDate)
+		return err
+	})
+	return id, err
+}
+
+// DeleteStorageItem moves a storage item to the trash, dropping the storage listings of the collaborators
+func (c *Cache) DeleteStorageItem(ctx context.Context, itemID, version int) error {
+	return c.sharedWrite(ctx, storagesTag, c.Store.GetStorageItemCollaborators, itemID, func() error {
+		return c.Store.DeleteStorageItem(ctx, itemID, version)
+	})
+}
+
+// CreateShoppingList creates a shopping list, dropping the shopping list listings of username
+func (c *Cache) CreateShoppingList(ctx context.Context, username, title string, owner bool) (int64, error) {
+	id, err := c.Store.CreateShoppingList(ctx, username, title, owner)
+	c.invalidate(shoppingListsTag, username)
+	return id, err
+}
+
+// UpdateShoppingListTitle renames a shopping list, dropping the shopping list listings of its collaborators
+func (c *Cache) UpdateShoppingListTitle(ctx context.Context, title string, shoppingListID, version int) error {
+	return c.sharedWrite(ctx, shoppingListsTag, c.Store.GetShoppingListCollaborators, shoppingListID, func() error {
+		return c.Store.UpdateShoppingListTitle(ctx, title, shoppingListID, version)
+	})
+}
+
+// DeleteShoppingList moves a shopping list to the trash, dropping the shopping list listings of its collaborators
+func (c *Cache) DeleteShoppingList(ctx context.Context, shoppingListID, version int) error {
+	return c.sharedWrite(ctx, shoppingListsTag, c.Store.GetShoppingListCollaborators, shoppingListID, func() error {
+		return c.Store.DeleteShoppingList(ctx, shoppingListID, version)
+	})
+}
+
+// ShareShoppingList attaches username to a shopping list, dropping its shopping list listings
+func (c *Cache) ShareShoppingList(ctx context.Context, username string, shoppingListID int) error {
+	err := c.Store.ShareShoppingList(ctx, username, shoppingListID)
+	c.invalidate(shoppingListsTag, username)
+	return err
+}
+
+// RemoveShareShoppingList detaches username from a shopping list, dropping the shopping list listings of its collaborators
+func (c *Cache) RemoveShareShoppingList(ctx context.Context, username string, shoppingListID int) error {
+	return c.sharedWrite(ctx, shoppingListsTag, c.Store.GetShoppingListCollaborators, shoppingListID, func() error {
+		return c.Store.RemoveShareShoppingList(ctx, username, shoppingListID)
+	})
+}
+
+// CreateShoppingListItem creates a shopping list item, dropping the shopping list listings of the collaborators, which count it
+func (c *Cache) CreateShoppingListItem(ctx context.Context, shoppingListID int, title string, quantity float64, quantityType string) (int64, error) {
+	id := int64(0)
+	err := c.sharedWrite(ctx, shoppingListsTag, c.Store.GetShoppingListCollaborators, shoppingListID, func() (err error) {
+		id, err = c.Store.CreateShoppingListItem(ctx, shoppingListID, title, quantity, quantityType)
+		return err
+	})
+	return id, err
+}
+
+// DeleteShoppingListItem moves a shopping list item to the trash, dropping the shopping list listings of the collaborators
+func (c *Cache) DeleteShoppingListItem(ctx context.Context, itemID, version int) error {
+	return c.sharedWrite(ctx, shoppingListsTag, c.Store.GetShoppingListItemCollaborators, itemID, func() error {
+		return c.Store.DeleteShoppingListItem(ctx, itemID, version)
+	})
+}
+
+// BatchStorageItems runs a batch on the items of a storage, its creates and deletes dropping the storage listings
+// of the collaborators as single ones do
+func (c *Cache) BatchStorageItems(ctx context.Context, storageID int, operations []ItemOperation, bestEffort bool) (BatchResult, error) {
+	return batchStorageItems(ctx, c, storageID, operations, bestEffort)
+}
+
+// BatchShoppingListItems runs a batch on the items of a shopping list, its creates and deletes dropping
+// the shopping list listings of the collaborators as single ones do
+func (c *Cache) BatchShoppingListItems(ctx context.Context, shoppingListID int, operations []ItemOperation, bestEffort bool) (BatchResult, error) {
+	return batchShoppingListItems(ctx, c, shoppingListID, operations, bestEffort)
+}
+
+// RestoreStorage brings a storage back from the trash, dropping the storage listings of its collaborators
+func (c *Cache) RestoreStorage(ctx context.Context, storageID int) error {
+	return c.sharedWrite(ctx, storagesTag, c.Store.GetStorageCollaborators, storageID, func() error {
+		return c.Store.RestoreStorage(ctx, storageID)
+	})
+}
+
+// RestoreStorageItem brings a storage item back from the trash, dropping the storage listings of the collaborators
+func (c *Cache) RestoreStorageItem(ctx context.Context, itemID int) error {
+	return c.sharedWrite(ctx, storagesTag, c.Store.GetStorageItemCollaborators, itemID, func() error {
+		return c.Store.RestoreStorageItem(ctx, itemID)
+	})
+}
+
+// RestoreShoppingList brings a shopping list back from the trash, dropping the shopping list listings of its collaborators
+func (c *Cache) RestoreShoppingList(ctx context.Context, shoppingListID int) error {
+	return c.sharedWrite(ctx, shoppingListsTag, c.Store.GetShoppingListCollaborators, shoppingListID, func() error {
+		return c.Store.RestoreShoppingList(ctx, shoppingListID)
+	})
+}
+
+// RestoreShoppingListItem brings a shopping list item back from the trash, dropping the shopping list listings of the collaborators
+func (c *Cache) RestoreShoppingListItem(ctx context.Context, itemID int) error {
+	return c.sharedWrite(ctx, shoppingListsTag, c.Store.GetShoppingListItemCollaborators, itemID, func() error {
+		return c.Store.RestoreShoppingListItem(ctx, itemID)
+	})
+}
+
+// PushChanges applies the mutations pushed by a client of username, dropping the listings each of them changes
+func (c *Cache) PushChanges(ctx context.Context, username string, mutations []Mutation) (PushResult, error) {
+	if c.tx == nil {
+		result := PushResult{}
+		err := c.WithTx(ctx, func(tx Store) error {
+			var err error
+			result, err = tx.PushChanges(ctx, username, mutations)
+			return err
+		})
+		return result, err
+	}
+
+	// The writes go through the Cache, which drops their listings, and the bookkeeping to the transaction
+	tx, ok := c.Store.(pushStore)
+	if !ok {
+		result, err := c.Store.PushChanges(ctx, username, mutations)
+		c.invalidateAll()
+		return result, err
+	}
+
+	return pushChanges(ctx, c, tx, username, mutations)
+}
+
+// ImportAccount recreates the storages and shopping lists of archive under username, its writes dropping
+// the listings they change as single ones do
+func (c *Cache) ImportAccount(ctx context.Context, username string, archive Archive) (ImportReport, error) {
+	return importAccount(ctx, c, username, archive)
+}
+
+// replaceItemEvents replaces the history of a storage item in the Store, which the Cache does not hold
+func (c *Cache) replaceItemEvents(ctx context.Context, itemID int, events []ItemEvent) error {
+	if store, ok := c.Store.(historyStore); ok {
+		return store.replaceItemEvents(ctx, itemID, events)
+	}
+	return nil
+}
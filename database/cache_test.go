@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCacheInvalidation(t *testing.T) {
+	for _, s := range testStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := NewCache(s.store, CacheOptions{Size: 100, TTL: time.Hour})
+
+			// storages lists the storages of username through the cache as title:count
+			storages := func(username string) []string {
+				page, err := cache.GetStorages(ctx, username, ListOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := []string{}
+				for _, folder := range page.Items {
+					got = append(got, fmt.Sprintf("%s:%d", folder.Title, folder.Count))
+				}
+				return got
+			}
+			check := func(step, username string, want ...string) {
+				t.Helper()
+				if want == nil {
+					want = []string{}
+				}
+				if got := storages(username); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: storages of %s = %v, want %v", step, username, got, want)
+				}
+			}
+
+			for _, username := range []string{"ann", "bob"} {
+				if _, err := cache.CreateAccount(ctx, username, username+"@example.com", "password", "salt"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			check("before any storage", "ann")
+			check("before any storage", "bob")
+
+			storageID, err := cache.CreateStorage(ctx, "ann", "Fridge", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check("created", "ann", "Fridge:0")
+			check("created", "bob")
+
+			if err := cache.ShareStorage(ctx, "bob", int(storageID)); err != nil {
+				t.Fatal(err)
+			}
+			check("shared", "bob", "Fridge:0")
+
+			itemID, err := cache.CreateStorageItem(ctx, int(storageID), "Milk", 1, "pieces", 0, 0, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			check("item created", "ann", "Fridge:1")
+			check("item created", "bob", "Fridge:1")
+
+			if err := cache.UpdateStorage(ctx, "Cellar", int(storageID), AnyVersion); err != nil {
+				t.Fatal(err)
+			}
+			check("renamed", "ann", "Cellar:1")
+			check("renamed", "bob", "Cellar:1")
+
+			if err := cache.WithTx(ctx, func(tx Store) error {
+				if err := tx.UpdateStorage(ctx, "Pantry", int(storageID), AnyVersion); err != nil {
+					return err
+				}
+				// A listing read by the transaction is not kept past it
+				_, err := tx.GetStorages(ctx, "ann", ListOptions{})
+				return err
+			}); err != nil {
+				t.Fatal(err)
+			}
+			check("renamed in a transaction", "ann", "Pantry:1")
+			check("renamed in a transaction", "bob", "Pantry:1")
+
+			errRollback := errors.New("rollback")
+			if err := cache.WithTx(ctx, func(tx Store) error {
+				if err := tx.UpdateStorage(ctx, "Attic", int(storageID), AnyVersion); err != nil {
+					return err
+				}
+				if _, err := tx.GetStorages(ctx, "ann", ListOptions{}); err != nil {
+					return err
+				}
+				return errRollback
+			}); err != errRollback {
+				t.Fatalf("rolled back transaction error = %v, want %v", err, errRollback)
+			}
+			check("renamed in a rolled back transaction", "ann", "Pantry:1")
+
+			if err := cache.DeleteStorageItem(ctx, int(itemID), AnyVersion); err != nil {
+				t.Fatal(err)
+			}
+			check("item deleted", "ann", "Pantry:0")
+			check("item deleted", "bob", "Pantry:0")
+
+			if err := cache.RemoveShareStorage(ctx, "bob", int(storageID)); err != nil {
+				t.Fatal(err)
+			}
+			check("unshared", "ann", "Pantry:0")
+			check("unshared", "bob")
+
+			if err := cache.DeleteStorage(ctx, int(storageID), AnyVersion); err != nil {
+				t.Fatal(err)
+			}
+			check("deleted", "ann")
+		})
+	}
+}
@@ -1,45 +1,252 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"time"
+	"strings"
 
-	_ "github.com/go-sql-driver/mysql" // mysql driver
+	"github.com/go-sql-driver/mysql" // mysql driver
 )
 
 // Handler structure
 type Handler struct {
-	DB *sql.DB
+	DB      *sql.DB
+	dialect dialect
+	// tx is set on the handlers passed by WithTx, whose statements then run in the transaction
+	tx      *sql.Tx
+	options Options
+	health  *healthState
+}
+
+// dialect holds what differs between the SQL databases the Handler supports
+type dialect interface {
+	// name is the dialect's directory in migrations
+	name() string
+	// rebind rewrites the ? placeholders of query into the dialect's own
+	rebind(query string) string
+	// returningID reports whether new row IDs are read with RETURNING instead of LastInsertId
+	returningID() bool
+	// translateError maps a driver error onto the Store errors
+	translateError(err error) error
+	// execScript executes a migration script made of several statements
+	execScript(ctx context.Context, tx *sql.Tx, script string) error
+	// suspendForeignKeys lets the migrations run on conn rebuild referenced tables, until restore is called
+	suspendForeignKeys(ctx context.Context, conn *sql.Conn) (restore func(), err error)
+	// checkForeignKeys returns an error if a migration left rows referring to missing ones
+	checkForeignKeys(ctx context.Context, tx *sql.Tx) error
+	// forUpdate is the clause appended to a SELECT to lock the rows it reads until the transaction ends
+	forUpdate() string
+	// binaryEquals is the condition that column holds the text of the next placeholder byte for byte, whatever its collation
+	binaryEquals(column string) string
+	// resetSequence moves the ID sequence of table past the IDs a restore inserted
+	resetSequence(ctx context.Context, tx *sql.Tx, table string) error
+}
+
+// mysqlDialect is the dialect of MySQL
+type mysqlDialect struct{}
+
+func (mysqlDialect) name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) rebind(query string) string {
+	return query
+}
+
+func (mysqlDialect) returningID() bool {
+	return false
+}
+
+// translateError maps MySQL constraint violations onto the Store errors
+func (mysqlDialect) translateError(err error) error {
+	mysqlErr := &mysql.MySQLError{}
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+
+	// Messages end with the key or column quoted, e.g. "Duplicate entry 'bob' for key 'username'"
+	quoted := strings.Split(mysqlErr.Message, "'")
+	name := ""
+	if len(quoted) >= 3 {
+		name = quoted[len(quoted)-2]
+		name = name[strings.LastIndex(name, ".")+1:]
+	}
+
+	switch mysqlErr.Number {
+	case 1062:
+		return errDuplicateKey(name, err)
+	case 1451:
+		return &Error{Kind: ErrConflict, Err: err}
+	case 1452:
+		return &Error{Kind: ErrForeignKey, Err: err}
+	case 1265, 1292, 1366, 1406:
+		return errDataTruncated(name, err)
+	default:
+		return err
+	}
+}
+
+// execScript executes the statements of script one by one, as the driver runs a single statement per query
+func (mysqlDialect) execScript(ctx context.Context, tx *sql.Tx, script string) error {
+	for _, statement := range strings.Split(script, ";\n") {
+		statement = strings.TrimSpace(statement)
+		statement = strings.TrimSuffix(statement, ";")
+		if statement == "" {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// suspendForeignKeys does nothing, MySQL migrations alter tables in place
+func (mysqlDialect) suspendForeignKeys(ctx context.Context, conn *sql.Conn) (func(), error) {
+	return func() {}, nil
+}
+
+// checkForeignKeys does nothing, the keys are never suspended
+func (mysqlDialect) checkForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+func (mysqlDialect) forUpdate() string {
+	return " FOR UPDATE"
+}
+
+func (mysqlDialect) binaryEquals(column string) string {
+	return column + " = BINARY ?"
+}
+
+// resetSequence does nothing, AUTO_INCREMENT moves past the IDs inserted
+func (mysqlDialect) resetSequence(ctx context.Context, tx *sql.Tx, table string) error {
+	return nil
 }
 
 // Init returns a new Database handler
-func Init(username, password, name, host string, port int) *Handler {
-	dbURI := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true", username, password, host, port, name)
+func Init(username, password, name, host string, port int, options Options) *Handler {
+	config := mysql.NewConfig()
+	config.User = username
+	config.Passwd = password
+	config.Net = "tcp"
+	config.Addr = fmt.Sprintf("%s:%d", host, port)
+	config.DBName = name
+	config.ParseTime = true
+	// The session reads and compares TIMESTAMP columns in UTC, like the times passed to PurgeTrash
+	config.Params = map[string]string{"time_zone": "'+00:00'"}
+	config.Timeout = options.ConnectTimeout
+	config.ReadTimeout = options.ReadTimeout
+	config.WriteTimeout = options.WriteTimeout
 
-	db, err := sql.Open("mysql", dbURI)
+	switch options.TLS {
+	case TLSRequire:
+		config.TLSConfig = "skip-verify"
+	case TLSVerify:
+		config.TLSConfig = "true"
+	}
+
+	return open("mysql", config.FormatDSN(), mysqlDialect{}, options)
+}
+
+// WithTx runs fn with a Store whose operations share a single transaction.
+// The transaction is committed when fn returns nil and rolled back otherwise.
+// Calls nested in fn join the running transaction.
+func (handler *Handler) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		return fn(tx)
+	})
+}
+
+// withTx is WithTx for the handler's own methods, which need the unexported helpers
+func (handler *Handler) withTx(ctx context.Context, fn func(tx *Handler) error) error {
+	if handler.tx != nil {
+		return fn(handler)
+	}
+
+	tx, err := handler.DB.BeginTx(ctx, nil)
 	if err != nil {
-		log.Fatal(err)
-		return nil
+		return err
+	}
+
+	txHandler := *handler
+	txHandler.tx = tx
+
+	if err := fn(&txHandler); err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	return &Handler{
-		db,
+	return tx.Commit()
+}
+
+// prepare prepares query, written with ? placeholders, for the handler's dialect
+func (handler *Handler) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	if handler.tx != nil {
+		return handler.tx.PrepareContext(ctx, handler.dialect.rebind(query))
 	}
+	return handler.DB.PrepareContext(ctx, handler.dialect.rebind(query))
 }
 
-// EnsureConnected pings the database to validate whether it is alive or not
-func (handler *Handler) EnsureConnected() error {
-	if err := handler.DB.Ping(); err != nil {
-		for i := 0; i <= 3; i++ {
-			log.Fatal(err)
-			time.Sleep(time.Duration(5) * time.Second)
-			if err := handler.DB.Ping(); err == nil {
-				break
-			}
+// insert executes an INSERT query and returns the ID of the new row
+func (handler *Handler) insert(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	lastInsertID := int64(0)
+
+	if handler.dialect.returningID() {
+		stmt, err := handler.prepare(ctx, query+" RETURNING id")
+		if err != nil {
+			return lastInsertID, err
 		}
-		return err
+
+		defer stmt.Close()
+
+		if err := stmt.QueryRowContext(ctx, args...).Scan(&lastInsertID); err != nil {
+			return lastInsertID, handler.dialect.translateError(err)
+		}
+
+		return lastInsertID, nil
 	}
-	return nil
+
+	stmt, err := handler.prepare(ctx, query)
+	if err != nil {
+		return lastInsertID, err
+	}
+
+	defer stmt.Close()
+
+	result, err := handler.exec(ctx, stmt, args...)
+	if err != nil {
+		return lastInsertID, err
+	}
+
+	return result.LastInsertId()
+}
+
+// run prepares and executes a single statement
+func (handler *Handler) run(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := handler.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	return handler.exec(ctx, stmt, args...)
+}
+
+// exec executes a prepared statement, reporting driver errors alike on every dialect
+func (handler *Handler) exec(ctx context.Context, stmt *sql.Stmt, args ...interface{}) (sql.Result, error) {
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return result, handler.dialect.translateError(err)
+	}
+	return result, nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
 }
@@ -0,0 +1,86 @@
+package database
+
+import "context"
+
+// GetStorageCollaborators gets the usernames of the accounts attached to a storage by ID
+func (handler *Handler) GetStorageCollaborators(ctx context.Context, storageID int) ([]string, error) {
+	return handler.usernames(ctx, `
+		SELECT a.username
+		FROM account_storage_binder AS asb
+		INNER JOIN accounts AS a
+		ON a.id = asb.account_id
+		WHERE asb.storage_id = ?
+		ORDER BY a.username
+	`, storageID)
+}
+
+// GetStorageItemCollaborators gets the usernames of the accounts attached to the storage of an item by ID, trashed or not
+func (handler *Handler) GetStorageItemCollaborators(ctx context.Context, itemID int) ([]string, error) {
+	return handler.usernames(ctx, `
+		SELECT a.username
+		FROM account_storage_binder AS asb
+		INNER JOIN storage_items AS si
+		ON si.storage_id = asb.storage_id
+		INNER JOIN accounts AS a
+		ON a.id = asb.account_id
+		WHERE si.id = ?
+		ORDER BY a.username
+	`, itemID)
+}
+
+// GetShoppingListCollaborators gets the usernames of the accounts attached to a shopping list by ID
+func (handler *Handler) GetShoppingListCollaborators(ctx context.Context, shoppingListID int) ([]string, error) {
+	return handler.usernames(ctx, `
+		SELECT a.username
+		FROM account_shopping_list_binder AS aslb
+		INNER JOIN accounts AS a
+		ON a.id = aslb.account_id
+		WHERE aslb.shopping_list_id = ?
+		ORDER BY a.username
+	`, shoppingListID)
+}
+
+// GetShoppingListItemCollaborators gets the usernames of the accounts attached to the shopping list of an item by ID, trashed or not
+func (handler *Handler) GetShoppingListItemCollaborators(ctx context.Context, itemID int) ([]string, error) {
+	return handler.usernames(ctx, `
+		SELECT a.username
+		FROM account_shopping_list_binder AS aslb
+		INNER JOIN shopping_list_items AS sli
+		ON sli.shopping_list_id = aslb.shopping_list_id
+		INNER JOIN accounts AS a
+		ON a.id = aslb.account_id
+		WHERE sli.id = ?
+		ORDER BY a.username
+	`, itemID)
+}
+
+// usernames returns the usernames selected by query
+func (handler *Handler) usernames(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	usernames := []string{}
+
+	stmt, err := handler.prepare(ctx, query)
+	if err != nil {
+		return usernames, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return usernames, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		username := ""
+
+		if err := rows.Scan(&username); err != nil {
+			return usernames, err
+		}
+
+		usernames = append(usernames, username)
+	}
+
+	return usernames, rows.Err()
+}
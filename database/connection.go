@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Options configures the connection pool of a Handler and how it connects
+type Options struct {
+	// MaxOpenConns bounds the open connections, 0 for no bound
+	MaxOpenConns int
+	// MaxIdleConns bounds the idle connections kept for reuse
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections older than it, 0 to keep them
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime closes connections idle for longer than it, 0 to keep them
+	ConnMaxIdleTime time.Duration
+
+	// ConnectTimeout bounds dialing the server, 0 for the driver's default
+	ConnectTimeout time.Duration
+	// ReadTimeout and WriteTimeout bound the network I/O of a query, 0 for none. MySQL only.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// TLS is one of TLSDisable, TLSRequire or TLSVerify, empty for TLSDisable
+	TLS string
+
+	// ConnectRetries is the number of pings EnsureConnected retries after the first one fails
+	ConnectRetries int
+	// RetryDelay is the delay before the first retry, doubled on each retry up to RetryMaxDelay
+	RetryDelay    time.Duration
+	RetryMaxDelay time.Duration
+
+	// HealthInterval is the period of the background health probe, 0 to probe on each Health call instead
+	HealthInterval time.Duration
+}
+
+// TLS modes of Options
+const (
+	// TLSDisable connects in plain text
+	TLSDisable = "disable"
+	// TLSRequire encrypts the connection without verifying the server certificate
+	TLSRequire = "require"
+	// TLSVerify encrypts the connection and verifies the server certificate and host name
+	TLSVerify = "verify"
+)
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		MaxOpenConns:    25,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+		ConnectTimeout:  5 * time.Second,
+		ReadTimeout:     30 * time.Second,
+		WriteTimeout:    30 * time.Second,
+		TLS:             TLSDisable,
+		ConnectRetries:  5,
+		RetryDelay:      time.Second,
+		RetryMaxDelay:   30 * time.Second,
+		HealthInterval:  30 * time.Second,
+	}
+}
+
+// Health is the state of a Store's connection
+type Health struct {
+	Up        bool      `json:"up"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Pool      PoolStats `json:"pool"`
+	// Cache holds the statistics of the Cache in front of the store, if any
+	Cache *CacheStats `json:"cache,omitempty"`
+}
+
+// PoolStats are the statistics of a connection pool
+type PoolStats struct {
+	MaxOpenConnections int           `json:"maxOpenConnections"`
+	OpenConnections    int           `json:"openConnections"`
+	InUse              int           `json:"inUse"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"waitCount"`
+	WaitDuration       time.Duration `json:"waitDuration"`
+	MaxIdleClosed      int64         `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64         `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64         `json:"maxLifetimeClosed"`
+}
+
+// healthState holds the result of the last health probe, shared by a Handler and its transactions
+type healthState struct {
+	mu        sync.RWMutex
+	err       error
+	checkedAt time.Time
+}
+
+// open opens the pool of driver on dsn and configures it with options
+func open(driver, dsn string, dialect dialect, options Options) *Handler {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		log.Fatal(err)
+		return nil
+	}
+
+	db.SetMaxOpenConns(options.MaxOpenConns)
+	db.SetMaxIdleConns(options.MaxIdleConns)
+	db.SetConnMaxLifetime(options.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(options.ConnMaxIdleTime)
+
+	return &Handler{
+		DB:      db,
+		dialect: dialect,
+		options: options,
+		health:  &healthState{},
+	}
+}
+
+// EnsureConnected pings the database until it answers, retrying with an exponential backoff and jitter
+func (handler *Handler) EnsureConnected(ctx context.Context) error {
+	delay := handler.options.RetryDelay
+
+	for attempt := 0; ; attempt++ {
+		err := handler.ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= handler.options.ConnectRetries {
+			return err
+		}
+
+		// Waiting between half and all of delay keeps restarted instances from retrying in step
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+		log.Printf("database unreachable (%v), retrying in %s", err, wait.Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+		if delay > handler.options.RetryMaxDelay {
+			delay = handler.options.RetryMaxDelay
+		}
+	}
+}
+
+// ProbeHealth pings the database every HealthInterval until ctx is done
+func (handler *Handler) ProbeHealth(ctx context.Context) {
+	if handler.options.HealthInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(handler.options.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			wasUp := handler.Health(ctx).Up
+
+			if err := handler.ping(ctx); err != nil && wasUp {
+				log.Printf("database health probe failed: %v", err)
+			} else if err == nil && !wasUp {
+				log.Print("database health probe recovered")
+			}
+		}
+	}
+}
+
+// Health returns the result of the last health probe along with the pool statistics.
+// Without a background probe or a previous result, the database is pinged first.
+func (handler *Handler) Health(ctx context.Context) Health {
+	handler.health.mu.RLock()
+	checked := !handler.health.checkedAt.IsZero()
+	handler.health.mu.RUnlock()
+
+	if handler.options.HealthInterval <= 0 || !checked {
+		handler.ping(ctx)
+	}
+
+	handler.health.mu.RLock()
+	defer handler.health.mu.RUnlock()
+
+	health := Health{
+		Up:        handler.health.err == nil,
+		CheckedAt: handler.health.checkedAt,
+		Pool:      poolStats(handler.DB.Stats()),
+	}
+
+	if handler.health.err != nil {
+		health.Error = handler.health.err.Error()
+	}
+
+	return health
+}
+
+// ping pings the database within ConnectTimeout and records the result for Health
+func (handler *Handler) ping(ctx context.Context) error {
+	if handler.options.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, handler.options.ConnectTimeout)
+		defer cancel()
+	}
+
+	err := handler.DB.PingContext(ctx)
+
+	handler.health.mu.Lock()
+	handler.health.err = err
+	handler.health.checkedAt = time.Now()
+	handler.health.mu.Unlock()
+
+	return err
+}
+
+func poolStats(stats sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration,
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
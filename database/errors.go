@@ -0,0 +1,78 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Kinds of errors reported by every Store, to be tested with errors.Is
+var (
+	// ErrNotFound reports that the row to read, update or delete does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict reports that a unique value is already taken or that the row is still referenced
+	ErrConflict = errors.New("conflict")
+	// ErrForeignKey reports that a row refers to another row which does not exist
+	ErrForeignKey = errors.New("foreign key violation")
+	// ErrValidation reports a value the schema does not allow
+	ErrValidation = errors.New("validation failed")
+	// ErrStale reports a write expecting a version the row no longer has
+	ErrStale = errors.New("stale version")
+	// ErrCursorExpired reports a sync cursor older than the tombstones kept, which the client has to sync without
+	ErrCursorExpired = errors.New("cursor expired")
+)
+
+// Error is an error of one of the kinds above.
+// It keeps the driver error it was translated from, which is meant for logs only.
+type Error struct {
+	Kind error
+	// Field is the column or unique key involved, when known
+	Field string
+	// Err is the driver error, if any
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Field != "" {
+		msg += " on " + e.Field
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Is reports whether target is the kind of e
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors shared by the stores
+var (
+	errNoRows          = &Error{Kind: ErrNotFound, Err: sql.ErrNoRows}
+	errNoRowsAffected  = &Error{Kind: ErrNotFound}
+	errForeignKeyChild = &Error{Kind: ErrForeignKey}
+	errStale           = &Error{Kind: ErrStale, Field: "version"}
+)
+
+// errDuplicateKey reports a value already taken in the unique key
+func errDuplicateKey(key string, err error) error {
+	return &Error{Kind: ErrConflict, Field: key, Err: err}
+}
+
+// errDataTruncated reports a value not allowed in column
+func errDataTruncated(column string, err error) error {
+	return &Error{Kind: ErrValidation, Field: column, Err: err}
+}
+
+// rowError reports a missing row as ErrNotFound
+func rowError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return errNoRows
+	}
+	return err
+}
@@ -0,0 +1,288 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ItemEvent is the change of one field of a storage item
+type ItemEvent struct {
+	ID     int    `json:"id"`
+	ItemID int    `json:"itemID"`
+	Actor  string `json:"actor"`
+	Field  string `json:"field"`
+	// OldValue is nil when the item was created, NewValue is never nil
+	OldValue  *string   `json:"oldValue"`
+	NewValue  *string   `json:"newValue"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ItemHistory is a page of the events of a storage item, latest first
+type ItemHistory struct {
+	Events []ItemEvent `json:"events"`
+	// NextCursor is the cursor of the following page, 0 on the last page
+	NextCursor int `json:"nextCursor,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx whose writes are recorded in the item history as made by username
+func WithActor(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, actorKey{}, username)
+}
+
+// actorOf returns the username given to WithActor, empty if none
+func actorOf(ctx context.Context) string {
+	username, _ := ctx.Value(actorKey{}).(string)
+	return username
+}
+
+// itemChange is the change of one field of a storage item, yet to be recorded
+type itemChange struct {
+	field    string
+	oldValue *string
+	newValue *string
+}
+
+// itemChanges lists the fields changed from before to after, every field but deleted when before is nil
+func itemChanges(before, after *Item) []itemChange {
+	changes := []itemChange{}
+	if after == nil {
+		return changes
+	}
+
+	field := func(name string, value func(item *Item) string) {
+		newValue := value(after)
+		if before == nil {
+			changes = append(changes, itemChange{field: name, newValue: &newValue})
+			return
+		}
+		if oldValue := value(before); oldValue != newValue {
+			changes = append(changes, itemChange{field: name, oldValue: &oldValue, newValue: &newValue})
+		}
+	}
+
+	field("title", func(item *Item) string { return item.Title })
+	field("image", func(item *Item) string { return item.Image })
+	field("quantity", func(item *Item) string { return formatQuantity(item.Quantity) })
+	field("quantityType", func(item *Item) string { return item.QuantityType })
+	field("quantityThreshold", func(item *Item) string { return formatQuantity(item.QuantityThreshold) })
+	field("expirationThreshold", func(item *Item) string { return strconv.Itoa(item.ExpirationThreshold) })
+	field("expirationDate", func(item *Item) string { return item.ExpirationDate })
+	// Items are created out of the trash
+	if before != nil {
+		field("deleted", func(item *Item) string { return strconv.FormatBool(item.DeletedAt != nil) })
+	}
+
+	return changes
+}
+
+// deletion is the change of an item moved to the trash, or out of it when deleted is false
+func deletion(deleted bool) []itemChange {
+	oldValue, newValue := strconv.FormatBool(!deleted), strconv.FormatBool(deleted)
+	return []itemChange{{field: "deleted", oldValue: &oldValue, newValue: &newValue}}
+}
+
+// formatQuantity formats a quantity as recorded in the history, whole quantities without decimals as they were
+// before quantities could be fractional
+func formatQuantity(quantity float64) string {
+	return strconv.FormatFloat(quantity, 'f', -1, 64)
+}
+
+// storageItemAnyState gets a storage item by ID, trashed or not, locking it until the transaction ends.
+// It returns nil when the item does not exist.
+func (handler *Handler) storageItemAnyState(ctx context.Context, itemID int) (*Item, error) {
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+itemColumns+`
+		FROM storage_items
+		WHERE id = ?`+handler.dialect.forUpdate())
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	item := Item{}
+
+	if err := scanItem(stmt.QueryRowContext(ctx, itemID), &item); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// recordingItem runs write in a transaction and records how it changed the storage item itemID
+func (handler *Handler) recordingItem(ctx context.Context, itemID int, write func(tx *Handler) error) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		before, err := tx.storageItemAnyState(ctx, itemID)
+		if err != nil {
+			return err
+		}
+
+		if err := write(tx); err != nil {
+			return err
+		}
+
+		after, err := tx.storageItemAnyState(ctx, itemID)
+		if err != nil {
+			return err
+		}
+
+		// A new expiration date is alerted of anew
+		if after.ExpirationDate != before.ExpirationDate {
+			if err := tx.clearItemAlerts(ctx, itemID, AlertExpiring, AlertExpired); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.checkStock(ctx, before, after); err != nil {
+			return err
+		}
+
+		return tx.recordItemEvents(ctx, []int{itemID}, itemChanges(before, after))
+	})
+}
+
+// recordItemEvents records changes for each of the storage items itemIDs as made by the actor of ctx,
+// by nobody when no account has its username
+func (handler *Handler) recordItemEvents(ctx context.Context, itemIDs []int, changes []itemChange) error {
+	if len(itemIDs) == 0 || len(changes) == 0 {
+		return nil
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		INSERT INTO storage_item_events(item_id, actor_id, field, old_value, new_value)
+		VALUES(?, `+accountIDOf+`, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	actor := actorOf(ctx)
+
+	for _, itemID := range itemIDs {
+		for _, change := range changes {
+			if _, err := handler.exec(ctx, stmt, itemID, actor, change.field, change.oldValue, change.newValue); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// historyStore is a Store whose item history an import writes, which the Stores of the package are
+type historyStore interface {
+	// replaceItemEvents replaces the history of the storage item itemID by events, oldest first, each recorded
+	// as made by its actor at its time, by nobody when no account has its username
+	replaceItemEvents(ctx context.Context, itemID int, events []ItemEvent) error
+}
+
+func (handler *Handler) replaceItemEvents(ctx context.Context, itemID int, events []ItemEvent) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		if _, err := tx.run(ctx, `
+			DELETE FROM storage_item_events
+			WHERE item_id = ?
+		`, itemID); err != nil {
+			return err
+		}
+
+		stmt, err := tx.prepare(ctx, `
+			INSERT INTO storage_item_events(item_id, actor_id, field, old_value, new_value, created_at)
+			VALUES(?, `+accountIDOf+`, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return err
+		}
+
+		defer stmt.Close()
+
+		for _, event := range events {
+			if _, err := tx.exec(ctx, stmt, itemID, event.Actor, event.Field, event.OldValue, event.NewValue, event.CreatedAt.UTC()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetStorageItemHistory gets a page of the events of a storage item, trashed or not, by storageID and ID.
+// The page holds the events before the cursor, all of them from the latest when cursor is 0.
+func (handler *Handler) GetStorageItemHistory(ctx context.Context, storageID, itemID, cursor, limit int) (ItemHistory, error) {
+	history := ItemHistory{Events: []ItemEvent{}}
+	limit = pageLimit(limit)
+
+	exists, err := handler.ids(ctx, `
+		SELECT id
+		FROM storage_items
+		WHERE storage_id = ? AND id = ?
+	`, storageID, itemID)
+	if err != nil {
+		return history, err
+	}
+
+	if len(exists) == 0 {
+		return history, errNoRows
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT sie.id, sie.item_id, COALESCE(a.username, ''), sie.field, sie.old_value, sie.new_value, sie.created_at
+		FROM storage_item_events AS sie
+		LEFT JOIN accounts AS a
+		ON a.id = sie.actor_id
+		WHERE sie.item_id = ? AND (? = 0 OR sie.id < ?)
+		ORDER BY sie.id DESC
+		LIMIT ?
+	`)
+	if err != nil {
+		return history, err
+	}
+
+	defer stmt.Close()
+
+	// One more event than the page holds tells whether there is a next page
+	rows, err := stmt.QueryContext(ctx, itemID, cursor, cursor, limit+1)
+	if err != nil {
+		return history, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		event := ItemEvent{}
+
+		if err := rows.Scan(
+			&event.ID,
+			&event.ItemID,
+			&event.Actor,
+			&event.Field,
+			&event.OldValue,
+			&event.NewValue,
+			&event.CreatedAt,
+		); err != nil {
+			return history, err
+		}
+
+		history.Events = append(history.Events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return history, err
+	}
+
+	if len(history.Events) > limit {
+		history.Events = history.Events[:limit]
+		history.NextCursor = history.Events[limit-1].ID
+	}
+
+	return history, nil
+}
@@ -0,0 +1,497 @@
+package database
+
+import (
+	"cat-clerk-api/units"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListOptions selects, sorts and pages the rows of the list operations.
+// Each list ignores the filters which do not apply to its rows.
+type ListOptions struct {
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+	// Limit is the page size, defaultPageLimit when 0 and at most maxPageLimit
+	Limit int
+	// Sort is one of the sort keys of the list, prefixed with "-" for descending order.
+	// Rows are sorted by ID when it is empty, and by ID among equal keys otherwise.
+	Sort string
+	// ExpiringBefore keeps the storage items with an expiration date before it
+	ExpiringBefore *time.Time
+	// BelowThreshold keeps the storage items whose quantity is at or below their quantity threshold
+	BelowThreshold bool
+	// QuantityType keeps the items of this quantity type
+	QuantityType string
+	// Q keeps the rows whose title, or name for foods, contains it regardless of case
+	Q string
+}
+
+// FolderPage is a page of storages
+type FolderPage struct {
+	Items []Folder `json:"items"`
+	// NextCursor is the cursor of the following page, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Total is the number of rows matching the filters, on every page
+	Total int `json:"total"`
+}
+
+// ItemPage is a page of storage items
+type ItemPage struct {
+	Items      []Item `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
+// ShoppingListPage is a page of shopping lists
+type ShoppingListPage struct {
+	Items      []ShoppingList `json:"items"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+	Total      int            `json:"total"`
+}
+
+// ShoppingListItemPage is a page of shopping list items
+type ShoppingListItemPage struct {
+	Items      []ShoppingListItem `json:"items"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+	Total      int                `json:"total"`
+}
+
+// FoodPage is a page of the foods catalog
+type FoodPage struct {
+	Items      []Foods `json:"items"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+	Total      int     `json:"total"`
+}
+
+// Page sizes of the list operations and GetStorageItemHistory
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pageLimit returns limit within (0, maxPageLimit], defaultPageLimit when unset
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+// Errors of the list options
+var (
+	errInvalidSort   = &Error{Kind: ErrValidation, Field: "sort"}
+	errInvalidCursor = &Error{Kind: ErrValidation, Field: "cursor"}
+)
+
+// sortExpr is an expression rows are sorted by, whose %[1]s stand for the alias of the table
+type sortExpr struct {
+	sql string
+	// args are the arguments of the placeholders of sql
+	args []interface{}
+	// nullable expressions are null on both rows compared, or on neither, once the previous expressions are equal
+	nullable bool
+}
+
+// sortKey is the expressions a sort option orders rows by, before their ID
+type sortKey []sortExpr
+
+// column sorts rows by a column which is never null
+func column(name string) sortKey {
+	return sortKey{{sql: "%[1]s." + name}}
+}
+
+// byExpirationDate sorts storage items by expiration date, those without one last.
+// Items without a date hold NULL or, depending on the database, an empty string or a zero date,
+// all of which are not after the zero time.
+var byExpirationDate = sortKey{
+	{sql: "CASE WHEN %[1]s.expiration_date > ? THEN 0 ELSE 1 END", args: []interface{}{time.Time{}}},
+	{sql: "CASE WHEN %[1]s.expiration_date > ? THEN %[1]s.expiration_date END", args: []interface{}{time.Time{}}, nullable: true},
+}
+
+// byQuantity sorts items by their quantity in the base unit of its type, so that 500 grams come before 1 kilos.
+// Quantities of different dimensions are sorted as they come, mass and volume alike.
+var byQuantity = func() sortKey {
+	names := []string{}
+	for name := range quantityTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cases := ""
+	for _, name := range names {
+		if factor := units.Factor(name); factor != 1 {
+			cases += fmt.Sprintf(" WHEN '%s' THEN %g", name, factor)
+		}
+	}
+
+	return sortKey{{sql: "%[1]s.quantity * CASE %[1]s.quantity_type" + cases + " ELSE 1 END"}}
+}()
+
+// baseQuantity is the quantity of quantityType in the base unit of its dimension, see byQuantity
+func baseQuantity(quantity float64, quantityType string) float64 {
+	base, err := units.Normalize(units.Quantity{Amount: quantity, Unit: quantityType})
+	if err != nil {
+		return quantity
+	}
+	return base.Amount
+}
+
+// Sort keys of the lists
+var (
+	storageSorts = map[string]sortKey{
+		"title":      column("title"),
+		"updated_at": column("updated_at"),
+	}
+	storageItemSorts = map[string]sortKey{
+		"title":           column("title"),
+		"expiration_date": byExpirationDate,
+		"quantity":        byQuantity,
+		"updated_at":      column("updated_at"),
+	}
+	shoppingListSorts = map[string]sortKey{
+		"title":      column("title"),
+		"updated_at": column("updated_at"),
+	}
+	shoppingListItemSorts = map[string]sortKey{
+		"title":      column("title"),
+		"quantity":   byQuantity,
+		"updated_at": column("updated_at"),
+	}
+	foodSorts = map[string]sortKey{
+		"name": column("name"),
+	}
+)
+
+// listOrder is a parsed sort option
+type listOrder struct {
+	// name is the sort option, cursors are only valid for the order they were made for
+	name string
+	// key is nil when rows are sorted by ID alone
+	key  sortKey
+	desc bool
+}
+
+// parseSort parses the sort option s against the sort keys of a list
+func parseSort(s string, sorts map[string]sortKey) (listOrder, error) {
+	order := listOrder{name: s}
+	if s == "" {
+		return order, nil
+	}
+
+	name := s
+	if strings.HasPrefix(name, "-") {
+		name = name[1:]
+		order.desc = true
+	}
+
+	key, ok := sorts[name]
+	if !ok {
+		return order, errInvalidSort
+	}
+
+	order.key = key
+	return order, nil
+}
+
+// keyName is the sort key of the order, without its direction
+func (order listOrder) keyName() string {
+	return strings.TrimPrefix(order.name, "-")
+}
+
+// cursor is the cursor of the page following the row id
+func (order listOrder) cursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(order.name + ":" + strconv.Itoa(id)))
+}
+
+// after decodes cursor into the ID of the row the page starts after, 0 when cursor is empty
+func (order listOrder) after(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+
+	colon := strings.LastIndex(string(decoded), ":")
+	if colon < 0 || string(decoded[:colon]) != order.name {
+		return 0, errInvalidCursor
+	}
+
+	id, err := strconv.Atoi(string(decoded[colon+1:]))
+	if err != nil || id <= 0 {
+		return 0, errInvalidCursor
+	}
+
+	return id, nil
+}
+
+// by is the ORDER BY list of rows aliased alias
+func (order listOrder) by(alias string) (string, []interface{}) {
+	direction := ""
+	if order.desc {
+		direction = " DESC"
+	}
+
+	terms := []string{}
+	args := []interface{}{}
+	for _, expr := range order.key {
+		terms = append(terms, fmt.Sprintf(expr.sql, alias)+direction)
+		args = append(args, expr.args...)
+	}
+	terms = append(terms, alias+".id"+direction)
+
+	return strings.Join(terms, ", "), args
+}
+
+// keyset is the condition keeping the rows aliased alias which come after the row afterID of table.
+// The values of that row are read by subqueries, so that it may have changed or left the filters since.
+func (order listOrder) keyset(alias, table string, afterID int) (string, []interface{}) {
+	op := " > "
+	if order.desc {
+		op = " < "
+	}
+
+	condition := alias + ".id" + op + "?"
+	args := []interface{}{afterID}
+
+	for i := len(order.key) - 1; i >= 0; i-- {
+		expr := order.key[i]
+		row := fmt.Sprintf(expr.sql, alias)
+		after := "(SELECT " + fmt.Sprintf(expr.sql, "c") + " FROM " + table + " AS c WHERE c.id = ?)"
+		afterArgs := append(append([]interface{}{}, expr.args...), afterID)
+
+		equal := row + " = " + after
+		equalArgs := append(append([]interface{}{}, expr.args...), afterArgs...)
+		if expr.nullable {
+			equal = "(" + equal + " OR " + row + " IS NULL)"
+			equalArgs = append(equalArgs, expr.args...)
+		}
+
+		condition = "(" + row + op + after + " OR (" + equal + " AND " + condition + "))"
+		args = append(append(append(append([]interface{}{}, expr.args...), afterArgs...), equalArgs...), args...)
+	}
+
+	return condition, args
+}
+
+// listFilter accumulates the conditions of a list query and their arguments
+type listFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+// and adds condition, whose placeholders stand for args
+func (filter *listFilter) and(condition string, args ...interface{}) {
+	filter.conditions = append(filter.conditions, condition)
+	filter.args = append(filter.args, args...)
+}
+
+// where is the WHERE clause of the conditions
+func (filter *listFilter) where() string {
+	if len(filter.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(filter.conditions, " AND ")
+}
+
+// contains adds the condition that column contains q regardless of case
+func (filter *listFilter) contains(column, q string) {
+	escaped := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(q))
+	filter.and("LOWER("+column+") LIKE ? ESCAPE '!'", "%"+escaped+"%")
+}
+
+// listQuery describes the query of a list
+type listQuery struct {
+	// columns is the SELECT list
+	columns string
+	// from is the FROM clause, in which the listed table is aliased alias
+	from  string
+	table string
+	alias string
+	// groupBy is the GROUP BY clause, if any
+	groupBy string
+}
+
+// page runs query with the conditions of filter and returns the cursor of the following page, if any.
+// It scans the rows of the page with scan, which returns the ID of the row.
+func (handler *Handler) page(ctx context.Context, query listQuery, filter listFilter, order listOrder, options ListOptions, scan func(row scanner) (int, error)) (string, error) {
+	afterID, err := order.after(options.Cursor)
+	if err != nil {
+		return "", err
+	}
+
+	if afterID != 0 {
+		condition, args := order.keyset(query.alias, query.table, afterID)
+		filter.and(condition, args...)
+	}
+
+	orderBy, orderArgs := order.by(query.alias)
+	limit := pageLimit(options.Limit)
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+query.columns+`
+		FROM `+query.from+`
+		`+filter.where()+`
+		`+query.groupBy+`
+		ORDER BY `+orderBy+`
+		LIMIT ?
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	defer stmt.Close()
+
+	// One more row than the page holds tells whether there is a next page
+	args := append(append(append([]interface{}{}, filter.args...), orderArgs...), limit+1)
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close()
+
+	lastID, count := 0, 0
+	for rows.Next() {
+		if count == limit {
+			return order.cursor(lastID), rows.Err()
+		}
+
+		id, err := scan(rows)
+		if err != nil {
+			return "", err
+		}
+
+		lastID = id
+		count++
+	}
+
+	return "", rows.Err()
+}
+
+// count counts the rows of from matching filter
+func (handler *Handler) count(ctx context.Context, from string, filter listFilter) (int, error) {
+	stmt, err := handler.prepare(ctx, `
+		SELECT COUNT(*)
+		FROM `+from+`
+		`+filter.where())
+	if err != nil {
+		return 0, err
+	}
+
+	defer stmt.Close()
+
+	count := 0
+
+	if err := stmt.QueryRowContext(ctx, filter.args...).Scan(
+		&count,
+	); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// sortRow is a row of the memory store with the values it is sorted by, as listed by the sort key
+type sortRow struct {
+	id     int
+	values []interface{}
+}
+
+// compareValues compares sort values alike: nil, int, float64, case insensitive string or time.Time
+func compareValues(a, b []interface{}) int {
+	for i := range a {
+		switch x := a[i].(type) {
+		case nil:
+			if b[i] != nil {
+				return 1
+			}
+		case int:
+			if y := b[i].(int); x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case float64:
+			if y := b[i].(float64); x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case string:
+			if c := strings.Compare(strings.ToLower(x), strings.ToLower(b[i].(string))); c != 0 {
+				return c
+			}
+		case time.Time:
+			y := b[i].(time.Time)
+			if x.Before(y) {
+				return -1
+			}
+			if x.After(y) {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// memoryPage sorts the rows of the memory store matching the filters and returns the IDs of the page
+// following after, nil for the first page, along with the cursor of the following page, if any.
+// Like the subqueries of listOrder.keyset, an after row without values, which no longer exists, ends the list
+// unless rows are sorted by ID.
+func memoryPage(rows []sortRow, order listOrder, after *sortRow, limit int) ([]int, string) {
+	if after != nil && after.values == nil && order.key != nil {
+		return []int{}, ""
+	}
+
+	less := func(a, b sortRow) bool {
+		c := compareValues(a.values, b.values)
+		if c == 0 {
+			c = a.id - b.id
+		}
+		if order.desc {
+			return c > 0
+		}
+		return c < 0
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return less(rows[i], rows[j])
+	})
+
+	if after != nil {
+		start := sort.Search(len(rows), func(i int) bool {
+			return less(*after, rows[i])
+		})
+		rows = rows[start:]
+	}
+
+	limit = pageLimit(limit)
+	ids := []int{}
+	for _, row := range rows {
+		if len(ids) == limit {
+			return ids, order.cursor(ids[limit-1])
+		}
+		ids = append(ids, row.id)
+	}
+
+	return ids, ""
+}
+
+// memoryContains reports whether s contains q regardless of case, like listFilter.contains
+func memoryContains(s, q string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(q))
+}
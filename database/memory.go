@@ -0,0 +1,2625 @@
+package database
+
+import (
+	"cat-clerk-api/units"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quantityTypes holds the values allowed by the quantity_type enum
+var quantityTypes = map[string]bool{
+	"grams":       true,
+	"kilos":       true,
+	"pieces":      true,
+	"liters":      true,
+	"milliliters": true,
+}
+
+// memoryStep checks by as checkStep does, for an item of quantityType, empty when the item is missing
+func memoryStep(by float64, quantityType string) error {
+	if by <= 0 {
+		return errDataTruncated("by", nil)
+	}
+	return ValidateQuantity("by", by, quantityType)
+}
+
+// stepQuantity adds delta to quantity of quantityType, stopping at 0, rounded as the databases do
+func stepQuantity(quantity, delta float64, quantityType string) float64 {
+	return math.Max(0, units.Round(units.Quantity{Amount: quantity + delta, Unit: quantityType}).Amount)
+}
+
+// checkVersion returns errStale unless version is AnyVersion or the current version of the row
+func checkVersion(current, version int) error {
+	if version != AnyVersion && version != current {
+		return errStale
+	}
+	return nil
+}
+
+// binder attaches an account to a storage or shopping list
+type binder struct {
+	username    string
+	containerID int
+	owner       bool
+	createdAt   time.Time
+}
+
+// Memory is a thread-safe in-memory Store.
+// It follows the constraints declared by the MySQL schema and is meant for tests and local development.
+// Its operations never wait on I/O so they only read the actor of their context, and WithTx drops the changes made after ctx is done.
+type Memory struct {
+	mu sync.RWMutex
+
+	accounts            map[int]Account
+	foods               []Foods
+	shareRequests       map[int]ShareRequest
+	storages            map[int]Folder
+	storageItems        map[int]Item
+	storageItemEvents   []ItemEvent
+	shoppingLists       map[int]ShoppingList
+	shoppingListItems   map[int]ShoppingListItem
+	storageBinders      []binder
+	shoppingListBinders []binder
+	tombstones          []memoryTombstone
+	pushedMutations     []memoryPushedMutation
+	itemAlerts          []memoryItemAlert
+	alertDeliveries     []memoryAlertDelivery
+	// titleUpdatedAts holds when the title of a row last changed, the title_updated_at column of the databases
+	titleUpdatedAts map[memoryRow]time.Time
+	// purgedBefore is the time the tombstones were last purged up to, the sync_purges table of the databases
+	purgedBefore time.Time
+
+	lastIDs map[string]int
+}
+
+// memoryRow names a row of a kind of Mutation
+type memoryRow struct {
+	kind string
+	id   int
+}
+
+// memoryTombstone is a Tombstone left to an account
+type memoryTombstone struct {
+	username string
+	Tombstone
+}
+
+// memoryPushedMutation is a Mutation applied for an account
+type memoryPushedMutation struct {
+	username  string
+	createdAt time.Time
+	MutationResult
+}
+
+// memoryItemAlert is an alert stage reached by a storage item
+type memoryItemAlert struct {
+	id int
+	itemAlert
+}
+
+// memoryAlertDelivery is an alert to send to an account by ID
+type memoryAlertDelivery struct {
+	id          int
+	alertID     int
+	accountID   int
+	deliveredAt *time.Time
+	droppedAt   *time.Time
+}
+
+// NewMemory returns an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		accounts:          map[int]Account{},
+		shareRequests:     map[int]ShareRequest{},
+		storages:          map[int]Folder{},
+		storageItems:      map[int]Item{},
+		shoppingLists:     map[int]ShoppingList{},
+		shoppingListItems: map[int]ShoppingListItem{},
+		titleUpdatedAts:   map[memoryRow]time.Time{},
+		lastIDs:           map[string]int{},
+	}
+}
+
+// AddFoods seeds the foods catalog
+func (m *Memory) AddFoods(names ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range names {
+		m.foods = append(m.foods, Foods{ID: fmt.Sprint(m.nextID("foods")), Name: name})
+	}
+}
+
+// WithTx runs fn on a copy of the store and keeps the copy's state only when fn returns nil
+// and ctx is still live.
+// Every other operation waits for fn to return.
+func (m *Memory) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := m.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.accounts = tx.accounts
+	m.foods = tx.foods
+	m.shareRequests = tx.shareRequests
+	m.storages = tx.storages
+	m.storageItems = tx.storageItems
+	m.storageItemEvents = tx.storageItemEvents
+	m.shoppingLists = tx.shoppingLists
+	m.shoppingListItems = tx.shoppingListItems
+	m.storageBinders = tx.storageBinders
+	m.shoppingListBinders = tx.shoppingListBinders
+	m.tombstones = tx.tombstones
+	m.pushedMutations = tx.pushedMutations
+	m.itemAlerts = tx.itemAlerts
+	m.alertDeliveries = tx.alertDeliveries
+	m.titleUpdatedAts = tx.titleUpdatedAts
+	m.purgedBefore = tx.purgedBefore
+	m.lastIDs = tx.lastIDs
+
+	return nil
+}
+
+// Health reports the store as always up, it has no connection pool
+func (m *Memory) Health(ctx context.Context) Health {
+	return Health{Up: true, CheckedAt: time.Now()}
+}
+
+// clone returns a deep copy of the store's state
+func (m *Memory) clone() *Memory {
+	c := NewMemory()
+
+	for id, acc := range m.accounts {
+		c.accounts[id] = acc
+	}
+	for id, sr := range m.shareRequests {
+		c.shareRequests[id] = sr
+	}
+	for id, folder := range m.storages {
+		c.storages[id] = folder
+	}
+	for id, item := range m.storageItems {
+		c.storageItems[id] = item
+	}
+	for id, sl := range m.shoppingLists {
+		c.shoppingLists[id] = sl
+	}
+	for id, item := range m.shoppingListItems {
+		c.shoppingListItems[id] = item
+	}
+	for row, at := range m.titleUpdatedAts {
+		c.titleUpdatedAts[row] = at
+	}
+	for table, id := range m.lastIDs {
+		c.lastIDs[table] = id
+	}
+	c.purgedBefore = m.purgedBefore
+	c.foods = append(c.foods, m.foods...)
+	c.storageItemEvents = append(c.storageItemEvents, m.storageItemEvents...)
+	c.storageBinders = append(c.storageBinders, m.storageBinders...)
+	c.shoppingListBinders = append(c.shoppingListBinders, m.shoppingListBinders...)
+	c.tombstones = append(c.tombstones, m.tombstones...)
+	c.pushedMutations = append(c.pushedMutations, m.pushedMutations...)
+	c.itemAlerts = append(c.itemAlerts, m.itemAlerts...)
+	c.alertDeliveries = append(c.alertDeliveries, m.alertDeliveries...)
+
+	return c
+}
+
+// nextID returns the next auto increment ID of table
+func (m *Memory) nextID(table string) int {
+	m.lastIDs[table]++
+	return m.lastIDs[table]
+}
+
+// retitle sets *current, the title of the row id of kind, to title and stamps its title update when it changes,
+// a change of case included
+func (m *Memory) retitle(kind string, id int, current *string, title string) {
+	if *current != title {
+		m.titleUpdatedAts[memoryRow{kind: kind, id: id}] = time.Now()
+	}
+	*current = title
+}
+
+// sameName compares usernames and emails case-insensitively, like the utf8mb4_general_ci collation
+func sameName(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+func (m *Memory) accountByUsername(username string) (Account, bool) {
+	for _, acc := range m.accounts {
+		if sameName(acc.Username, username) {
+			return acc, true
+		}
+	}
+	return Account{}, false
+}
+
+// renameAccount points what refers to the account username to newUsername, as the schema refers to accounts by ID
+func (m *Memory) renameAccount(username, newUsername string) {
+	for _, binders := range [][]binder{m.storageBinders, m.shoppingListBinders} {
+		for i := range binders {
+			if sameName(binders[i].username, username) {
+				binders[i].username = newUsername
+			}
+		}
+	}
+
+	for id, sr := range m.shareRequests {
+		if sameName(sr.FromUsername, username) {
+			sr.FromUsername = newUsername
+		}
+		if sameName(sr.ToUsername, username) {
+			sr.ToUsername = newUsername
+		}
+		m.shareRequests[id] = sr
+	}
+
+	for i := range m.tombstones {
+		if sameName(m.tombstones[i].username, username) {
+			m.tombstones[i].username = newUsername
+		}
+	}
+
+	for i := range m.pushedMutations {
+		if sameName(m.pushedMutations[i].username, username) {
+			m.pushedMutations[i].username = newUsername
+		}
+	}
+
+	for i := range m.storageItemEvents {
+		if sameName(m.storageItemEvents[i].Actor, username) {
+			m.storageItemEvents[i].Actor = newUsername
+		}
+	}
+}
+
+func (m *Memory) sortedAccounts() []Account {
+	ids := make([]int, 0, len(m.accounts))
+	for id := range m.accounts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	accounts := make([]Account, 0, len(ids))
+	for _, id := range ids {
+		accounts = append(accounts, m.accounts[id])
+	}
+	return accounts
+}
+
+func findBinder(binders []binder, username string, containerID int) int {
+	for i, b := range binders {
+		if sameName(b.username, username) && b.containerID == containerID {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeBinders(binders []binder, keep func(binder) bool) []binder {
+	kept := binders[:0]
+	for _, b := range binders {
+		if keep(b) {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+// CreateAccount creates a new account in memory
+func (m *Memory) CreateAccount(ctx context.Context, username, email, password, salt string) (sql.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, acc := range m.accounts {
+		switch {
+		case sameName(acc.Username, username):
+			return nil, errDuplicateKey("username", nil)
+		case sameName(acc.Email, email):
+			return nil, errDuplicateKey("email", nil)
+		}
+	}
+
+	now := time.Now()
+	id := m.nextID("accounts")
+	m.accounts[id] = Account{
+		ID:         id,
+		Username:   username,
+		Password:   password,
+		Salt:       salt,
+		Email:      email,
+		DarkTheme:  true,
+		UnitSystem: units.Metric,
+		LastLogin:  now,
+		UpdatedAt:  now,
+		CreatedAt:  now,
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+// GetAccount gets the account by username
+func (m *Memory) GetAccount(ctx context.Context, username string) (Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return acc, errNoRows
+	}
+
+	return acc, nil
+}
+
+// GetAccountEmail gets the account's email by username
+func (m *Memory) GetAccountEmail(ctx context.Context, username string) (Email, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return Email{}, errNoRows
+	}
+
+	return Email{Email: acc.Email}, nil
+}
+
+// EmailExists gets the account's username and email by email
+func (m *Memory) EmailExists(ctx context.Context, email string) (UsernameEmail, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, acc := range m.sortedAccounts() {
+		if sameName(acc.Email, email) {
+			return UsernameEmail{Username: acc.Username, Email: acc.Email}, nil
+		}
+	}
+
+	return UsernameEmail{}, errNoRows
+}
+
+// GetAccounts gets all accounts
+func (m *Memory) GetAccounts(ctx context.Context) ([]Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedAccounts(), nil
+}
+
+// CheckAccountCredentials gets the account by username or email
+func (m *Memory) CheckAccountCredentials(ctx context.Context, username, email string) (Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, acc := range m.sortedAccounts() {
+		if sameName(acc.Username, username) || sameName(acc.Email, email) {
+			return acc, nil
+		}
+	}
+
+	return Account{}, errNoRows
+}
+
+// updateAccount applies update to the account found by username.
+// A username change is carried to what refers to the account with renameAccount, as the schema refers to accounts by ID.
+func (m *Memory) updateAccount(username string, update func(*Account)) error {
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return errNoRowsAffected
+	}
+
+	updated := acc
+	update(&updated)
+
+	for id, other := range m.accounts {
+		if id == acc.ID {
+			continue
+		}
+		switch {
+		case sameName(other.Username, updated.Username):
+			return errDuplicateKey("username", nil)
+		case sameName(other.Email, updated.Email):
+			return errDuplicateKey("email", nil)
+		}
+	}
+
+	if updated.Username != acc.Username {
+		m.renameAccount(acc.Username, updated.Username)
+	}
+
+	updated.UpdatedAt = time.Now()
+	m.accounts[acc.ID] = updated
+
+	return nil
+}
+
+// UpdateAccount updates all account data fields by username
+func (m *Memory) UpdateAccount(ctx context.Context, username, newUsername, password, email string, darkTheme, notifications bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateAccount(username, func(acc *Account) {
+		acc.Username = newUsername
+		acc.Password = password
+		acc.Email = email
+		acc.DarkTheme = darkTheme
+		acc.Notifications = notifications
+	})
+}
+
+// UpdateAccountUsername updates the accounts username by current username
+func (m *Memory) UpdateAccountUsername(ctx context.Context, username, newUsername string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateAccount(username, func(acc *Account) {
+		acc.Username = newUsername
+	})
+}
+
+// UpdateAccountEmail updates the accounts email by username
+func (m *Memory) UpdateAccountEmail(ctx context.Context, username, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateAccount(username, func(acc *Account) {
+		acc.Email = email
+	})
+}
+
+// UpdateAccountPassword updates the accounts password by username
+func (m *Memory) UpdateAccountPassword(ctx context.Context, username, password, salt string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateAccount(username, func(acc *Account) {
+		acc.Password = password
+		acc.Salt = salt
+	})
+}
+
+// DeleteAccount deletes the account by username along with its share requests,
+// the storages and shopping lists it owns and its attachments to the ones shared with it
+func (m *Memory) DeleteAccount(ctx context.Context, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return errNoRowsAffected
+	}
+
+	m.recordAccountTombstones(username)
+
+	pushedMutations := []memoryPushedMutation{}
+	for _, pushed := range m.pushedMutations {
+		if !sameName(pushed.username, username) {
+			pushedMutations = append(pushedMutations, pushed)
+		}
+	}
+	m.pushedMutations = pushedMutations
+
+	for id, sr := range m.shareRequests {
+		if sameName(sr.FromUsername, username) || sameName(sr.ToUsername, username) {
+			delete(m.shareRequests, id)
+		}
+	}
+
+	for _, b := range m.storageBinders {
+		if !sameName(b.username, username) || !b.owner {
+			continue
+		}
+
+		for id, item := range m.storageItems {
+			if item.StorageID == b.containerID {
+				delete(m.storageItems, id)
+			}
+		}
+		delete(m.storages, b.containerID)
+	}
+	m.storageBinders = removeBinders(m.storageBinders, func(b binder) bool {
+		_, ok := m.storages[b.containerID]
+		return ok && !sameName(b.username, username)
+	})
+	m.removeItemEvents()
+	m.removeItemAlerts()
+
+	for _, b := range m.shoppingListBinders {
+		if !sameName(b.username, username) || !b.owner {
+			continue
+		}
+
+		for id, item := range m.shoppingListItems {
+			if item.ShoppingListID == b.containerID {
+				delete(m.shoppingListItems, id)
+			}
+		}
+		delete(m.shoppingLists, b.containerID)
+	}
+	m.shoppingListBinders = removeBinders(m.shoppingListBinders, func(b binder) bool {
+		_, ok := m.shoppingLists[b.containerID]
+		return ok && !sameName(b.username, username)
+	})
+
+	// The history of the account's items in other storages is left without an actor
+	for i := range m.storageItemEvents {
+		if sameName(m.storageItemEvents[i].Actor, username) {
+			m.storageItemEvents[i].Actor = ""
+		}
+	}
+
+	deliveries := []memoryAlertDelivery{}
+	for _, delivery := range m.alertDeliveries {
+		if delivery.accountID != acc.ID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	m.alertDeliveries = deliveries
+
+	delete(m.accounts, acc.ID)
+
+	return nil
+}
+
+// recordAccountTombstones leaves the other accounts tombstones of the storages and shopping lists username owns
+// and of the share requests it sent, and drops its own tombstones
+func (m *Memory) recordAccountTombstones(username string) {
+	for _, owned := range []struct {
+		kind    string
+		binders []binder
+	}{
+		{KindStorage, m.storageBinders},
+		{KindShoppingList, m.shoppingListBinders},
+	} {
+		for _, b := range owned.binders {
+			if !sameName(b.username, username) || !b.owner {
+				continue
+			}
+			for _, other := range owned.binders {
+				if other.containerID == b.containerID && !sameName(other.username, username) {
+					m.recordTombstone(other.username, owned.kind, b.containerID, TombstoneDeleted)
+				}
+			}
+		}
+	}
+
+	for id, sr := range m.shareRequests {
+		if sameName(sr.FromUsername, username) && !sameName(sr.ToUsername, username) {
+			m.recordTombstone(sr.ToUsername, KindShareRequest, id, TombstoneDeleted)
+		}
+	}
+
+	kept := []memoryTombstone{}
+	for _, tombstone := range m.tombstones {
+		if !sameName(tombstone.username, username) {
+			kept = append(kept, tombstone)
+		}
+	}
+	m.tombstones = kept
+}
+
+// GetNotificationSetting gets the account's notification setting preference by username
+func (m *Memory) GetNotificationSetting(ctx context.Context, username string) (Settings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return Settings{}, errNoRows
+	}
+
+	return Settings{Notifications: acc.Notifications}, nil
+}
+
+// ToggleNotificationSetting toggles the notification setting by username
+func (m *Memory) ToggleNotificationSetting(ctx context.Context, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateAccount(username, func(acc *Account) {
+		acc.Notifications = !acc.Notifications
+	})
+}
+
+// GetUnitSetting gets the account's unit system preference by username
+func (m *Memory) GetUnitSetting(ctx context.Context, username string) (UnitSettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return UnitSettings{}, errNoRows
+	}
+
+	return UnitSettings{UnitSystem: acc.UnitSystem}, nil
+}
+
+// UpdateUnitSetting sets the unit system preference by username, metric or imperial
+func (m *Memory) UpdateUnitSetting(ctx context.Context, username, unitSystem string) error {
+	if !units.KnownSystem(unitSystem) {
+		return errDataTruncated("unit_system", nil)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateAccount(username, func(acc *Account) {
+		acc.UnitSystem = unitSystem
+	})
+}
+
+// GetFoods gets a page of the food varieties
+func (m *Memory) GetFoods(ctx context.Context, options ListOptions) (FoodPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	page := FoodPage{Items: []Foods{}}
+
+	order, err := parseSort(options.Sort, foodSorts)
+	if err != nil {
+		return page, err
+	}
+
+	afterID, err := order.after(options.Cursor)
+	if err != nil {
+		return page, err
+	}
+
+	foods := map[int]Foods{}
+	rows := []sortRow{}
+	var after *sortRow
+	for _, food := range m.foods {
+		id, _ := strconv.Atoi(food.ID)
+		foods[id] = food
+
+		if id == afterID {
+			after = &sortRow{id: id, values: foodSortValues(food, order)}
+		}
+		if options.Q == "" || memoryContains(food.Name, options.Q) {
+			rows = append(rows, sortRow{id: id, values: foodSortValues(food, order)})
+		}
+	}
+	if afterID != 0 && after == nil {
+		after = &sortRow{id: afterID}
+	}
+
+	page.Total = len(rows)
+
+	ids, nextCursor := memoryPage(rows, order, after, options.Limit)
+	for _, id := range ids {
+		page.Items = append(page.Items, foods[id])
+	}
+	page.NextCursor = nextCursor
+
+	return page, nil
+}
+
+// foodSortValues are the values food is sorted by in order
+func foodSortValues(food Foods, order listOrder) []interface{} {
+	if order.keyName() == "name" {
+		return []interface{}{food.Name}
+	}
+	return []interface{}{}
+}
+
+// CreateShareRequest creates a share request
+func (m *Memory) CreateShareRequest(ctx context.Context, fromUsername, toUsername, shareType, title string, idRequest int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, ok := m.accountByUsername(fromUsername)
+	if !ok {
+		return errForeignKeyChild
+	}
+	to, ok := m.accountByUsername(toUsername)
+	if !ok {
+		return errForeignKeyChild
+	}
+	if shareType != "storage" && shareType != "shopping_list" {
+		return errDataTruncated("share_type", nil)
+	}
+
+	id := m.nextID("share_requests")
+	m.shareRequests[id] = ShareRequest{
+		ID:           id,
+		FromUsername: from.Username,
+		ToUsername:   to.Username,
+		ShareType:    shareType,
+		Title:        title,
+		IDRequest:    idRequest,
+		CreatedAt:    time.Now(),
+	}
+
+	return nil
+}
+
+// GetShareRequests gets all share requests sent to username
+func (m *Memory) GetShareRequests(ctx context.Context, username string) ([]ShareRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]int, 0, len(m.shareRequests))
+	for id := range m.shareRequests {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	shareRequests := []ShareRequest{}
+	for _, id := range ids {
+		if sr := m.shareRequests[id]; sameName(sr.ToUsername, username) {
+			shareRequests = append(shareRequests, sr)
+		}
+	}
+
+	return shareRequests, nil
+}
+
+// GetShareRequest gets a share request by ID
+func (m *Memory) GetShareRequest(ctx context.Context, shareID int) (ShareRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sr, ok := m.shareRequests[shareID]
+	if !ok {
+		return ShareRequest{}, errNoRows
+	}
+
+	return sr, nil
+}
+
+// DeleteShareRequest deletes a share request by ID and leaves its recipient a tombstone of it
+func (m *Memory) DeleteShareRequest(ctx context.Context, shareID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sr, ok := m.shareRequests[shareID]
+	if !ok {
+		return errNoRowsAffected
+	}
+
+	delete(m.shareRequests, shareID)
+	m.recordTombstone(sr.ToUsername, KindShareRequest, shareID, TombstoneDeleted)
+
+	return nil
+}
+
+// AcceptShareRequest attaches username to the requested storage or shopping list and deletes the request, all at once
+func (m *Memory) AcceptShareRequest(ctx context.Context, username string, shareID int) error {
+	return m.WithTx(ctx, func(tx Store) error {
+		return acceptShareRequest(ctx, tx, username, shareID)
+	})
+}
+
+// CreateStorage creates a storage and attaches the account to it by username
+func (m *Memory) CreateStorage(ctx context.Context, username, title string, owner bool) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return 0, errForeignKeyChild
+	}
+
+	now := time.Now()
+	id := m.nextID("storages")
+	m.storages[id] = Folder{
+		ID:        id,
+		Title:     title,
+		Version:   1,
+		UpdatedAt: now,
+		CreatedAt: now,
+	}
+	m.storageBinders = append(m.storageBinders, binder{username: acc.Username, containerID: id, owner: owner, createdAt: now})
+
+	return int64(id), nil
+}
+
+// GetStorages gets a page of the storages attached to username, with their item count
+func (m *Memory) GetStorages(ctx context.Context, username string, options ListOptions) (FolderPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	page := FolderPage{Items: []Folder{}}
+
+	order, err := parseSort(options.Sort, storageSorts)
+	if err != nil {
+		return page, err
+	}
+
+	afterID, err := order.after(options.Cursor)
+	if err != nil {
+		return page, err
+	}
+
+	counts := map[int]int{}
+	for _, item := range m.storageItems {
+		if item.DeletedAt == nil {
+			counts[item.StorageID]++
+		}
+	}
+
+	rows := []sortRow{}
+	for _, b := range m.storageBinders {
+		folder := m.storages[b.containerID]
+		if !sameName(b.username, username) || folder.DeletedAt != nil {
+			continue
+		}
+		if options.Q != "" && !memoryContains(folder.Title, options.Q) {
+			continue
+		}
+		rows = append(rows, sortRow{id: folder.ID, values: containerSortValues(folder.Title, folder.UpdatedAt, order)})
+	}
+
+	page.Total = len(rows)
+
+	var after *sortRow
+	if afterID != 0 {
+		after = &sortRow{id: afterID}
+		if folder, ok := m.storages[afterID]; ok {
+			after.values = containerSortValues(folder.Title, folder.UpdatedAt, order)
+		}
+	}
+
+	ids, nextCursor := memoryPage(rows, order, after, options.Limit)
+	for _, id := range ids {
+		folder := m.storages[id]
+		folder.Count = counts[id]
+		page.Items = append(page.Items, folder)
+	}
+	page.NextCursor = nextCursor
+
+	return page, nil
+}
+
+// containerSortValues are the values a storage or shopping list is sorted by in order
+func containerSortValues(title string, updatedAt time.Time, order listOrder) []interface{} {
+	switch order.keyName() {
+	case "title":
+		return []interface{}{title}
+	case "updated_at":
+		return []interface{}{updatedAt}
+	}
+	return []interface{}{}
+}
+
+// GetStorage gets a storage by ID, with its item count
+func (m *Memory) GetStorage(ctx context.Context, storageID int) (Folder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	folder, ok := m.storages[storageID]
+	if !ok || folder.DeletedAt != nil {
+		return Folder{}, errNoRows
+	}
+
+	for _, item := range m.storageItems {
+		if item.StorageID == storageID && item.DeletedAt == nil {
+			folder.Count++
+		}
+	}
+
+	return folder, nil
+}
+
+// GetStoragesCount gets the amount of storages attached to username
+func (m *Memory) GetStoragesCount(ctx context.Context, username string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, b := range m.storageBinders {
+		if sameName(b.username, username) && m.storages[b.containerID].DeletedAt == nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// UpdateStorage updates a storage's title by ID
+func (m *Memory) UpdateStorage(ctx context.Context, title string, storageID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	folder, ok := m.storages[storageID]
+	if !ok || folder.DeletedAt != nil {
+		return errNoRowsAffected
+	}
+	if err := checkVersion(folder.Version, version); err != nil {
+		return err
+	}
+
+	m.retitle(KindStorage, folder.ID, &folder.Title, title)
+	folder.Version++
+	folder.UpdatedAt = time.Now()
+	m.storages[storageID] = folder
+
+	return nil
+}
+
+// DeleteStorage moves a storage to the trash by ID, along with its items
+func (m *Memory) DeleteStorage(ctx context.Context, storageID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	folder, ok := m.storages[storageID]
+	if !ok || folder.DeletedAt != nil {
+		return errNoRowsAffected
+	}
+	if err := checkVersion(folder.Version, version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	itemIDs := []int{}
+	for id, item := range m.storageItems {
+		if item.StorageID == storageID && item.DeletedAt == nil {
+			item.DeletedAt = &now
+			item.UpdatedAt = now
+			m.storageItems[id] = item
+			itemIDs = append(itemIDs, id)
+		}
+	}
+	sort.Ints(itemIDs)
+	m.recordItemEvents(ctx, itemIDs, deletion(true))
+	folder.DeletedAt = &now
+	folder.UpdatedAt = now
+	folder.Version++
+	m.storages[storageID] = folder
+
+	return nil
+}
+
+// ShareStorage attaches a storage to an account by username and ID
+func (m *Memory) ShareStorage(ctx context.Context, username string, storageID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return errForeignKeyChild
+	}
+	if _, ok := m.storages[storageID]; !ok {
+		return errForeignKeyChild
+	}
+	if findBinder(m.storageBinders, username, storageID) >= 0 {
+		return errDuplicateKey("account_id_storage_id", nil)
+	}
+
+	m.storageBinders = append(m.storageBinders, binder{username: acc.Username, containerID: storageID, createdAt: time.Now()})
+
+	return nil
+}
+
+// RemoveShareStorage removes an accounts attachment to a storage by username and ID
+// and leaves the account a tombstone of it
+func (m *Memory) RemoveShareStorage(ctx context.Context, usernameRequest string, storageID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := findBinder(m.storageBinders, usernameRequest, storageID)
+	if i < 0 {
+		return errNoRowsAffected
+	}
+
+	m.storageBinders = append(m.storageBinders[:i], m.storageBinders[i+1:]...)
+	m.recordTombstone(usernameRequest, KindStorage, storageID, TombstoneAccessLost)
+
+	return nil
+}
+
+// GetStorageOwner returns whether the account owns the storage by username and ID
+func (m *Memory) GetStorageOwner(ctx context.Context, owner string, storageID int) (Share, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i := findBinder(m.storageBinders, owner, storageID)
+	if i < 0 {
+		return Share{}, errNoRows
+	}
+
+	share := Share{}
+	if m.storageBinders[i].owner {
+		share.Owner = 1
+	}
+
+	return share, nil
+}
+
+// memoryExpirationDate is the expiration date kept for expirationDate, in UTC as the SQL stores return it
+func memoryExpirationDate(expirationDate string) (string, error) {
+	date, err := expirationDateArg(expirationDate)
+	if date == nil {
+		return "", err
+	}
+	return date.(time.Time).Format(time.RFC3339Nano), nil
+}
+
+// CreateStorageItem creates a storage item, attaches it to storageID and returns its ID
+func (m *Memory) CreateStorageItem(ctx context.Context, storageID int, title string, quantity float64, quantityType string, quantityThreshold float64, expirationThreshold int, expirationDate string) (int64, error) {
+	if err := validateItemQuantities(quantity, quantityType, quantityThreshold); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if folder, ok := m.storages[storageID]; !ok || folder.DeletedAt != nil {
+		return 0, errForeignKeyChild
+	}
+	if !quantityTypes[quantityType] {
+		return 0, errDataTruncated("quantity_type", nil)
+	}
+
+	expirationDate, err := memoryExpirationDate(expirationDate)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	id := m.nextID("storage_items")
+	item := Item{
+		ID:                  id,
+		StorageID:           storageID,
+		Title:               title,
+		Quantity:            quantity,
+		QuantityType:        quantityType,
+		QuantityThreshold:   quantityThreshold,
+		ExpirationThreshold: expirationThreshold,
+		ExpirationDate:      expirationDate,
+		Version:             1,
+		UpdatedAt:           now,
+		CreatedAt:           now,
+	}
+	m.storageItems[id] = item
+	m.recordItemEvents(ctx, []int{id}, itemChanges(nil, &item))
+
+	return int64(id), nil
+}
+
+// GetStorageItems gets a page of the storage items of storageID
+func (m *Memory) GetStorageItems(ctx context.Context, storageID int, options ListOptions) (ItemPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	page := ItemPage{Items: []Item{}}
+
+	order, err := parseSort(options.Sort, storageItemSorts)
+	if err != nil {
+		return page, err
+	}
+
+	afterID, err := order.after(options.Cursor)
+	if err != nil {
+		return page, err
+	}
+
+	rows := []sortRow{}
+	for id, item := range m.storageItems {
+		if item.StorageID != storageID || item.DeletedAt != nil || !storageItemMatches(item, options) {
+			continue
+		}
+		rows = append(rows, sortRow{id: id, values: storageItemSortValues(item, order)})
+	}
+
+	page.Total = len(rows)
+
+	var after *sortRow
+	if afterID != 0 {
+		after = &sortRow{id: afterID}
+		if item, ok := m.storageItems[afterID]; ok {
+			after.values = storageItemSortValues(item, order)
+		}
+	}
+
+	ids, nextCursor := memoryPage(rows, order, after, options.Limit)
+	for _, id := range ids {
+		page.Items = append(page.Items, m.storageItems[id])
+	}
+	page.NextCursor = nextCursor
+
+	return page, nil
+}
+
+// storageItemMatches reports whether item passes the filters of options
+func storageItemMatches(item Item, options ListOptions) bool {
+	if options.ExpiringBefore != nil {
+		date, ok := parseExpirationDate(item.ExpirationDate)
+		if !ok || !date.Before(*options.ExpiringBefore) {
+			return false
+		}
+	}
+	if options.BelowThreshold && item.Quantity > item.QuantityThreshold {
+		return false
+	}
+	if options.QuantityType != "" && item.QuantityType != options.QuantityType {
+		return false
+	}
+	return options.Q == "" || memoryContains(item.Title, options.Q)
+}
+
+// storageItemSortValues are the values item is sorted by in order
+func storageItemSortValues(item Item, order listOrder) []interface{} {
+	switch order.keyName() {
+	case "title":
+		return []interface{}{item.Title}
+	case "expiration_date":
+		if date, ok := parseExpirationDate(item.ExpirationDate); ok {
+			return []interface{}{0, date}
+		}
+		return []interface{}{1, nil}
+	case "quantity":
+		return []interface{}{baseQuantity(item.Quantity, item.QuantityType)}
+	case "updated_at":
+		return []interface{}{item.UpdatedAt}
+	}
+	return []interface{}{}
+}
+
+// GetStorageItem gets a storage item by storageID and ID
+func (m *Memory) GetStorageItem(ctx context.Context, storageID, itemID int) (Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.storageItems[itemID]
+	if !ok || item.StorageID != storageID || item.DeletedAt != nil {
+		return Item{}, errNoRows
+	}
+
+	return item, nil
+}
+
+// GetStorageItemsCount gets the amount of storage items attached to username
+func (m *Memory) GetStorageItemsCount(ctx context.Context, username string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, item := range m.storageItems {
+		if item.DeletedAt == nil && findBinder(m.storageBinders, username, item.StorageID) >= 0 {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// GetStorageItemTotals sums the storage items attached to username by title and dimension, out of the trash
+func (m *Memory) GetStorageItemTotals(ctx context.Context, username string) ([]ItemTotal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items := []Item{}
+	for _, item := range m.storageItems {
+		if item.DeletedAt == nil && m.storages[item.StorageID].DeletedAt == nil && findBinder(m.storageBinders, username, item.StorageID) >= 0 {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	return itemTotals(items)
+}
+
+// updateStorageItem applies update to the storage item found by ID and records the changes
+func (m *Memory) updateStorageItem(ctx context.Context, itemID, version int, update func(*Item) bool) error {
+	item, ok := m.storageItems[itemID]
+	if !ok || item.DeletedAt != nil {
+		return errNoRowsAffected
+	}
+	if err := checkVersion(item.Version, version); err != nil {
+		return err
+	}
+	before := item
+	if !update(&item) {
+		return errNoRowsAffected
+	}
+	if !quantityTypes[item.QuantityType] {
+		return errDataTruncated("quantity_type", nil)
+	}
+
+	item.Version++
+	item.UpdatedAt = time.Now()
+	m.storageItems[itemID] = item
+	m.recordItemEvents(ctx, []int{itemID}, itemChanges(&before, &item))
+
+	// A new expiration date is alerted of anew
+	if item.ExpirationDate != before.ExpirationDate {
+		m.clearItemAlerts(itemID, AlertExpiring, AlertExpired)
+	}
+
+	m.checkStock(&before, &item)
+
+	return nil
+}
+
+// UpdateStorageItem updates a storage item by ID
+func (m *Memory) UpdateStorageItem(ctx context.Context, title, image string, quantity float64, quantityType string, quantityThreshold float64, expirationThreshold int, expirationDate string, itemID, version int) error {
+	if err := validateItemQuantities(quantity, quantityType, quantityThreshold); err != nil {
+		return err
+	}
+
+	expirationDate, err := memoryExpirationDate(expirationDate)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateStorageItem(ctx, itemID, version, func(item *Item) bool {
+		m.retitle(KindStorageItem, item.ID, &item.Title, title)
+		item.Image = image
+		item.Quantity = quantity
+		item.QuantityType = quantityType
+		item.QuantityThreshold = quantityThreshold
+		item.ExpirationThreshold = expirationThreshold
+		item.ExpirationDate = expirationDate
+		return true
+	})
+}
+
+// DecrementStorageItemQuantity decrements a storage item's quantity by by, stopping at 0, by ID
+func (m *Memory) DecrementStorageItemQuantity(ctx context.Context, by float64, itemID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := memoryStep(by, m.storageItems[itemID].QuantityType); err != nil {
+		return err
+	}
+
+	return m.updateStorageItem(ctx, itemID, version, func(item *Item) bool {
+		if item.Quantity <= 0 {
+			return false
+		}
+		item.Quantity = stepQuantity(item.Quantity, -by, item.QuantityType)
+		return true
+	})
+}
+
+// IncrementStorageItemQuantity increments a storage item's quantity by by, by ID
+func (m *Memory) IncrementStorageItemQuantity(ctx context.Context, by float64, itemID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := memoryStep(by, m.storageItems[itemID].QuantityType); err != nil {
+		return err
+	}
+
+	return m.updateStorageItem(ctx, itemID, version, func(item *Item) bool {
+		item.Quantity = stepQuantity(item.Quantity, by, item.QuantityType)
+		return true
+	})
+}
+
+// DeleteStorageItem moves a storage item to the trash by ID
+func (m *Memory) DeleteStorageItem(ctx context.Context, itemID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.storageItems[itemID]
+	if !ok || item.DeletedAt != nil {
+		return errNoRowsAffected
+	}
+	if err := checkVersion(item.Version, version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	item.DeletedAt = &now
+	item.UpdatedAt = now
+	item.Version++
+	m.storageItems[itemID] = item
+	m.recordItemEvents(ctx, []int{itemID}, deletion(true))
+
+	return nil
+}
+
+// CreateShoppingList creates a shopping list, attaches the account to it by username and returns its ID
+func (m *Memory) CreateShoppingList(ctx context.Context, username, title string, owner bool) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return 0, errForeignKeyChild
+	}
+
+	now := time.Now()
+	id := m.nextID("shopping_lists")
+	m.shoppingLists[id] = ShoppingList{
+		ID:        id,
+		Title:     title,
+		Version:   1,
+		UpdatedAt: now,
+		CreatedAt: now,
+	}
+	m.shoppingListBinders = append(m.shoppingListBinders, binder{username: acc.Username, containerID: id, owner: owner, createdAt: now})
+
+	return int64(id), nil
+}
+
+// GetShoppingLists gets a page of the shopping lists attached to username, with their item count
+func (m *Memory) GetShoppingLists(ctx context.Context, username string, options ListOptions) (ShoppingListPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	page := ShoppingListPage{Items: []ShoppingList{}}
+
+	order, err := parseSort(options.Sort, shoppingListSorts)
+	if err != nil {
+		return page, err
+	}
+
+	afterID, err := order.after(options.Cursor)
+	if err != nil {
+		return page, err
+	}
+
+	counts := map[int]int{}
+	for _, item := range m.shoppingListItems {
+		if item.DeletedAt == nil {
+			counts[item.ShoppingListID]++
+		}
+	}
+
+	rows := []sortRow{}
+	for _, b := range m.shoppingListBinders {
+		sl := m.shoppingLists[b.containerID]
+		if !sameName(b.username, username) || sl.DeletedAt != nil {
+			continue
+		}
+		if options.Q != "" && !memoryContains(sl.Title, options.Q) {
+			continue
+		}
+		rows = append(rows, sortRow{id: sl.ID, values: containerSortValues(sl.Title, sl.UpdatedAt, order)})
+	}
+
+	page.Total = len(rows)
+
+	var after *sortRow
+	if afterID != 0 {
+		after = &sortRow{id: afterID}
+		if sl, ok := m.shoppingLists[afterID]; ok {
+			after.values = containerSortValues(sl.Title, sl.UpdatedAt, order)
+		}
+	}
+
+	ids, nextCursor := memoryPage(rows, order, after, options.Limit)
+	for _, id := range ids {
+		sl := m.shoppingLists[id]
+		sl.Count = counts[id]
+		page.Items = append(page.Items, sl)
+	}
+	page.NextCursor = nextCursor
+
+	return page, nil
+}
+
+// GetShoppingList gets a shopping list by ID, with its item count
+func (m *Memory) GetShoppingList(ctx context.Context, shoppingListID int) (ShoppingList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sl, ok := m.shoppingLists[shoppingListID]
+	if !ok || sl.DeletedAt != nil {
+		return ShoppingList{}, errNoRows
+	}
+
+	for _, item := range m.shoppingListItems {
+		if item.ShoppingListID == shoppingListID && item.DeletedAt == nil {
+			sl.Count++
+		}
+	}
+
+	return sl, nil
+}
+
+// GetShoppingListsCount gets the amount of shopping lists attached to username
+func (m *Memory) GetShoppingListsCount(ctx context.Context, username string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, b := range m.shoppingListBinders {
+		if sameName(b.username, username) && m.shoppingLists[b.containerID].DeletedAt == nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// UpdateShoppingListTitle updates a shopping list's title by ID
+func (m *Memory) UpdateShoppingListTitle(ctx context.Context, title string, shoppingListID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sl, ok := m.shoppingLists[shoppingListID]
+	if !ok || sl.DeletedAt != nil {
+		return errNoRowsAffected
+	}
+	if err := checkVersion(sl.Version, version); err != nil {
+		return err
+	}
+
+	m.retitle(KindShoppingList, sl.ID, &sl.Title, title)
+	sl.Version++
+	sl.UpdatedAt = time.Now()
+	m.shoppingLists[shoppingListID] = sl
+
+	return nil
+}
+
+// DeleteShoppingList moves a shopping list to the trash by ID, along with its items
+func (m *Memory) DeleteShoppingList(ctx context.Context, shoppingListID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sl, ok := m.shoppingLists[shoppingListID]
+	if !ok || sl.DeletedAt != nil {
+		return errNoRowsAffected
+	}
+	if err := checkVersion(sl.Version, version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for id, item := range m.shoppingListItems {
+		if item.ShoppingListID == shoppingListID && item.DeletedAt == nil {
+			item.DeletedAt = &now
+			item.UpdatedAt = now
+			m.shoppingListItems[id] = item
+		}
+	}
+	sl.DeletedAt = &now
+	sl.UpdatedAt = now
+	sl.Version++
+	m.shoppingLists[shoppingListID] = sl
+
+	return nil
+}
+
+// ShareShoppingList attaches a shopping list to an account by username and ID
+func (m *Memory) ShareShoppingList(ctx context.Context, username string, shoppingListID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return errForeignKeyChild
+	}
+	if _, ok := m.shoppingLists[shoppingListID]; !ok {
+		return errForeignKeyChild
+	}
+	if findBinder(m.shoppingListBinders, username, shoppingListID) >= 0 {
+		return errDuplicateKey("account_id_shopping_list_id", nil)
+	}
+
+	m.shoppingListBinders = append(m.shoppingListBinders, binder{username: acc.Username, containerID: shoppingListID, createdAt: time.Now()})
+
+	return nil
+}
+
+// RemoveShareShoppingList removes an accounts attachment to a shopping list by username and ID
+// and leaves the account a tombstone of it
+func (m *Memory) RemoveShareShoppingList(ctx context.Context, username string, shoppingListID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := findBinder(m.shoppingListBinders, username, shoppingListID)
+	if i < 0 {
+		return errNoRowsAffected
+	}
+
+	m.shoppingListBinders = append(m.shoppingListBinders[:i], m.shoppingListBinders[i+1:]...)
+	m.recordTombstone(username, KindShoppingList, shoppingListID, TombstoneAccessLost)
+
+	return nil
+}
+
+// GetShoppingListOwner returns whether the account owns the shopping list by username and ID
+func (m *Memory) GetShoppingListOwner(ctx context.Context, owner string, shoppingListID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i := findBinder(m.shoppingListBinders, owner, shoppingListID)
+	if i < 0 {
+		return false, errNoRows
+	}
+
+	return m.shoppingListBinders[i].owner, nil
+}
+
+// CreateShoppingListItem creates a shopping list item attached to shoppingListID and returns its ID
+func (m *Memory) CreateShoppingListItem(ctx context.Context, shoppingListID int, title string, quantity float64, quantityType string) (int64, error) {
+	if err := ValidateQuantity("quantity", quantity, quantityType); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sl, ok := m.shoppingLists[shoppingListID]; !ok || sl.DeletedAt != nil {
+		return 0, errForeignKeyChild
+	}
+	if !quantityTypes[quantityType] {
+		return 0, errDataTruncated("quantity_type", nil)
+	}
+
+	now := time.Now()
+	id := m.nextID("shopping_list_items")
+	m.shoppingListItems[id] = ShoppingListItem{
+		ID:             id,
+		ShoppingListID: shoppingListID,
+		Title:          title,
+		Quantity:       quantity,
+		QuantityType:   quantityType,
+		Version:        1,
+		UpdatedAt:      now,
+		CreatedAt:      now,
+	}
+
+	return int64(id), nil
+}
+
+// GetShoppingListItems gets a page of the shopping list items of shoppingListID
+func (m *Memory) GetShoppingListItems(ctx context.Context, shoppingListID int, options ListOptions) (ShoppingListItemPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	page := ShoppingListItemPage{Items: []ShoppingListItem{}}
+
+	order, err := parseSort(options.Sort, shoppingListItemSorts)
+	if err != nil {
+		return page, err
+	}
+
+	afterID, err := order.after(options.Cursor)
+	if err != nil {
+		return page, err
+	}
+
+	rows := []sortRow{}
+	for id, item := range m.shoppingListItems {
+		if item.ShoppingListID != shoppingListID || item.DeletedAt != nil {
+			continue
+		}
+		if options.QuantityType != "" && item.QuantityType != options.QuantityType {
+			continue
+		}
+		if options.Q != "" && !memoryContains(item.Title, options.Q) {
+			continue
+		}
+		rows = append(rows, sortRow{id: id, values: shoppingListItemSortValues(item, order)})
+	}
+
+	page.Total = len(rows)
+
+	var after *sortRow
+	if afterID != 0 {
+		after = &sortRow{id: afterID}
+		if item, ok := m.shoppingListItems[afterID]; ok {
+			after.values = shoppingListItemSortValues(item, order)
+		}
+	}
+
+	ids, nextCursor := memoryPage(rows, order, after, options.Limit)
+	for _, id := range ids {
+		page.Items = append(page.Items, m.shoppingListItems[id])
+	}
+	page.NextCursor = nextCursor
+
+	return page, nil
+}
+
+// shoppingListItemSortValues are the values item is sorted by in order
+func shoppingListItemSortValues(item ShoppingListItem, order listOrder) []interface{} {
+	switch order.keyName() {
+	case "title":
+		return []interface{}{item.Title}
+	case "quantity":
+		return []interface{}{baseQuantity(item.Quantity, item.QuantityType)}
+	case "updated_at":
+		return []interface{}{item.UpdatedAt}
+	}
+	return []interface{}{}
+}
+
+// GetShoppingListItem gets a single shopping list item by ID
+func (m *Memory) GetShoppingListItem(ctx context.Context, itemID int) (ShoppingListItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.shoppingListItems[itemID]
+	if !ok || item.DeletedAt != nil {
+		return ShoppingListItem{}, errNoRows
+	}
+
+	return item, nil
+}
+
+// GetShoppingListItemsCount gets the amount of shopping list items attached to username
+func (m *Memory) GetShoppingListItemsCount(ctx context.Context, username string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, item := range m.shoppingListItems {
+		if item.DeletedAt == nil && findBinder(m.shoppingListBinders, username, item.ShoppingListID) >= 0 {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// updateShoppingListItem applies update to the shopping list item found by ID
+func (m *Memory) updateShoppingListItem(itemID, version int, update func(*ShoppingListItem) bool) error {
+	item, ok := m.shoppingListItems[itemID]
+	if !ok || item.DeletedAt != nil {
+		return errNoRowsAffected
+	}
+	if err := checkVersion(item.Version, version); err != nil {
+		return err
+	}
+	if !update(&item) {
+		return errNoRowsAffected
+	}
+	if !quantityTypes[item.QuantityType] {
+		return errDataTruncated("quantity_type", nil)
+	}
+
+	item.Version++
+	item.UpdatedAt = time.Now()
+	m.shoppingListItems[itemID] = item
+
+	return nil
+}
+
+// UpdateShoppingListItem updates a shopping list item by ID
+func (m *Memory) UpdateShoppingListItem(ctx context.Context, title string, quantity float64, quantityType string, itemID, version int) error {
+	if err := ValidateQuantity("quantity", quantity, quantityType); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateShoppingListItem(itemID, version, func(item *ShoppingListItem) bool {
+		m.retitle(KindShoppingListItem, item.ID, &item.Title, title)
+		item.Quantity = quantity
+		item.QuantityType = quantityType
+		return true
+	})
+}
+
+// UpdateShoppingListItemTitle updates a shopping list item's title by ID
+func (m *Memory) UpdateShoppingListItemTitle(ctx context.Context, title string, itemID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateShoppingListItem(itemID, version, func(item *ShoppingListItem) bool {
+		m.retitle(KindShoppingListItem, item.ID, &item.Title, title)
+		return true
+	})
+}
+
+// DecrementShoppingListItemQuantity decrements a shopping list item's quantity by by, stopping at 0, by ID
+func (m *Memory) DecrementShoppingListItemQuantity(ctx context.Context, by float64, itemID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := memoryStep(by, m.shoppingListItems[itemID].QuantityType); err != nil {
+		return err
+	}
+
+	return m.updateShoppingListItem(itemID, version, func(item *ShoppingListItem) bool {
+		if item.Quantity <= 0 {
+			return false
+		}
+		item.Quantity = stepQuantity(item.Quantity, -by, item.QuantityType)
+		return true
+	})
+}
+
+// IncrementShoppingListItemQuantity increments a shopping list item's quantity by by, by ID
+func (m *Memory) IncrementShoppingListItemQuantity(ctx context.Context, by float64, itemID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := memoryStep(by, m.shoppingListItems[itemID].QuantityType); err != nil {
+		return err
+	}
+
+	return m.updateShoppingListItem(itemID, version, func(item *ShoppingListItem) bool {
+		item.Quantity = stepQuantity(item.Quantity, by, item.QuantityType)
+		return true
+	})
+}
+
+// DeleteShoppingListItem moves a shopping list item to the trash by ID
+func (m *Memory) DeleteShoppingListItem(ctx context.Context, itemID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.shoppingListItems[itemID]
+	if !ok || item.DeletedAt != nil {
+		return errNoRowsAffected
+	}
+	if err := checkVersion(item.Version, version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	item.DeletedAt = &now
+	item.UpdatedAt = now
+	item.Version++
+	m.shoppingListItems[itemID] = item
+
+	return nil
+}
+
+// GetTrash gets the trashed storages, shopping lists and items attached to username, latest first
+func (m *Memory) GetTrash(ctx context.Context, username string) (Trash, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	trash := Trash{
+		Storages:          []Folder{},
+		StorageItems:      []Item{},
+		ShoppingLists:     []ShoppingList{},
+		ShoppingListItems: []ShoppingListItem{},
+	}
+
+	for _, b := range m.storageBinders {
+		folder := m.storages[b.containerID]
+		if !sameName(b.username, username) || folder.DeletedAt == nil {
+			continue
+		}
+		for _, item := range m.storageItems {
+			if item.StorageID == folder.ID && item.DeletedAt != nil && item.DeletedAt.Equal(*folder.DeletedAt) {
+				folder.Count++
+			}
+		}
+		trash.Storages = append(trash.Storages, folder)
+	}
+	for _, item := range m.storageItems {
+		if item.DeletedAt != nil && m.storages[item.StorageID].DeletedAt == nil && findBinder(m.storageBinders, username, item.StorageID) >= 0 {
+			trash.StorageItems = append(trash.StorageItems, item)
+		}
+	}
+	for _, b := range m.shoppingListBinders {
+		sl := m.shoppingLists[b.containerID]
+		if !sameName(b.username, username) || sl.DeletedAt == nil {
+			continue
+		}
+		for _, item := range m.shoppingListItems {
+			if item.ShoppingListID == sl.ID && item.DeletedAt != nil && item.DeletedAt.Equal(*sl.DeletedAt) {
+				sl.Count++
+			}
+		}
+		trash.ShoppingLists = append(trash.ShoppingLists, sl)
+	}
+	for _, item := range m.shoppingListItems {
+		if item.DeletedAt != nil && m.shoppingLists[item.ShoppingListID].DeletedAt == nil && findBinder(m.shoppingListBinders, username, item.ShoppingListID) >= 0 {
+			trash.ShoppingListItems = append(trash.ShoppingListItems, item)
+		}
+	}
+
+	latestFirst := func(a, b *time.Time, idA, idB int) bool {
+		if !a.Equal(*b) {
+			return a.After(*b)
+		}
+		return idA < idB
+	}
+	sort.Slice(trash.Storages, func(i, j int) bool {
+		return latestFirst(trash.Storages[i].DeletedAt, trash.Storages[j].DeletedAt, trash.Storages[i].ID, trash.Storages[j].ID)
+	})
+	sort.Slice(trash.StorageItems, func(i, j int) bool {
+		return latestFirst(trash.StorageItems[i].DeletedAt, trash.StorageItems[j].DeletedAt, trash.StorageItems[i].ID, trash.StorageItems[j].ID)
+	})
+	sort.Slice(trash.ShoppingLists, func(i, j int) bool {
+		return latestFirst(trash.ShoppingLists[i].DeletedAt, trash.ShoppingLists[j].DeletedAt, trash.ShoppingLists[i].ID, trash.ShoppingLists[j].ID)
+	})
+	sort.Slice(trash.ShoppingListItems, func(i, j int) bool {
+		return latestFirst(trash.ShoppingListItems[i].DeletedAt, trash.ShoppingListItems[j].DeletedAt, trash.ShoppingListItems[i].ID, trash.ShoppingListItems[j].ID)
+	})
+
+	return trash, nil
+}
+
+// RestoreStorage brings a storage back from the trash by ID, along with the items trashed with it
+func (m *Memory) RestoreStorage(ctx context.Context, storageID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	folder, ok := m.storages[storageID]
+	if !ok || folder.DeletedAt == nil {
+		return errNoRowsAffected
+	}
+
+	itemIDs := []int{}
+	for id, item := range m.storageItems {
+		if item.StorageID == storageID && item.DeletedAt != nil && item.DeletedAt.Equal(*folder.DeletedAt) {
+			item.DeletedAt = nil
+			item.UpdatedAt = time.Now()
+			m.storageItems[id] = item
+			itemIDs = append(itemIDs, id)
+		}
+	}
+	sort.Ints(itemIDs)
+	m.recordItemEvents(ctx, itemIDs, deletion(false))
+	folder.DeletedAt = nil
+	folder.UpdatedAt = time.Now()
+	folder.Version++
+	m.storages[storageID] = folder
+
+	return nil
+}
+
+// RestoreStorageItem brings a storage item back from the trash by ID, unless its storage is in the trash
+func (m *Memory) RestoreStorageItem(ctx context.Context, itemID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.storageItems[itemID]
+	if !ok || item.DeletedAt == nil || m.storages[item.StorageID].DeletedAt != nil {
+		return errNoRowsAffected
+	}
+
+	item.DeletedAt = nil
+	item.UpdatedAt = time.Now()
+	item.Version++
+	m.storageItems[itemID] = item
+	m.recordItemEvents(ctx, []int{itemID}, deletion(false))
+
+	return nil
+}
+
+// RestoreShoppingList brings a shopping list back from the trash by ID, along with the items trashed with it
+func (m *Memory) RestoreShoppingList(ctx context.Context, shoppingListID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sl, ok := m.shoppingLists[shoppingListID]
+	if !ok || sl.DeletedAt == nil {
+		return errNoRowsAffected
+	}
+
+	for id, item := range m.shoppingListItems {
+		if item.ShoppingListID == shoppingListID && item.DeletedAt != nil && item.DeletedAt.Equal(*sl.DeletedAt) {
+			item.DeletedAt = nil
+			item.UpdatedAt = time.Now()
+			m.shoppingListItems[id] = item
+		}
+	}
+	sl.DeletedAt = nil
+	sl.UpdatedAt = time.Now()
+	sl.Version++
+	m.shoppingLists[shoppingListID] = sl
+
+	return nil
+}
+
+// RestoreShoppingListItem brings a shopping list item back from the trash by ID, unless its shopping list is in the trash
+func (m *Memory) RestoreShoppingListItem(ctx context.Context, itemID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.shoppingListItems[itemID]
+	if !ok || item.DeletedAt == nil || m.shoppingLists[item.ShoppingListID].DeletedAt != nil {
+		return errNoRowsAffected
+	}
+
+	item.DeletedAt = nil
+	item.UpdatedAt = time.Now()
+	item.Version++
+	m.shoppingListItems[itemID] = item
+
+	return nil
+}
+
+// PurgeTrash deletes for good what was trashed before before, along with the attachments and the tombstones
+// as old, and returns the number of trashed rows deleted
+func (m *Memory) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := int64(0)
+	expired := func(deletedAt *time.Time) bool {
+		return deletedAt != nil && deletedAt.Before(before)
+	}
+
+	for id, item := range m.storageItems {
+		if expired(item.DeletedAt) {
+			delete(m.storageItems, id)
+			purged++
+		}
+	}
+	for id, folder := range m.storages {
+		if expired(folder.DeletedAt) {
+			m.storageBinders = removeBinders(m.storageBinders, func(b binder) bool {
+				return b.containerID != id
+			})
+			delete(m.storages, id)
+			purged++
+		}
+	}
+	m.removeItemEvents()
+	m.removeItemAlerts()
+	for id, item := range m.shoppingListItems {
+		if expired(item.DeletedAt) {
+			delete(m.shoppingListItems, id)
+			purged++
+		}
+	}
+	for id, sl := range m.shoppingLists {
+		if expired(sl.DeletedAt) {
+			m.shoppingListBinders = removeBinders(m.shoppingListBinders, func(b binder) bool {
+				return b.containerID != id
+			})
+			delete(m.shoppingLists, id)
+			purged++
+		}
+	}
+
+	kept := []memoryTombstone{}
+	for _, tombstone := range m.tombstones {
+		if !tombstone.DeletedAt.Before(before) {
+			kept = append(kept, tombstone)
+		}
+	}
+	m.tombstones = kept
+
+	pushedMutations := []memoryPushedMutation{}
+	for _, pushed := range m.pushedMutations {
+		if !pushed.createdAt.Before(before) {
+			pushedMutations = append(pushedMutations, pushed)
+		}
+	}
+	m.pushedMutations = pushedMutations
+
+	if before.After(m.purgedBefore) {
+		m.purgedBefore = before
+	}
+
+	return purged, nil
+}
+
+// recordItemEvents records changes for each of the storage items itemIDs as made by the actor of ctx,
+// by nobody when no account has its username
+func (m *Memory) recordItemEvents(ctx context.Context, itemIDs []int, changes []itemChange) {
+	now := time.Now()
+
+	actor := ""
+	if acc, ok := m.accountByUsername(actorOf(ctx)); ok {
+		actor = acc.Username
+	}
+
+	for _, itemID := range itemIDs {
+		for _, change := range changes {
+			m.storageItemEvents = append(m.storageItemEvents, ItemEvent{
+				ID:        m.nextID("storage_item_events"),
+				ItemID:    itemID,
+				Actor:     actor,
+				Field:     change.field,
+				OldValue:  change.oldValue,
+				NewValue:  change.newValue,
+				CreatedAt: now,
+			})
+		}
+	}
+}
+
+func (m *Memory) replaceItemEvents(ctx context.Context, itemID int, events []ItemEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := []ItemEvent{}
+	for _, event := range m.storageItemEvents {
+		if event.ItemID != itemID {
+			kept = append(kept, event)
+		}
+	}
+	m.storageItemEvents = kept
+
+	for _, event := range events {
+		actor := ""
+		if acc, ok := m.accountByUsername(event.Actor); ok {
+			actor = acc.Username
+		}
+
+		m.storageItemEvents = append(m.storageItemEvents, ItemEvent{
+			ID:        m.nextID("storage_item_events"),
+			ItemID:    itemID,
+			Actor:     actor,
+			Field:     event.Field,
+			OldValue:  event.OldValue,
+			NewValue:  event.NewValue,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return nil
+}
+
+// removeItemEvents drops the events of the storage items which no longer exist, like the schema's cascade
+func (m *Memory) removeItemEvents() {
+	events := []ItemEvent{}
+	for _, event := range m.storageItemEvents {
+		if _, ok := m.storageItems[event.ItemID]; ok {
+			events = append(events, event)
+		}
+	}
+	m.storageItemEvents = events
+}
+
+// GetStorageItemHistory gets a page of the events of a storage item, trashed or not, by storageID and ID
+func (m *Memory) GetStorageItemHistory(ctx context.Context, storageID, itemID, cursor, limit int) (ItemHistory, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := ItemHistory{Events: []ItemEvent{}}
+	limit = pageLimit(limit)
+
+	if item, ok := m.storageItems[itemID]; !ok || item.StorageID != storageID {
+		return history, errNoRows
+	}
+
+	// The events are appended in ID order
+	for i := len(m.storageItemEvents) - 1; i >= 0; i-- {
+		event := m.storageItemEvents[i]
+		if event.ItemID != itemID || (cursor != 0 && event.ID >= cursor) {
+			continue
+		}
+		if len(history.Events) == limit {
+			history.NextCursor = history.Events[limit-1].ID
+			break
+		}
+		history.Events = append(history.Events, event)
+	}
+
+	return history, nil
+}
+
+// Search finds the storages, storage items and shopping list items attached to username whose title
+// matches q, tolerating typos, and returns the limit best hits
+func (m *Memory) Search(ctx context.Context, username, q string, limit int) (SearchResults, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := []SearchHit{}
+
+	for _, b := range m.storageBinders {
+		if folder := m.storages[b.containerID]; sameName(b.username, username) && folder.DeletedAt == nil {
+			candidates = append(candidates, SearchHit{Kind: KindStorage, ID: folder.ID, Title: folder.Title})
+		}
+	}
+
+	for _, item := range m.storageItems {
+		folder := m.storages[item.StorageID]
+		if item.DeletedAt != nil || folder.DeletedAt != nil || findBinder(m.storageBinders, username, item.StorageID) < 0 {
+			continue
+		}
+		candidates = append(candidates, SearchHit{
+			Kind:   KindStorageItem,
+			ID:     item.ID,
+			Title:  item.Title,
+			Parent: &SearchParent{Kind: KindStorage, ID: folder.ID, Title: folder.Title},
+		})
+	}
+
+	for _, item := range m.shoppingListItems {
+		sl := m.shoppingLists[item.ShoppingListID]
+		if item.DeletedAt != nil || sl.DeletedAt != nil || findBinder(m.shoppingListBinders, username, item.ShoppingListID) < 0 {
+			continue
+		}
+		candidates = append(candidates, SearchHit{
+			Kind:   KindShoppingListItem,
+			ID:     item.ID,
+			Title:  item.Title,
+			Parent: &SearchParent{Kind: KindShoppingList, ID: sl.ID, Title: sl.Title},
+		})
+	}
+
+	return SearchResults{Hits: rankHits(candidates, q, limit)}, nil
+}
+
+// GetStorageCollaborators gets the usernames of the accounts attached to a storage by ID
+func (m *Memory) GetStorageCollaborators(ctx context.Context, storageID int) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return binderUsernames(m.storageBinders, storageID), nil
+}
+
+// GetStorageItemCollaborators gets the usernames of the accounts attached to the storage of an item by ID, trashed or not
+func (m *Memory) GetStorageItemCollaborators(ctx context.Context, itemID int) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.storageItems[itemID]
+	if !ok {
+		return []string{}, nil
+	}
+
+	return binderUsernames(m.storageBinders, item.StorageID), nil
+}
+
+// GetShoppingListCollaborators gets the usernames of the accounts attached to a shopping list by ID
+func (m *Memory) GetShoppingListCollaborators(ctx context.Context, shoppingListID int) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return binderUsernames(m.shoppingListBinders, shoppingListID), nil
+}
+
+// GetShoppingListItemCollaborators gets the usernames of the accounts attached to the shopping list of an item by ID, trashed or not
+func (m *Memory) GetShoppingListItemCollaborators(ctx context.Context, itemID int) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.shoppingListItems[itemID]
+	if !ok {
+		return []string{}, nil
+	}
+
+	return binderUsernames(m.shoppingListBinders, item.ShoppingListID), nil
+}
+
+// binderUsernames lists the usernames attached to containerID, sorted
+func binderUsernames(binders []binder, containerID int) []string {
+	usernames := []string{}
+	for _, b := range binders {
+		if b.containerID == containerID {
+			usernames = append(usernames, b.username)
+		}
+	}
+	sort.Strings(usernames)
+
+	return usernames
+}
+
+// recordTombstone leaves username a tombstone of the row id of kind, gone for reason, unless no account has it
+func (m *Memory) recordTombstone(username, kind string, id int, reason string) {
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return
+	}
+
+	m.tombstones = append(m.tombstones, memoryTombstone{
+		username:  acc.Username,
+		Tombstone: Tombstone{Kind: kind, ID: id, Reason: reason, DeletedAt: time.Now()},
+	})
+}
+
+// GetChanges gets what changed for username since cursor, everything it can see when cursor is empty
+func (m *Memory) GetChanges(ctx context.Context, username, cursor string) (Changes, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	changes := newChanges(time.Now())
+
+	since, err := syncSince(cursor)
+	if err != nil {
+		return changes, err
+	}
+	if cursor != "" && since.Before(m.purgedBefore) {
+		return changes, errCursorExpired
+	}
+	changed := func(updatedAt time.Time) bool {
+		return !updatedAt.Before(since)
+	}
+	trashed := func(deletedAt *time.Time) bool {
+		return !since.IsZero() && deletedAt != nil && changed(*deletedAt)
+	}
+
+	// attached holds when username was attached to each of its storages and shopping lists
+	attached := func(binders []binder) map[int]time.Time {
+		times := map[int]time.Time{}
+		for _, b := range binders {
+			if sameName(b.username, username) {
+				times[b.containerID] = b.createdAt
+			}
+		}
+		return times
+	}
+	storages, shoppingLists := attached(m.storageBinders), attached(m.shoppingListBinders)
+
+	counts := map[int]int{}
+	for _, item := range m.storageItems {
+		if item.DeletedAt == nil {
+			counts[item.StorageID]++
+		}
+	}
+	for id, attachedAt := range storages {
+		folder := m.storages[id]
+		switch {
+		case trashed(folder.DeletedAt):
+			changes.Tombstones = append(changes.Tombstones, Tombstone{Kind: KindStorage, ID: id, Reason: TombstoneDeleted, DeletedAt: *folder.DeletedAt})
+		case folder.DeletedAt == nil && (changed(folder.UpdatedAt) || changed(attachedAt)):
+			folder.Count = counts[id]
+			changes.Storages = append(changes.Storages, folder)
+		}
+	}
+	for _, item := range m.storageItems {
+		attachedAt, ok := storages[item.StorageID]
+		switch {
+		case !ok:
+		case trashed(item.DeletedAt):
+			changes.Tombstones = append(changes.Tombstones, Tombstone{Kind: KindStorageItem, ID: item.ID, Reason: TombstoneDeleted, DeletedAt: *item.DeletedAt})
+		case item.DeletedAt == nil && m.storages[item.StorageID].DeletedAt == nil && (changed(item.UpdatedAt) || changed(attachedAt)):
+			changes.StorageItems = append(changes.StorageItems, item)
+		}
+	}
+
+	counts = map[int]int{}
+	for _, item := range m.shoppingListItems {
+		if item.DeletedAt == nil {
+			counts[item.ShoppingListID]++
+		}
+	}
+	for id, attachedAt := range shoppingLists {
+		sl := m.shoppingLists[id]
+		switch {
+		case trashed(sl.DeletedAt):
+			changes.Tombstones = append(changes.Tombstones, Tombstone{Kind: KindShoppingList, ID: id, Reason: TombstoneDeleted, DeletedAt: *sl.DeletedAt})
+		case sl.DeletedAt == nil && (changed(sl.UpdatedAt) || changed(attachedAt)):
+			sl.Count = counts[id]
+			changes.ShoppingLists = append(changes.ShoppingLists, sl)
+		}
+	}
+	for _, item := range m.shoppingListItems {
+		attachedAt, ok := shoppingLists[item.ShoppingListID]
+		switch {
+		case !ok:
+		case trashed(item.DeletedAt):
+			changes.Tombstones = append(changes.Tombstones, Tombstone{Kind: KindShoppingListItem, ID: item.ID, Reason: TombstoneDeleted, DeletedAt: *item.DeletedAt})
+		case item.DeletedAt == nil && m.shoppingLists[item.ShoppingListID].DeletedAt == nil && (changed(item.UpdatedAt) || changed(attachedAt)):
+			changes.ShoppingListItems = append(changes.ShoppingListItems, item)
+		}
+	}
+
+	for _, sr := range m.shareRequests {
+		if sameName(sr.ToUsername, username) && changed(sr.CreatedAt) {
+			changes.ShareRequests = append(changes.ShareRequests, sr)
+		}
+	}
+
+	if !since.IsZero() {
+		for _, tombstone := range m.tombstones {
+			if sameName(tombstone.username, username) && changed(tombstone.DeletedAt) {
+				changes.Tombstones = append(changes.Tombstones, tombstone.Tombstone)
+			}
+		}
+	}
+
+	sort.Slice(changes.Storages, func(i, j int) bool { return changes.Storages[i].ID < changes.Storages[j].ID })
+	sort.Slice(changes.StorageItems, func(i, j int) bool { return changes.StorageItems[i].ID < changes.StorageItems[j].ID })
+	sort.Slice(changes.ShoppingLists, func(i, j int) bool { return changes.ShoppingLists[i].ID < changes.ShoppingLists[j].ID })
+	sort.Slice(changes.ShoppingListItems, func(i, j int) bool {
+		return changes.ShoppingListItems[i].ID < changes.ShoppingListItems[j].ID
+	})
+	sort.Slice(changes.ShareRequests, func(i, j int) bool { return changes.ShareRequests[i].ID < changes.ShareRequests[j].ID })
+	changes.settle()
+
+	return changes, nil
+}
+
+// PushChanges applies the mutations pushed by a client of username in one transaction
+func (m *Memory) PushChanges(ctx context.Context, username string, mutations []Mutation) (PushResult, error) {
+	result := PushResult{}
+
+	err := m.WithTx(ctx, func(tx Store) error {
+		var err error
+		result, err = pushChanges(ctx, tx, tx.(*Memory), username, mutations)
+		return err
+	})
+
+	return result, err
+}
+
+func (m *Memory) titleUpdatedAt(ctx context.Context, kind string, id int) (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var createdAt time.Time
+	var ok bool
+
+	switch kind {
+	case KindStorage:
+		var folder Folder
+		folder, ok = m.storages[id]
+		createdAt = folder.CreatedAt
+	case KindStorageItem:
+		var item Item
+		item, ok = m.storageItems[id]
+		createdAt = item.CreatedAt
+	case KindShoppingList:
+		var sl ShoppingList
+		sl, ok = m.shoppingLists[id]
+		createdAt = sl.CreatedAt
+	case KindShoppingListItem:
+		var item ShoppingListItem
+		item, ok = m.shoppingListItems[id]
+		createdAt = item.CreatedAt
+	}
+	if !ok {
+		return time.Time{}, errNoRows
+	}
+
+	// Rows whose title never changed take the time they were created
+	if titleUpdatedAt, ok := m.titleUpdatedAts[memoryRow{kind: kind, id: id}]; ok {
+		return titleUpdatedAt, nil
+	}
+	return createdAt, nil
+}
+
+func (m *Memory) setTitleUpdatedAt(ctx context.Context, kind string, id int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.titleUpdatedAts[memoryRow{kind: kind, id: id}] = at
+
+	return nil
+}
+
+// savepoint runs fn: the writes of Memory fail as a whole, so a rejected mutation leaves none to undo
+func (m *Memory) savepoint(ctx context.Context, fn func() error) error {
+	return fn()
+}
+
+func (m *Memory) pushedMutation(ctx context.Context, username, clientID string) (MutationResult, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, pushed := range m.pushedMutations {
+		if sameName(pushed.username, username) && pushed.ClientID == clientID {
+			return pushed.MutationResult, true, nil
+		}
+	}
+
+	return MutationResult{}, false, nil
+}
+
+func (m *Memory) recordPushedMutation(ctx context.Context, username string, result MutationResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acc, ok := m.accountByUsername(username)
+	if !ok {
+		return errForeignKeyChild
+	}
+
+	m.pushedMutations = append(m.pushedMutations, memoryPushedMutation{
+		username:       acc.Username,
+		createdAt:      time.Now(),
+		MutationResult: MutationResult{ClientID: result.ClientID, Kind: result.Kind, Status: MutationApplied, ID: result.ID},
+	})
+
+	return nil
+}
+
+// BatchStorageItems creates, updates and deletes items of a storage in one call
+func (m *Memory) BatchStorageItems(ctx context.Context, storageID int, operations []ItemOperation, bestEffort bool) (BatchResult, error) {
+	return batchStorageItems(ctx, m, storageID, operations, bestEffort)
+}
+
+// BatchShoppingListItems creates, updates and deletes items of a shopping list in one call
+func (m *Memory) BatchShoppingListItems(ctx context.Context, shoppingListID int, operations []ItemOperation, bestEffort bool) (BatchResult, error) {
+	return batchShoppingListItems(ctx, m, shoppingListID, operations, bestEffort)
+}
+
+// ExportAccount reads the archive of the data of username
+func (m *Memory) ExportAccount(ctx context.Context, username string) (Archive, error) {
+	return exportAccount(ctx, m, username)
+}
+
+// ImportAccount recreates the storages and shopping lists of archive under username
+func (m *Memory) ImportAccount(ctx context.Context, username string, archive Archive) (ImportReport, error) {
+	return importAccount(ctx, m, username, archive)
+}
+
+// RaiseExpirationAlerts raises the alerts of the storage items which reached a stage of their expiration at now,
+// out of the trash, and returns how many it raised
+func (m *Memory) RaiseExpirationAlerts(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	itemIDs := []int{}
+	for id, item := range m.storageItems {
+		if folder, ok := m.storages[item.StorageID]; ok && item.DeletedAt == nil && folder.DeletedAt == nil {
+			itemIDs = append(itemIDs, id)
+		}
+	}
+	sort.Ints(itemIDs)
+
+	raised := 0
+	for _, id := range itemIDs {
+		if m.hasItemAlert(id, AlertExpired) {
+			continue
+		}
+
+		item := m.storageItems[id]
+		if stage := expirationStage(item.ExpirationDate, item.ExpirationThreshold, m.hasItemAlert(id, AlertExpiring), now); stage != "" {
+			m.raiseAlert(id, stage)
+			raised++
+		}
+	}
+
+	return raised, nil
+}
+
+// hasItemAlert reports whether the storage item itemID reached stage
+func (m *Memory) hasItemAlert(itemID int, stage string) bool {
+	for _, alert := range m.itemAlerts {
+		if alert.itemID == itemID && alert.stage == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// raiseAlert raises the alert stage of the storage item itemID for the accounts attached to its storage
+// which turned notifications on
+func (m *Memory) raiseAlert(itemID int, stage string) {
+	alert := memoryItemAlert{id: m.nextID("item_alerts"), itemAlert: itemAlert{itemID: itemID, stage: stage}}
+	m.itemAlerts = append(m.itemAlerts, alert)
+
+	for _, b := range m.storageBinders {
+		if b.containerID != m.storageItems[itemID].StorageID {
+			continue
+		}
+
+		if acc, ok := m.accountByUsername(b.username); ok && acc.Notifications {
+			m.alertDeliveries = append(m.alertDeliveries, memoryAlertDelivery{
+				id:        m.nextID("item_alert_deliveries"),
+				alertID:   alert.id,
+				accountID: acc.ID,
+			})
+		}
+	}
+}
+
+// checkStock raises the low stock alert of a storage item written from before to after when the write drops it
+// to or below its quantity threshold and the alert is not raised already, and clears the alert once it is restocked
+func (m *Memory) checkStock(before, after *Item) {
+	dropped, restocked := lowStock(before, after)
+
+	switch {
+	case restocked:
+		m.clearItemAlerts(after.ID, AlertLowStock)
+	case dropped && !m.hasItemAlert(after.ID, AlertLowStock):
+		m.raiseAlert(after.ID, AlertLowStock)
+	}
+}
+
+// clearItemAlerts drops the alerts of the storage item itemID at stages, along with their deliveries
+func (m *Memory) clearItemAlerts(itemID int, stages ...string) {
+	alerts := []memoryItemAlert{}
+	for _, alert := range m.itemAlerts {
+		cleared := false
+		for _, stage := range stages {
+			cleared = cleared || (alert.itemID == itemID && alert.stage == stage)
+		}
+		if !cleared {
+			alerts = append(alerts, alert)
+		}
+	}
+	m.itemAlerts = alerts
+	m.removeAlertDeliveries()
+}
+
+// removeItemAlerts drops the alerts of the storage items which no longer exist, like the schema's cascade
+func (m *Memory) removeItemAlerts() {
+	alerts := []memoryItemAlert{}
+	for _, alert := range m.itemAlerts {
+		if _, ok := m.storageItems[alert.itemID]; ok {
+			alerts = append(alerts, alert)
+		}
+	}
+	m.itemAlerts = alerts
+	m.removeAlertDeliveries()
+}
+
+// removeAlertDeliveries drops the deliveries of the alerts which no longer exist, like the schema's cascade
+func (m *Memory) removeAlertDeliveries() {
+	alerts := map[int]bool{}
+	for _, alert := range m.itemAlerts {
+		alerts[alert.id] = true
+	}
+
+	deliveries := []memoryAlertDelivery{}
+	for _, delivery := range m.alertDeliveries {
+		if alerts[delivery.alertID] {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	m.alertDeliveries = deliveries
+}
+
+// GetPendingAlertDeliveries gets the alert deliveries not delivered yet after the delivery ID after, oldest first,
+// and marks those which can no longer be made as dropped
+func (m *Memory) GetPendingAlertDeliveries(ctx context.Context, after, limit int) ([]AlertDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alerts := map[int]memoryItemAlert{}
+	for _, alert := range m.itemAlerts {
+		alerts[alert.id] = alert
+	}
+
+	deliveries := []AlertDelivery{}
+	limit = pageLimit(limit)
+	now := time.Now()
+
+	// The deliveries are appended in ID order
+	for i, delivery := range m.alertDeliveries {
+		if len(deliveries) == limit {
+			break
+		}
+		if delivery.deliveredAt != nil || delivery.droppedAt != nil || delivery.id <= after {
+			continue
+		}
+
+		alert := alerts[delivery.alertID]
+		acc := m.accounts[delivery.accountID]
+		item := m.storageItems[alert.itemID]
+		folder := m.storages[item.StorageID]
+
+		if !acc.Notifications || item.DeletedAt != nil || folder.DeletedAt != nil || findBinder(m.storageBinders, acc.Username, folder.ID) < 0 {
+			m.alertDeliveries[i].droppedAt = &now
+			continue
+		}
+
+		deliveries = append(deliveries, AlertDelivery{
+			ID:             delivery.id,
+			AlertID:        alert.id,
+			Stage:          alert.stage,
+			Username:       acc.Username,
+			Email:          acc.Email,
+			StorageID:      item.StorageID,
+			StorageTitle:   folder.Title,
+			ItemID:         item.ID,
+			ItemTitle:      item.Title,
+			Quantity:       item.Quantity,
+			QuantityType:   item.QuantityType,
+			ExpirationDate: item.ExpirationDate,
+		})
+	}
+
+	return deliveries, nil
+}
+
+// MarkAlertDelivered marks an alert delivery as delivered by ID
+func (m *Memory) MarkAlertDelivered(ctx context.Context, deliveryID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, delivery := range m.alertDeliveries {
+		if delivery.id == deliveryID && delivery.deliveredAt == nil {
+			now := time.Now()
+			m.alertDeliveries[i].deliveredAt = &now
+			return nil
+		}
+	}
+
+	return errNoRowsAffected
+}
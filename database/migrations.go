@@ -0,0 +1,262 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+// migrationFiles holds the migrations of every dialect, under migrations/<dialect>
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationFileName matches migration file names, e.g. 0002_share_request_title.up.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change of the schema along with the script reverting it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations returns the migrations of the handler's dialect ordered by version
+func (handler *Handler) Migrations() ([]Migration, error) {
+	dir := path.Join("migrations", handler.dialect.name())
+
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+
+		script, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := []Migration{}
+
+	for version := 1; version <= len(byVersion); version++ {
+		migration, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("migration %d is missing", version)
+		}
+
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down script", version)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	return migrations, nil
+}
+
+// SchemaVersion returns the version of the last applied migration, 0 for an empty database.
+// A database created from the former init.sql, which has tables but no schema_migrations, is recorded at version 1.
+func (handler *Handler) SchemaVersion(ctx context.Context) (int, error) {
+	version := 0
+
+	if _, err := handler.DB.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return version, err
+	}
+
+	if err := handler.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(version), 0)
+		FROM schema_migrations
+	`).Scan(
+		&version,
+	); err != nil {
+		return version, err
+	}
+
+	if version > 0 {
+		return version, nil
+	}
+
+	// Probing the table is the only check that reads alike on every dialect
+	if _, err := handler.DB.ExecContext(ctx, `SELECT 1 FROM accounts WHERE 1 = 0`); err != nil {
+		return version, nil
+	}
+
+	migrations, err := handler.Migrations()
+	if err != nil {
+		return version, err
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		INSERT INTO schema_migrations(version, name)
+		VALUES(?, ?)
+	`)
+	if err != nil {
+		return version, err
+	}
+
+	defer stmt.Close()
+
+	if _, err := handler.exec(ctx, stmt, migrations[0].Version, migrations[0].Name); err != nil {
+		return version, err
+	}
+
+	return migrations[0].Version, nil
+}
+
+// Migrate applies or reverts migrations, one transaction each, until the schema is at version target
+func (handler *Handler) Migrate(ctx context.Context, target int) error {
+	migrations, err := handler.Migrations()
+	if err != nil {
+		return err
+	}
+
+	if target < 0 || target > len(migrations) {
+		return fmt.Errorf("unknown schema version %d, the latest is %d", target, len(migrations))
+	}
+
+	version, err := handler.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("unknown schema version %d, the latest is %d", version, len(migrations))
+	}
+
+	for ; version < target; version++ {
+		if err := handler.applyMigration(ctx, migrations[version], true); err != nil {
+			return err
+		}
+	}
+
+	for ; version > target; version-- {
+		if err := handler.applyMigration(ctx, migrations[version-1], false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrateLatest applies every pending migration
+func (handler *Handler) MigrateLatest(ctx context.Context) error {
+	migrations, err := handler.Migrations()
+	if err != nil {
+		return err
+	}
+
+	return handler.Migrate(ctx, len(migrations))
+}
+
+// CheckSchema returns an error unless the schema is at the latest version known to this build
+func (handler *Handler) CheckSchema(ctx context.Context) error {
+	migrations, err := handler.Migrations()
+	if err != nil {
+		return err
+	}
+
+	version, err := handler.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version > len(migrations):
+		return fmt.Errorf("unknown schema version %d, the latest known is %d", version, len(migrations))
+	case version < len(migrations):
+		return fmt.Errorf("schema version %d is behind the latest %d, migrate the database first", version, len(migrations))
+	default:
+		return nil
+	}
+}
+
+// applyMigration runs the up or down script of migration and records it in schema_migrations
+func (handler *Handler) applyMigration(ctx context.Context, migration Migration, up bool) error {
+	conn, err := handler.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	restore, err := handler.dialect.suspendForeignKeys(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	defer restore()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	script := migration.Down
+	record := `
+		DELETE FROM schema_migrations
+		WHERE version = ?
+	`
+	args := []interface{}{migration.Version}
+
+	if up {
+		script = migration.Up
+		record = `
+			INSERT INTO schema_migrations(version, name)
+			VALUES(?, ?)
+		`
+		args = append(args, migration.Name)
+	}
+
+	if err := handler.dialect.execScript(ctx, tx, script); err != nil {
+		return fmt.Errorf("migration %d %s: %v", migration.Version, migration.Name, err)
+	}
+
+	if err := handler.dialect.checkForeignKeys(ctx, tx); err != nil {
+		return fmt.Errorf("migration %d %s: %v", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, handler.dialect.rebind(record), args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -1,7 +1,3 @@
-create database if not exists `cat_clerk`;
-
-use `cat_clerk`;
-
 CREATE TABLE `accounts` (
 	`id` INT(12) NOT NULL AUTO_INCREMENT,
 	`username` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_general_ci',
@@ -60,7 +56,7 @@ CREATE TABLE `storage_items` (
 	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	PRIMARY KEY (`id`) USING BTREE,
 	INDEX `FK_storage_items_storages` (`storage_id`) USING BTREE,
-	CONSTRAINT `FK_storage_items_storages` FOREIGN KEY (`storage_id`) REFERENCES `cat_clerk`.`storages` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
+	CONSTRAINT `FK_storage_items_storages` FOREIGN KEY (`storage_id`) REFERENCES `storages` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
 )
 COLLATE='utf8mb4_general_ci'
 ENGINE=InnoDB
@@ -86,7 +82,7 @@ CREATE TABLE `shopping_list_items` (
 	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	PRIMARY KEY (`id`) USING BTREE,
 	INDEX `FK_shopping_list_items_shopping_lists` (`shopping_list_id`) USING BTREE,
-	CONSTRAINT `FK_shopping_list_items_shopping_lists` FOREIGN KEY (`shopping_list_id`) REFERENCES `cat_clerk`.`shopping_lists` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION
+	CONSTRAINT `FK_shopping_list_items_shopping_lists` FOREIGN KEY (`shopping_list_id`) REFERENCES `shopping_lists` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION
 )
 COLLATE='utf8mb4_general_ci'
 ENGINE=InnoDB
@@ -99,7 +95,7 @@ CREATE TABLE `settings` (
 	`notifications` TINYINT(1) NOT NULL DEFAULT '0',
 	PRIMARY KEY (`id`) USING BTREE,
 	UNIQUE INDEX `username` (`username`) USING BTREE,
-	CONSTRAINT `FK__accounts` FOREIGN KEY (`username`) REFERENCES `cat_clerk`.`accounts` (`username`) ON UPDATE CASCADE ON DELETE CASCADE
+	CONSTRAINT `FK__accounts` FOREIGN KEY (`username`) REFERENCES `accounts` (`username`) ON UPDATE CASCADE ON DELETE CASCADE
 )
 COLLATE='utf8mb4_general_ci'
 ENGINE=InnoDB
@@ -124,8 +120,8 @@ CREATE TABLE `account_storage_binder` (
 	UNIQUE INDEX `username_storage_id` (`username`, `storage_id`) USING BTREE,
 	INDEX `FK_account_storage_binder_storages` (`storage_id`) USING BTREE,
 	INDEX `FK_account_storage_binder_accounts` (`username`) USING BTREE,
-	CONSTRAINT `FK_account_storage_binder_accounts` FOREIGN KEY (`username`) REFERENCES `cat_clerk`.`accounts` (`username`) ON UPDATE NO ACTION ON DELETE NO ACTION,
-	CONSTRAINT `FK_account_storage_binder_storages` FOREIGN KEY (`storage_id`) REFERENCES `cat_clerk`.`storages` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
+	CONSTRAINT `FK_account_storage_binder_accounts` FOREIGN KEY (`username`) REFERENCES `accounts` (`username`) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	CONSTRAINT `FK_account_storage_binder_storages` FOREIGN KEY (`storage_id`) REFERENCES `storages` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
 )
 COLLATE='utf8mb4_general_ci'
 ENGINE=InnoDB
@@ -139,9 +135,9 @@ CREATE TABLE `account_shopping_list_binder` (
 	PRIMARY KEY (`id`) USING BTREE,
 	UNIQUE INDEX `username_shopping_list_id` (`username`, `shopping_list_id`) USING BTREE,
 	INDEX `FK_account_shopping_list_binder_shopping_lists` (`shopping_list_id`) USING BTREE,
-	CONSTRAINT `FK_account_shopping_list_binder_accounts` FOREIGN KEY (`username`) REFERENCES `cat_clerk`.`accounts` (`username`) ON UPDATE CASCADE ON DELETE CASCADE,
-	CONSTRAINT `FK_account_shopping_list_binder_shopping_lists` FOREIGN KEY (`shopping_list_id`) REFERENCES `cat_clerk`.`shopping_lists` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
+	CONSTRAINT `FK_account_shopping_list_binder_accounts` FOREIGN KEY (`username`) REFERENCES `accounts` (`username`) ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT `FK_account_shopping_list_binder_shopping_lists` FOREIGN KEY (`shopping_list_id`) REFERENCES `shopping_lists` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
 )
 COLLATE='utf8mb4_general_ci'
 ENGINE=InnoDB
-;
\ No newline at end of file
+;
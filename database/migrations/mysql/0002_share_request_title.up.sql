@@ -0,0 +1,2 @@
+ALTER TABLE `share_requests`
+	ADD COLUMN `title` VARCHAR(50) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `share_type`;
@@ -0,0 +1,2 @@
+ALTER TABLE `shopping_list_items`
+	DROP COLUMN `quantity_type`;
@@ -0,0 +1,2 @@
+ALTER TABLE `shopping_list_items`
+	ADD COLUMN `quantity_type` ENUM('grams','kilos','pieces','liters','milliliters') NOT NULL DEFAULT 'pieces' COLLATE 'utf8_general_ci' AFTER `quantity`;
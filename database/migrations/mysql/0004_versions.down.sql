@@ -0,0 +1,11 @@
+ALTER TABLE `storages`
+	DROP COLUMN `version`;
+
+ALTER TABLE `storage_items`
+	DROP COLUMN `version`;
+
+ALTER TABLE `shopping_lists`
+	DROP COLUMN `version`;
+
+ALTER TABLE `shopping_list_items`
+	DROP COLUMN `version`;
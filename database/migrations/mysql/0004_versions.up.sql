@@ -0,0 +1,11 @@
+ALTER TABLE `storages`
+	ADD COLUMN `version` INT(12) NOT NULL DEFAULT '1' AFTER `title`;
+
+ALTER TABLE `storage_items`
+	ADD COLUMN `version` INT(12) NOT NULL DEFAULT '1' AFTER `expiration_date`;
+
+ALTER TABLE `shopping_lists`
+	ADD COLUMN `version` INT(12) NOT NULL DEFAULT '1' AFTER `title`;
+
+ALTER TABLE `shopping_list_items`
+	ADD COLUMN `version` INT(12) NOT NULL DEFAULT '1' AFTER `quantity_type`;
@@ -0,0 +1,15 @@
+ALTER TABLE `storages`
+	DROP INDEX `deleted_at`,
+	DROP COLUMN `deleted_at`;
+
+ALTER TABLE `storage_items`
+	DROP INDEX `deleted_at`,
+	DROP COLUMN `deleted_at`;
+
+ALTER TABLE `shopping_lists`
+	DROP INDEX `deleted_at`,
+	DROP COLUMN `deleted_at`;
+
+ALTER TABLE `shopping_list_items`
+	DROP INDEX `deleted_at`,
+	DROP COLUMN `deleted_at`;
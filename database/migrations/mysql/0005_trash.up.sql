@@ -0,0 +1,15 @@
+ALTER TABLE `storages`
+	ADD COLUMN `deleted_at` TIMESTAMP NULL DEFAULT NULL AFTER `version`,
+	ADD INDEX `deleted_at` (`deleted_at`);
+
+ALTER TABLE `storage_items`
+	ADD COLUMN `deleted_at` TIMESTAMP NULL DEFAULT NULL AFTER `version`,
+	ADD INDEX `deleted_at` (`deleted_at`);
+
+ALTER TABLE `shopping_lists`
+	ADD COLUMN `deleted_at` TIMESTAMP NULL DEFAULT NULL AFTER `version`,
+	ADD INDEX `deleted_at` (`deleted_at`);
+
+ALTER TABLE `shopping_list_items`
+	ADD COLUMN `deleted_at` TIMESTAMP NULL DEFAULT NULL AFTER `version`,
+	ADD INDEX `deleted_at` (`deleted_at`);
@@ -0,0 +1,15 @@
+CREATE TABLE `storage_item_events` (
+	`id` INT(12) NOT NULL AUTO_INCREMENT,
+	`item_id` INT(12) NOT NULL,
+	`actor` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci',
+	`field` VARCHAR(32) NOT NULL COLLATE 'utf8mb4_general_ci',
+	`old_value` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_general_ci',
+	`new_value` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_general_ci',
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`) USING BTREE,
+	INDEX `FK_storage_item_events_storage_items` (`item_id`, `id`) USING BTREE,
+	CONSTRAINT `FK_storage_item_events_storage_items` FOREIGN KEY (`item_id`) REFERENCES `storage_items` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
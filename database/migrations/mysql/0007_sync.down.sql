@@ -0,0 +1,12 @@
+DROP TABLE `sync_purges`;
+
+DROP TABLE `sync_tombstones`;
+
+ALTER TABLE `account_shopping_list_binder`
+	DROP COLUMN `created_at`;
+
+ALTER TABLE `account_storage_binder`
+	DROP COLUMN `created_at`;
+
+ALTER TABLE `storages`
+	MODIFY COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
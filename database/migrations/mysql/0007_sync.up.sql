@@ -0,0 +1,32 @@
+ALTER TABLE `storages`
+	MODIFY COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+ALTER TABLE `account_storage_binder`
+	ADD COLUMN `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER `owner`;
+
+ALTER TABLE `account_shopping_list_binder`
+	ADD COLUMN `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER `owner`;
+
+CREATE TABLE `sync_tombstones` (
+	`id` INT(12) NOT NULL AUTO_INCREMENT,
+	`username` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_general_ci',
+	`kind` VARCHAR(32) NOT NULL COLLATE 'utf8mb4_general_ci',
+	`entity_id` INT(12) NOT NULL,
+	`reason` VARCHAR(32) NOT NULL COLLATE 'utf8mb4_general_ci',
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`) USING BTREE,
+	INDEX `username_created_at` (`username`, `created_at`) USING BTREE,
+	INDEX `created_at` (`created_at`) USING BTREE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
+
+CREATE TABLE `sync_purges` (
+	`id` INT(12) NOT NULL AUTO_INCREMENT,
+	`purged_before` TIMESTAMP(6) NOT NULL,
+	PRIMARY KEY (`id`) USING BTREE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,32 @@
+ALTER TABLE `storages`
+	ADD COLUMN `title_updated_at` TIMESTAMP(6) NULL DEFAULT NULL AFTER `version`;
+
+ALTER TABLE `storage_items`
+	ADD COLUMN `title_updated_at` TIMESTAMP(6) NULL DEFAULT NULL AFTER `version`;
+
+ALTER TABLE `shopping_lists`
+	ADD COLUMN `title_updated_at` TIMESTAMP(6) NULL DEFAULT NULL AFTER `version`;
+
+ALTER TABLE `shopping_list_items`
+	ADD COLUMN `title_updated_at` TIMESTAMP(6) NULL DEFAULT NULL AFTER `version`;
+
+-- Setting updated_at to itself keeps ON UPDATE CURRENT_TIMESTAMP from touching it
+UPDATE `storages` SET `title_updated_at` = `updated_at`, `updated_at` = `updated_at`;
+UPDATE `storage_items` SET `title_updated_at` = `updated_at`, `updated_at` = `updated_at`;
+UPDATE `shopping_lists` SET `title_updated_at` = `updated_at`, `updated_at` = `updated_at`;
+UPDATE `shopping_list_items` SET `title_updated_at` = `updated_at`, `updated_at` = `updated_at`;
+
+CREATE TABLE `pushed_mutations` (
+	`id` INT(12) NOT NULL AUTO_INCREMENT,
+	`username` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_general_ci',
+	`client_id` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_bin',
+	`kind` VARCHAR(32) NOT NULL COLLATE 'utf8mb4_general_ci',
+	`entity_id` INT(12) NOT NULL,
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `username_client_id` (`username`, `client_id`) USING BTREE,
+	INDEX `created_at` (`created_at`) USING BTREE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,118 @@
+ALTER TABLE `storage_item_events`
+	ADD COLUMN `actor` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `item_id`;
+
+UPDATE `storage_item_events` AS sie
+INNER JOIN `accounts` AS a ON a.`id` = sie.`actor_id`
+SET sie.`actor` = a.`username`;
+
+ALTER TABLE `storage_item_events`
+	DROP FOREIGN KEY `FK_storage_item_events_accounts`;
+
+ALTER TABLE `storage_item_events`
+	DROP INDEX `FK_storage_item_events_accounts`,
+	DROP COLUMN `actor_id`;
+
+ALTER TABLE `pushed_mutations`
+	ADD COLUMN `username` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `id`;
+
+UPDATE `pushed_mutations` AS pm
+INNER JOIN `accounts` AS a ON a.`id` = pm.`account_id`
+SET pm.`username` = a.`username`;
+
+ALTER TABLE `pushed_mutations`
+	DROP FOREIGN KEY `FK_pushed_mutations_accounts`;
+
+ALTER TABLE `pushed_mutations`
+	DROP INDEX `account_id_client_id`,
+	DROP COLUMN `account_id`,
+	ALTER COLUMN `username` DROP DEFAULT,
+	ADD UNIQUE INDEX `username_client_id` (`username`, `client_id`) USING BTREE;
+
+ALTER TABLE `sync_tombstones`
+	ADD COLUMN `username` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `id`;
+
+UPDATE `sync_tombstones` AS st
+INNER JOIN `accounts` AS a ON a.`id` = st.`account_id`
+SET st.`username` = a.`username`;
+
+ALTER TABLE `sync_tombstones`
+	DROP FOREIGN KEY `FK_sync_tombstones_accounts`;
+
+ALTER TABLE `sync_tombstones`
+	DROP INDEX `account_id_created_at`,
+	DROP COLUMN `account_id`,
+	ALTER COLUMN `username` DROP DEFAULT,
+	ADD INDEX `username_created_at` (`username`, `created_at`) USING BTREE;
+
+ALTER TABLE `share_requests`
+	ADD COLUMN `from_username` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `id`,
+	ADD COLUMN `to_username` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `from_username`;
+
+UPDATE `share_requests` AS sr
+INNER JOIN `accounts` AS fa ON fa.`id` = sr.`from_account_id`
+INNER JOIN `accounts` AS ta ON ta.`id` = sr.`to_account_id`
+SET sr.`from_username` = fa.`username`, sr.`to_username` = ta.`username`;
+
+ALTER TABLE `share_requests`
+	DROP FOREIGN KEY `FK_share_requests_from_accounts`,
+	DROP FOREIGN KEY `FK_share_requests_to_accounts`;
+
+ALTER TABLE `share_requests`
+	DROP INDEX `FK_share_requests_from_accounts`,
+	DROP INDEX `FK_share_requests_to_accounts`,
+	DROP COLUMN `from_account_id`,
+	DROP COLUMN `to_account_id`,
+	ALTER COLUMN `from_username` DROP DEFAULT,
+	ALTER COLUMN `to_username` DROP DEFAULT;
+
+ALTER TABLE `account_shopping_list_binder`
+	ADD COLUMN `username` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `id`;
+
+UPDATE `account_shopping_list_binder` AS aslb
+INNER JOIN `accounts` AS a ON a.`id` = aslb.`account_id`
+SET aslb.`username` = a.`username`;
+
+ALTER TABLE `account_shopping_list_binder`
+	DROP FOREIGN KEY `FK_account_shopping_list_binder_accounts`;
+
+ALTER TABLE `account_shopping_list_binder`
+	DROP INDEX `account_id_shopping_list_id`,
+	DROP COLUMN `account_id`,
+	ALTER COLUMN `username` DROP DEFAULT,
+	ADD UNIQUE INDEX `username_shopping_list_id` (`username`, `shopping_list_id`) USING BTREE,
+	ADD CONSTRAINT `FK_account_shopping_list_binder_accounts` FOREIGN KEY (`username`) REFERENCES `accounts` (`username`) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE `account_storage_binder`
+	ADD COLUMN `username` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `id`;
+
+UPDATE `account_storage_binder` AS asb
+INNER JOIN `accounts` AS a ON a.`id` = asb.`account_id`
+SET asb.`username` = a.`username`;
+
+ALTER TABLE `account_storage_binder`
+	DROP FOREIGN KEY `FK_account_storage_binder_accounts`;
+
+ALTER TABLE `account_storage_binder`
+	DROP INDEX `account_id_storage_id`,
+	DROP COLUMN `account_id`,
+	ALTER COLUMN `username` DROP DEFAULT,
+	ADD UNIQUE INDEX `username_storage_id` (`username`, `storage_id`) USING BTREE,
+	ADD INDEX `FK_account_storage_binder_accounts` (`username`) USING BTREE,
+	ADD CONSTRAINT `FK_account_storage_binder_accounts` FOREIGN KEY (`username`) REFERENCES `accounts` (`username`) ON UPDATE NO ACTION ON DELETE NO ACTION;
+
+ALTER TABLE `settings`
+	ADD COLUMN `username` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_general_ci' AFTER `id`;
+
+UPDATE `settings` AS s
+INNER JOIN `accounts` AS a ON a.`id` = s.`account_id`
+SET s.`username` = a.`username`;
+
+ALTER TABLE `settings`
+	DROP FOREIGN KEY `FK_settings_accounts`;
+
+ALTER TABLE `settings`
+	DROP INDEX `account_id`,
+	DROP COLUMN `account_id`,
+	ALTER COLUMN `username` DROP DEFAULT,
+	ADD UNIQUE INDEX `username` (`username`) USING BTREE,
+	ADD CONSTRAINT `FK__accounts` FOREIGN KEY (`username`) REFERENCES `accounts` (`username`) ON UPDATE CASCADE ON DELETE CASCADE;
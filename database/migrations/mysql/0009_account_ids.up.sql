@@ -0,0 +1,122 @@
+-- Rows refer to accounts by ID, so that renaming an account only changes accounts.username.
+-- Rows naming an account which no longer exists cannot be kept and are deleted.
+ALTER TABLE `settings`
+	ADD COLUMN `account_id` INT(12) NULL DEFAULT NULL AFTER `id`;
+
+UPDATE `settings` AS s
+INNER JOIN `accounts` AS a ON a.`username` = s.`username`
+SET s.`account_id` = a.`id`;
+
+DELETE FROM `settings` WHERE `account_id` IS NULL;
+
+ALTER TABLE `settings`
+	DROP FOREIGN KEY `FK__accounts`;
+
+ALTER TABLE `settings`
+	DROP INDEX `username`,
+	DROP COLUMN `username`,
+	MODIFY COLUMN `account_id` INT(12) NOT NULL,
+	ADD UNIQUE INDEX `account_id` (`account_id`) USING BTREE,
+	ADD CONSTRAINT `FK_settings_accounts` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE `account_storage_binder`
+	ADD COLUMN `account_id` INT(12) NULL DEFAULT NULL AFTER `id`;
+
+UPDATE `account_storage_binder` AS asb
+INNER JOIN `accounts` AS a ON a.`username` = asb.`username`
+SET asb.`account_id` = a.`id`;
+
+DELETE FROM `account_storage_binder` WHERE `account_id` IS NULL;
+
+ALTER TABLE `account_storage_binder`
+	DROP FOREIGN KEY `FK_account_storage_binder_accounts`;
+
+ALTER TABLE `account_storage_binder`
+	DROP INDEX `FK_account_storage_binder_accounts`,
+	DROP INDEX `username_storage_id`,
+	DROP COLUMN `username`,
+	MODIFY COLUMN `account_id` INT(12) NOT NULL,
+	ADD UNIQUE INDEX `account_id_storage_id` (`account_id`, `storage_id`) USING BTREE,
+	ADD CONSTRAINT `FK_account_storage_binder_accounts` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE NO ACTION;
+
+ALTER TABLE `account_shopping_list_binder`
+	ADD COLUMN `account_id` INT(12) NULL DEFAULT NULL AFTER `id`;
+
+UPDATE `account_shopping_list_binder` AS aslb
+INNER JOIN `accounts` AS a ON a.`username` = aslb.`username`
+SET aslb.`account_id` = a.`id`;
+
+DELETE FROM `account_shopping_list_binder` WHERE `account_id` IS NULL;
+
+ALTER TABLE `account_shopping_list_binder`
+	DROP FOREIGN KEY `FK_account_shopping_list_binder_accounts`;
+
+ALTER TABLE `account_shopping_list_binder`
+	DROP INDEX `username_shopping_list_id`,
+	DROP COLUMN `username`,
+	MODIFY COLUMN `account_id` INT(12) NOT NULL,
+	ADD UNIQUE INDEX `account_id_shopping_list_id` (`account_id`, `shopping_list_id`) USING BTREE,
+	ADD CONSTRAINT `FK_account_shopping_list_binder_accounts` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE `share_requests`
+	ADD COLUMN `from_account_id` INT(12) NULL DEFAULT NULL AFTER `id`,
+	ADD COLUMN `to_account_id` INT(12) NULL DEFAULT NULL AFTER `from_account_id`;
+
+UPDATE `share_requests` AS sr
+INNER JOIN `accounts` AS fa ON fa.`username` = sr.`from_username`
+INNER JOIN `accounts` AS ta ON ta.`username` = sr.`to_username`
+SET sr.`from_account_id` = fa.`id`, sr.`to_account_id` = ta.`id`;
+
+DELETE FROM `share_requests` WHERE `from_account_id` IS NULL OR `to_account_id` IS NULL;
+
+ALTER TABLE `share_requests`
+	DROP COLUMN `from_username`,
+	DROP COLUMN `to_username`,
+	MODIFY COLUMN `from_account_id` INT(12) NOT NULL,
+	MODIFY COLUMN `to_account_id` INT(12) NOT NULL,
+	ADD CONSTRAINT `FK_share_requests_from_accounts` FOREIGN KEY (`from_account_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE CASCADE,
+	ADD CONSTRAINT `FK_share_requests_to_accounts` FOREIGN KEY (`to_account_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE `sync_tombstones`
+	ADD COLUMN `account_id` INT(12) NULL DEFAULT NULL AFTER `id`;
+
+UPDATE `sync_tombstones` AS st
+INNER JOIN `accounts` AS a ON a.`username` = st.`username`
+SET st.`account_id` = a.`id`;
+
+DELETE FROM `sync_tombstones` WHERE `account_id` IS NULL;
+
+ALTER TABLE `sync_tombstones`
+	DROP INDEX `username_created_at`,
+	DROP COLUMN `username`,
+	MODIFY COLUMN `account_id` INT(12) NOT NULL,
+	ADD INDEX `account_id_created_at` (`account_id`, `created_at`) USING BTREE,
+	ADD CONSTRAINT `FK_sync_tombstones_accounts` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE `pushed_mutations`
+	ADD COLUMN `account_id` INT(12) NULL DEFAULT NULL AFTER `id`;
+
+UPDATE `pushed_mutations` AS pm
+INNER JOIN `accounts` AS a ON a.`username` = pm.`username`
+SET pm.`account_id` = a.`id`;
+
+DELETE FROM `pushed_mutations` WHERE `account_id` IS NULL;
+
+ALTER TABLE `pushed_mutations`
+	DROP INDEX `username_client_id`,
+	DROP COLUMN `username`,
+	MODIFY COLUMN `account_id` INT(12) NOT NULL,
+	ADD UNIQUE INDEX `account_id_client_id` (`account_id`, `client_id`) USING BTREE,
+	ADD CONSTRAINT `FK_pushed_mutations_accounts` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE CASCADE;
+
+-- The history outlives the accounts which made it, whose events are then made by nobody
+ALTER TABLE `storage_item_events`
+	ADD COLUMN `actor_id` INT(12) NULL DEFAULT NULL AFTER `item_id`;
+
+UPDATE `storage_item_events` AS sie
+INNER JOIN `accounts` AS a ON a.`username` = sie.`actor`
+SET sie.`actor_id` = a.`id`;
+
+ALTER TABLE `storage_item_events`
+	DROP COLUMN `actor`,
+	ADD CONSTRAINT `FK_storage_item_events_accounts` FOREIGN KEY (`actor_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE SET NULL;
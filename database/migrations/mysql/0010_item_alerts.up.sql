@@ -0,0 +1,30 @@
+CREATE TABLE `item_alerts` (
+	`id` INT(12) NOT NULL AUTO_INCREMENT,
+	`item_id` INT(12) NOT NULL,
+	`stage` VARCHAR(32) NOT NULL COLLATE 'utf8mb4_general_ci',
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `item_id_stage` (`item_id`, `stage`) USING BTREE,
+	CONSTRAINT `FK_item_alerts_storage_items` FOREIGN KEY (`item_id`) REFERENCES `storage_items` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
+
+CREATE TABLE `item_alert_deliveries` (
+	`id` INT(12) NOT NULL AUTO_INCREMENT,
+	`alert_id` INT(12) NOT NULL,
+	`account_id` INT(12) NOT NULL,
+	`delivered_at` TIMESTAMP NULL DEFAULT NULL,
+	`dropped_at` TIMESTAMP NULL DEFAULT NULL,
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `alert_id_account_id` (`alert_id`, `account_id`) USING BTREE,
+	INDEX `FK_item_alert_deliveries_accounts` (`account_id`) USING BTREE,
+	INDEX `delivered_at` (`delivered_at`, `id`) USING BTREE,
+	CONSTRAINT `FK_item_alert_deliveries_item_alerts` FOREIGN KEY (`alert_id`) REFERENCES `item_alerts` (`id`) ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT `FK_item_alert_deliveries_accounts` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`) ON UPDATE CASCADE ON DELETE CASCADE
+)
+COLLATE='utf8mb4_general_ci'
+ENGINE=InnoDB
+;
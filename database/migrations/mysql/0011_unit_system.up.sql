@@ -0,0 +1,2 @@
+ALTER TABLE `accounts`
+	ADD COLUMN `unit_system` ENUM('metric','imperial') NOT NULL DEFAULT 'metric' COLLATE 'utf8mb4_general_ci' AFTER `notifications`;
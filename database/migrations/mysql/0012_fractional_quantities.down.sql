@@ -0,0 +1,7 @@
+-- Fractional quantities are rounded to whole ones.
+ALTER TABLE `storage_items`
+	MODIFY COLUMN `quantity` INT(12) NOT NULL DEFAULT '0',
+	MODIFY COLUMN `quantity_threshold` INT(12) NOT NULL DEFAULT '0';
+
+ALTER TABLE `shopping_list_items`
+	MODIFY COLUMN `quantity` INT(12) NOT NULL DEFAULT '1';
@@ -0,0 +1,6 @@
+ALTER TABLE `storage_items`
+	MODIFY COLUMN `quantity` DECIMAL(12,3) NOT NULL DEFAULT '0',
+	MODIFY COLUMN `quantity_threshold` DECIMAL(12,3) NOT NULL DEFAULT '0';
+
+ALTER TABLE `shopping_list_items`
+	MODIFY COLUMN `quantity` DECIMAL(12,3) NOT NULL DEFAULT '1';
@@ -0,0 +1,2 @@
+-- Expiration dates are already stored as timestamps, only SQLite kept them as sent
+SELECT 1;
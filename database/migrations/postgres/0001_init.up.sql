@@ -0,0 +1,123 @@
+CREATE EXTENSION IF NOT EXISTS citext;
+
+CREATE TYPE share_type AS ENUM ('storage', 'shopping_list');
+
+CREATE TYPE quantity_type AS ENUM ('grams', 'kilos', 'pieces', 'liters', 'milliliters');
+
+CREATE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+	NEW.updated_at = CURRENT_TIMESTAMP;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE accounts (
+	id SERIAL PRIMARY KEY,
+	username CITEXT NOT NULL,
+	password VARCHAR(128) NOT NULL,
+	salt VARCHAR(128) NOT NULL,
+	email CITEXT NOT NULL,
+	dark_theme BOOLEAN NOT NULL DEFAULT TRUE,
+	notifications BOOLEAN NOT NULL DEFAULT FALSE,
+	last_login TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT accounts_username_key UNIQUE (username),
+	CONSTRAINT accounts_email_key UNIQUE (email)
+);
+
+CREATE TRIGGER accounts_updated_at BEFORE UPDATE ON accounts
+FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+
+CREATE TABLE storages (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(50) NOT NULL DEFAULT '',
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE share_requests (
+	id SERIAL PRIMARY KEY,
+	from_username CITEXT NOT NULL,
+	to_username CITEXT NOT NULL,
+	share_type share_type NOT NULL,
+	id_request INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE storage_items (
+	id SERIAL PRIMARY KEY,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	title VARCHAR(50) NOT NULL,
+	image VARCHAR(512) NOT NULL DEFAULT '',
+	quantity INTEGER NOT NULL DEFAULT 0,
+	quantity_type quantity_type NOT NULL DEFAULT 'pieces',
+	quantity_threshold INTEGER NOT NULL DEFAULT 0,
+	expiration_threshold INTEGER NOT NULL DEFAULT 0,
+	expiration_date TIMESTAMPTZ NULL DEFAULT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX fk_storage_items_storages ON storage_items (storage_id);
+
+CREATE TRIGGER storage_items_updated_at BEFORE UPDATE ON storage_items
+FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+
+CREATE TABLE shopping_lists (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(50) NOT NULL DEFAULT '',
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER shopping_lists_updated_at BEFORE UPDATE ON shopping_lists
+FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+
+CREATE TABLE shopping_list_items (
+	id SERIAL PRIMARY KEY,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	title VARCHAR(50) NOT NULL DEFAULT '',
+	quantity INTEGER NOT NULL DEFAULT 1,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX fk_shopping_list_items_shopping_lists ON shopping_list_items (shopping_list_id);
+
+CREATE TRIGGER shopping_list_items_updated_at BEFORE UPDATE ON shopping_list_items
+FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+
+CREATE TABLE settings (
+	id SERIAL PRIMARY KEY,
+	username CITEXT NOT NULL REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE,
+	dark_theme BOOLEAN NOT NULL DEFAULT TRUE,
+	notifications BOOLEAN NOT NULL DEFAULT FALSE,
+	CONSTRAINT settings_username_key UNIQUE (username)
+);
+
+CREATE TABLE foods (
+	id SERIAL PRIMARY KEY,
+	name CITEXT NOT NULL,
+	CONSTRAINT foods_name_key UNIQUE (name)
+);
+
+CREATE TABLE account_storage_binder (
+	id SERIAL PRIMARY KEY,
+	username CITEXT NOT NULL REFERENCES accounts (username) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner BOOLEAN NOT NULL DEFAULT FALSE,
+	CONSTRAINT account_storage_binder_username_storage_id_key UNIQUE (username, storage_id)
+);
+
+CREATE INDEX fk_account_storage_binder_storages ON account_storage_binder (storage_id);
+
+CREATE TABLE account_shopping_list_binder (
+	id SERIAL PRIMARY KEY,
+	username CITEXT NOT NULL REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner BOOLEAN NOT NULL DEFAULT FALSE,
+	CONSTRAINT account_shopping_list_binder_username_shopping_list_id_key UNIQUE (username, shopping_list_id)
+);
+
+CREATE INDEX fk_account_shopping_list_binder_shopping_lists ON account_shopping_list_binder (shopping_list_id);
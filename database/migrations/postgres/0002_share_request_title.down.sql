@@ -0,0 +1,2 @@
+ALTER TABLE share_requests
+	DROP COLUMN title;
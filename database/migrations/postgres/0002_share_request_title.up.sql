@@ -0,0 +1,2 @@
+ALTER TABLE share_requests
+	ADD COLUMN title VARCHAR(50) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE shopping_list_items
+	ADD COLUMN quantity_type quantity_type NOT NULL DEFAULT 'pieces';
@@ -0,0 +1,11 @@
+ALTER TABLE storages
+	ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE storage_items
+	ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE shopping_lists
+	ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE shopping_list_items
+	ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
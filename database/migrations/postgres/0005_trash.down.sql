@@ -0,0 +1,11 @@
+ALTER TABLE storages
+	DROP COLUMN deleted_at;
+
+ALTER TABLE storage_items
+	DROP COLUMN deleted_at;
+
+ALTER TABLE shopping_lists
+	DROP COLUMN deleted_at;
+
+ALTER TABLE shopping_list_items
+	DROP COLUMN deleted_at;
@@ -0,0 +1,19 @@
+ALTER TABLE storages
+	ADD COLUMN deleted_at TIMESTAMPTZ NULL DEFAULT NULL;
+
+CREATE INDEX storages_deleted_at ON storages (deleted_at);
+
+ALTER TABLE storage_items
+	ADD COLUMN deleted_at TIMESTAMPTZ NULL DEFAULT NULL;
+
+CREATE INDEX storage_items_deleted_at ON storage_items (deleted_at);
+
+ALTER TABLE shopping_lists
+	ADD COLUMN deleted_at TIMESTAMPTZ NULL DEFAULT NULL;
+
+CREATE INDEX shopping_lists_deleted_at ON shopping_lists (deleted_at);
+
+ALTER TABLE shopping_list_items
+	ADD COLUMN deleted_at TIMESTAMPTZ NULL DEFAULT NULL;
+
+CREATE INDEX shopping_list_items_deleted_at ON shopping_list_items (deleted_at);
@@ -0,0 +1 @@
+DROP TABLE storage_item_events;
@@ -0,0 +1,11 @@
+CREATE TABLE storage_item_events (
+	id SERIAL PRIMARY KEY,
+	item_id INTEGER NOT NULL REFERENCES storage_items (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	actor VARCHAR(64) NOT NULL DEFAULT '',
+	field VARCHAR(32) NOT NULL,
+	old_value TEXT NULL DEFAULT NULL,
+	new_value TEXT NULL DEFAULT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX storage_item_events_item_id ON storage_item_events (item_id, id);
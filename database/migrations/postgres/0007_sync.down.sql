@@ -0,0 +1,11 @@
+DROP TABLE sync_purges;
+
+DROP TABLE sync_tombstones;
+
+ALTER TABLE account_shopping_list_binder
+	DROP COLUMN created_at;
+
+ALTER TABLE account_storage_binder
+	DROP COLUMN created_at;
+
+DROP TRIGGER storages_updated_at ON storages;
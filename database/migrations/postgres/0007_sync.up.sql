@@ -0,0 +1,25 @@
+CREATE TRIGGER storages_updated_at BEFORE UPDATE ON storages
+FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+
+ALTER TABLE account_storage_binder
+	ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE account_shopping_list_binder
+	ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE TABLE sync_tombstones (
+	id SERIAL PRIMARY KEY,
+	username CITEXT NOT NULL,
+	kind VARCHAR(32) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	reason VARCHAR(32) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX sync_tombstones_username_created_at ON sync_tombstones (username, created_at);
+CREATE INDEX sync_tombstones_created_at ON sync_tombstones (created_at);
+
+CREATE TABLE sync_purges (
+	id SERIAL PRIMARY KEY,
+	purged_before TIMESTAMPTZ NOT NULL
+);
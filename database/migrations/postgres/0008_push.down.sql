@@ -0,0 +1,13 @@
+DROP TABLE pushed_mutations;
+
+ALTER TABLE shopping_list_items
+	DROP COLUMN title_updated_at;
+
+ALTER TABLE shopping_lists
+	DROP COLUMN title_updated_at;
+
+ALTER TABLE storage_items
+	DROP COLUMN title_updated_at;
+
+ALTER TABLE storages
+	DROP COLUMN title_updated_at;
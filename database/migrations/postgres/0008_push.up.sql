@@ -0,0 +1,39 @@
+ALTER TABLE storages
+	ADD COLUMN title_updated_at TIMESTAMPTZ NULL DEFAULT NULL;
+
+ALTER TABLE storage_items
+	ADD COLUMN title_updated_at TIMESTAMPTZ NULL DEFAULT NULL;
+
+ALTER TABLE shopping_lists
+	ADD COLUMN title_updated_at TIMESTAMPTZ NULL DEFAULT NULL;
+
+ALTER TABLE shopping_list_items
+	ADD COLUMN title_updated_at TIMESTAMPTZ NULL DEFAULT NULL;
+
+-- The updated_at triggers are off while the titles take their time from it
+ALTER TABLE storages DISABLE TRIGGER storages_updated_at;
+ALTER TABLE storage_items DISABLE TRIGGER storage_items_updated_at;
+ALTER TABLE shopping_lists DISABLE TRIGGER shopping_lists_updated_at;
+ALTER TABLE shopping_list_items DISABLE TRIGGER shopping_list_items_updated_at;
+
+UPDATE storages SET title_updated_at = updated_at;
+UPDATE storage_items SET title_updated_at = updated_at;
+UPDATE shopping_lists SET title_updated_at = updated_at;
+UPDATE shopping_list_items SET title_updated_at = updated_at;
+
+ALTER TABLE storages ENABLE TRIGGER storages_updated_at;
+ALTER TABLE storage_items ENABLE TRIGGER storage_items_updated_at;
+ALTER TABLE shopping_lists ENABLE TRIGGER shopping_lists_updated_at;
+ALTER TABLE shopping_list_items ENABLE TRIGGER shopping_list_items_updated_at;
+
+CREATE TABLE pushed_mutations (
+	id SERIAL PRIMARY KEY,
+	username CITEXT NOT NULL,
+	client_id VARCHAR(64) NOT NULL,
+	kind VARCHAR(32) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pushed_mutations_username_client_id_key UNIQUE (username, client_id)
+);
+
+CREATE INDEX pushed_mutations_created_at ON pushed_mutations (created_at);
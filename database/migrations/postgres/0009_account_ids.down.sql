@@ -0,0 +1,92 @@
+-- Dropping an account_id column drops the constraints and indexes it is part of
+ALTER TABLE storage_item_events
+	ADD COLUMN actor VARCHAR(64) NOT NULL DEFAULT '';
+
+UPDATE storage_item_events AS sie
+SET actor = a.username
+FROM accounts AS a
+WHERE a.id = sie.actor_id;
+
+ALTER TABLE storage_item_events
+	DROP COLUMN actor_id;
+
+ALTER TABLE pushed_mutations
+	ADD COLUMN username CITEXT NULL DEFAULT NULL;
+
+UPDATE pushed_mutations AS pm
+SET username = a.username
+FROM accounts AS a
+WHERE a.id = pm.account_id;
+
+ALTER TABLE pushed_mutations
+	DROP COLUMN account_id,
+	ALTER COLUMN username SET NOT NULL,
+	ADD CONSTRAINT pushed_mutations_username_client_id_key UNIQUE (username, client_id);
+
+ALTER TABLE sync_tombstones
+	ADD COLUMN username CITEXT NULL DEFAULT NULL;
+
+UPDATE sync_tombstones AS st
+SET username = a.username
+FROM accounts AS a
+WHERE a.id = st.account_id;
+
+ALTER TABLE sync_tombstones
+	DROP COLUMN account_id,
+	ALTER COLUMN username SET NOT NULL;
+
+CREATE INDEX sync_tombstones_username_created_at ON sync_tombstones (username, created_at);
+
+ALTER TABLE share_requests
+	ADD COLUMN from_username CITEXT NULL DEFAULT NULL,
+	ADD COLUMN to_username CITEXT NULL DEFAULT NULL;
+
+UPDATE share_requests AS sr
+SET from_username = fa.username, to_username = ta.username
+FROM accounts AS fa, accounts AS ta
+WHERE fa.id = sr.from_account_id AND ta.id = sr.to_account_id;
+
+ALTER TABLE share_requests
+	DROP COLUMN from_account_id,
+	DROP COLUMN to_account_id,
+	ALTER COLUMN from_username SET NOT NULL,
+	ALTER COLUMN to_username SET NOT NULL;
+
+ALTER TABLE account_shopping_list_binder
+	ADD COLUMN username CITEXT NULL DEFAULT NULL REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE;
+
+UPDATE account_shopping_list_binder AS aslb
+SET username = a.username
+FROM accounts AS a
+WHERE a.id = aslb.account_id;
+
+ALTER TABLE account_shopping_list_binder
+	DROP COLUMN account_id,
+	ALTER COLUMN username SET NOT NULL,
+	ADD CONSTRAINT account_shopping_list_binder_username_shopping_list_id_key UNIQUE (username, shopping_list_id);
+
+ALTER TABLE account_storage_binder
+	ADD COLUMN username CITEXT NULL DEFAULT NULL REFERENCES accounts (username) ON UPDATE NO ACTION ON DELETE NO ACTION;
+
+UPDATE account_storage_binder AS asb
+SET username = a.username
+FROM accounts AS a
+WHERE a.id = asb.account_id;
+
+ALTER TABLE account_storage_binder
+	DROP COLUMN account_id,
+	ALTER COLUMN username SET NOT NULL,
+	ADD CONSTRAINT account_storage_binder_username_storage_id_key UNIQUE (username, storage_id);
+
+ALTER TABLE settings
+	ADD COLUMN username CITEXT NULL DEFAULT NULL REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE;
+
+UPDATE settings AS s
+SET username = a.username
+FROM accounts AS a
+WHERE a.id = s.account_id;
+
+ALTER TABLE settings
+	DROP COLUMN account_id,
+	ALTER COLUMN username SET NOT NULL,
+	ADD CONSTRAINT settings_username_key UNIQUE (username);
@@ -0,0 +1,111 @@
+-- Rows refer to accounts by ID, so that renaming an account only changes accounts.username.
+-- Rows naming an account which no longer exists cannot be kept and are deleted.
+-- Dropping a username column drops the constraints and indexes it is part of.
+ALTER TABLE settings
+	ADD COLUMN account_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+UPDATE settings AS s
+SET account_id = a.id
+FROM accounts AS a
+WHERE a.username = s.username;
+
+DELETE FROM settings WHERE account_id IS NULL;
+
+ALTER TABLE settings
+	DROP COLUMN username,
+	ALTER COLUMN account_id SET NOT NULL,
+	ADD CONSTRAINT settings_account_id_key UNIQUE (account_id);
+
+ALTER TABLE account_storage_binder
+	ADD COLUMN account_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE NO ACTION;
+
+UPDATE account_storage_binder AS asb
+SET account_id = a.id
+FROM accounts AS a
+WHERE a.username = asb.username;
+
+DELETE FROM account_storage_binder WHERE account_id IS NULL;
+
+ALTER TABLE account_storage_binder
+	DROP COLUMN username,
+	ALTER COLUMN account_id SET NOT NULL,
+	ADD CONSTRAINT account_storage_binder_account_id_storage_id_key UNIQUE (account_id, storage_id);
+
+ALTER TABLE account_shopping_list_binder
+	ADD COLUMN account_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+UPDATE account_shopping_list_binder AS aslb
+SET account_id = a.id
+FROM accounts AS a
+WHERE a.username = aslb.username;
+
+DELETE FROM account_shopping_list_binder WHERE account_id IS NULL;
+
+ALTER TABLE account_shopping_list_binder
+	DROP COLUMN username,
+	ALTER COLUMN account_id SET NOT NULL,
+	ADD CONSTRAINT account_shopping_list_binder_account_id_shopping_list_id_key UNIQUE (account_id, shopping_list_id);
+
+ALTER TABLE share_requests
+	ADD COLUMN from_account_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	ADD COLUMN to_account_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+UPDATE share_requests AS sr
+SET from_account_id = fa.id, to_account_id = ta.id
+FROM accounts AS fa, accounts AS ta
+WHERE fa.username = sr.from_username AND ta.username = sr.to_username;
+
+DELETE FROM share_requests WHERE from_account_id IS NULL OR to_account_id IS NULL;
+
+ALTER TABLE share_requests
+	DROP COLUMN from_username,
+	DROP COLUMN to_username,
+	ALTER COLUMN from_account_id SET NOT NULL,
+	ALTER COLUMN to_account_id SET NOT NULL;
+
+CREATE INDEX share_requests_from_account_id ON share_requests (from_account_id);
+
+CREATE INDEX share_requests_to_account_id ON share_requests (to_account_id);
+
+ALTER TABLE sync_tombstones
+	ADD COLUMN account_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+UPDATE sync_tombstones AS st
+SET account_id = a.id
+FROM accounts AS a
+WHERE a.username = st.username;
+
+DELETE FROM sync_tombstones WHERE account_id IS NULL;
+
+ALTER TABLE sync_tombstones
+	DROP COLUMN username,
+	ALTER COLUMN account_id SET NOT NULL;
+
+CREATE INDEX sync_tombstones_account_id_created_at ON sync_tombstones (account_id, created_at);
+
+ALTER TABLE pushed_mutations
+	ADD COLUMN account_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+UPDATE pushed_mutations AS pm
+SET account_id = a.id
+FROM accounts AS a
+WHERE a.username = pm.username;
+
+DELETE FROM pushed_mutations WHERE account_id IS NULL;
+
+ALTER TABLE pushed_mutations
+	DROP COLUMN username,
+	ALTER COLUMN account_id SET NOT NULL,
+	ADD CONSTRAINT pushed_mutations_account_id_client_id_key UNIQUE (account_id, client_id);
+
+-- The history outlives the accounts which made it, whose events are then made by nobody
+ALTER TABLE storage_item_events
+	ADD COLUMN actor_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+UPDATE storage_item_events AS sie
+SET actor_id = a.id
+FROM accounts AS a
+WHERE a.username = sie.actor;
+
+ALTER TABLE storage_item_events
+	DROP COLUMN actor;
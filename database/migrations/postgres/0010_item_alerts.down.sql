@@ -0,0 +1,3 @@
+DROP TABLE item_alert_deliveries;
+
+DROP TABLE item_alerts;
@@ -0,0 +1,4 @@
+ALTER TABLE accounts
+	DROP COLUMN unit_system;
+
+DROP TYPE unit_system;
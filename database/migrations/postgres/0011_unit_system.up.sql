@@ -0,0 +1,4 @@
+CREATE TYPE unit_system AS ENUM ('metric', 'imperial');
+
+ALTER TABLE accounts
+	ADD COLUMN unit_system unit_system NOT NULL DEFAULT 'metric';
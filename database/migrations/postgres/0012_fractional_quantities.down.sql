@@ -0,0 +1,7 @@
+-- Fractional quantities are rounded to whole ones.
+ALTER TABLE storage_items
+	ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity),
+	ALTER COLUMN quantity_threshold TYPE INTEGER USING ROUND(quantity_threshold);
+
+ALTER TABLE shopping_list_items
+	ALTER COLUMN quantity TYPE INTEGER USING ROUND(quantity);
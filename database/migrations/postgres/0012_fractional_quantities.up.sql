@@ -0,0 +1,6 @@
+ALTER TABLE storage_items
+	ALTER COLUMN quantity TYPE NUMERIC(12, 3),
+	ALTER COLUMN quantity_threshold TYPE NUMERIC(12, 3);
+
+ALTER TABLE shopping_list_items
+	ALTER COLUMN quantity TYPE NUMERIC(12, 3);
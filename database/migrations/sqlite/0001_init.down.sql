@@ -0,0 +1,10 @@
+DROP TABLE account_shopping_list_binder;
+DROP TABLE account_storage_binder;
+DROP TABLE foods;
+DROP TABLE settings;
+DROP TABLE shopping_list_items;
+DROP TABLE shopping_lists;
+DROP TABLE storage_items;
+DROP TABLE share_requests;
+DROP TABLE storages;
+DROP TABLE accounts;
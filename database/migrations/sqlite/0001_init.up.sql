@@ -0,0 +1,122 @@
+CREATE TABLE accounts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	password VARCHAR(128) NOT NULL,
+	salt VARCHAR(128) NOT NULL,
+	email VARCHAR(64) NOT NULL COLLATE NOCASE,
+	dark_theme INTEGER NOT NULL DEFAULT 1,
+	notifications INTEGER NOT NULL DEFAULT 0,
+	last_login TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT username UNIQUE (username),
+	CONSTRAINT email UNIQUE (email)
+);
+
+CREATE TRIGGER accounts_updated_at AFTER UPDATE ON accounts
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE accounts SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TABLE storages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE share_requests (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	from_username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	to_username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	share_type TEXT NOT NULL CONSTRAINT share_type CHECK (share_type IN ('storage', 'shopping_list')),
+	id_request INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE storage_items (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	title VARCHAR(50) NOT NULL COLLATE NOCASE,
+	image VARCHAR(512) NOT NULL DEFAULT '',
+	quantity INTEGER NOT NULL DEFAULT 0,
+	quantity_type TEXT NOT NULL DEFAULT 'pieces' CONSTRAINT quantity_type CHECK (quantity_type IN ('grams', 'kilos', 'pieces', 'liters', 'milliliters')),
+	quantity_threshold INTEGER NOT NULL DEFAULT 0,
+	expiration_threshold INTEGER NOT NULL DEFAULT 0,
+	expiration_date TIMESTAMP NULL DEFAULT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX FK_storage_items_storages ON storage_items (storage_id);
+
+CREATE TRIGGER storage_items_updated_at AFTER UPDATE ON storage_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE storage_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TABLE shopping_lists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER shopping_lists_updated_at AFTER UPDATE ON shopping_lists
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE shopping_lists SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TABLE shopping_list_items (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX FK_shopping_list_items_shopping_lists ON shopping_list_items (shopping_list_id);
+
+CREATE TRIGGER shopping_list_items_updated_at AFTER UPDATE ON shopping_list_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE shopping_list_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TABLE settings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE,
+	dark_theme INTEGER NOT NULL DEFAULT 1,
+	notifications INTEGER NOT NULL DEFAULT 0,
+	CONSTRAINT username UNIQUE (username)
+);
+
+CREATE TABLE foods (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(50) NOT NULL COLLATE NOCASE,
+	CONSTRAINT name UNIQUE (name)
+);
+
+CREATE TABLE account_storage_binder (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	CONSTRAINT username_storage_id UNIQUE (username, storage_id)
+);
+
+CREATE INDEX FK_account_storage_binder_storages ON account_storage_binder (storage_id);
+
+CREATE TABLE account_shopping_list_binder (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	CONSTRAINT username_shopping_list_id UNIQUE (username, shopping_list_id)
+);
+
+CREATE INDEX FK_account_shopping_list_binder_shopping_lists ON account_shopping_list_binder (shopping_list_id);
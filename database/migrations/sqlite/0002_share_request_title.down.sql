@@ -0,0 +1,16 @@
+-- SQLite cannot drop a column, so the table is rebuilt without it
+CREATE TABLE share_requests_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	from_username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	to_username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	share_type TEXT NOT NULL CONSTRAINT share_type CHECK (share_type IN ('storage', 'shopping_list')),
+	id_request INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO share_requests_old (id, from_username, to_username, share_type, id_request, created_at)
+SELECT id, from_username, to_username, share_type, id_request, created_at FROM share_requests;
+
+DROP TABLE share_requests;
+
+ALTER TABLE share_requests_old RENAME TO share_requests;
@@ -0,0 +1,24 @@
+-- SQLite cannot drop a column, so the table is rebuilt without it
+CREATE TABLE shopping_list_items_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO shopping_list_items_old (id, shopping_list_id, title, quantity, updated_at, created_at)
+SELECT id, shopping_list_id, title, quantity, updated_at, created_at FROM shopping_list_items;
+
+DROP TABLE shopping_list_items;
+
+ALTER TABLE shopping_list_items_old RENAME TO shopping_list_items;
+
+CREATE INDEX FK_shopping_list_items_shopping_lists ON shopping_list_items (shopping_list_id);
+
+CREATE TRIGGER shopping_list_items_updated_at AFTER UPDATE ON shopping_list_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE shopping_list_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
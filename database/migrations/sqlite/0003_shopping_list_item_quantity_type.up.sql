@@ -0,0 +1,2 @@
+ALTER TABLE shopping_list_items
+	ADD COLUMN quantity_type TEXT NOT NULL DEFAULT 'pieces' CONSTRAINT quantity_type CHECK (quantity_type IN ('grams', 'kilos', 'pieces', 'liters', 'milliliters'));
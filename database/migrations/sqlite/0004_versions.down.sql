@@ -0,0 +1,89 @@
+-- SQLite cannot drop a column, so the tables are rebuilt without it.
+-- Migrations run with foreign keys off, so dropping storages and shopping_lists does not cascade.
+CREATE TABLE storages_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO storages_old (id, title, updated_at, created_at)
+SELECT id, title, updated_at, created_at FROM storages;
+
+DROP TABLE storages;
+
+ALTER TABLE storages_old RENAME TO storages;
+
+CREATE TABLE storage_items_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	title VARCHAR(50) NOT NULL COLLATE NOCASE,
+	image VARCHAR(512) NOT NULL DEFAULT '',
+	quantity INTEGER NOT NULL DEFAULT 0,
+	quantity_type TEXT NOT NULL DEFAULT 'pieces' CONSTRAINT quantity_type CHECK (quantity_type IN ('grams', 'kilos', 'pieces', 'liters', 'milliliters')),
+	quantity_threshold INTEGER NOT NULL DEFAULT 0,
+	expiration_threshold INTEGER NOT NULL DEFAULT 0,
+	expiration_date TIMESTAMP NULL DEFAULT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO storage_items_old (id, storage_id, title, image, quantity, quantity_type, quantity_threshold, expiration_threshold, expiration_date, updated_at, created_at)
+SELECT id, storage_id, title, image, quantity, quantity_type, quantity_threshold, expiration_threshold, expiration_date, updated_at, created_at FROM storage_items;
+
+DROP TABLE storage_items;
+
+ALTER TABLE storage_items_old RENAME TO storage_items;
+
+CREATE INDEX FK_storage_items_storages ON storage_items (storage_id);
+
+CREATE TRIGGER storage_items_updated_at AFTER UPDATE ON storage_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE storage_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TABLE shopping_lists_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO shopping_lists_old (id, title, updated_at, created_at)
+SELECT id, title, updated_at, created_at FROM shopping_lists;
+
+DROP TABLE shopping_lists;
+
+ALTER TABLE shopping_lists_old RENAME TO shopping_lists;
+
+CREATE TRIGGER shopping_lists_updated_at AFTER UPDATE ON shopping_lists
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE shopping_lists SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TABLE shopping_list_items_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	quantity_type TEXT NOT NULL DEFAULT 'pieces' CONSTRAINT quantity_type CHECK (quantity_type IN ('grams', 'kilos', 'pieces', 'liters', 'milliliters'))
+);
+
+INSERT INTO shopping_list_items_old (id, shopping_list_id, title, quantity, updated_at, created_at, quantity_type)
+SELECT id, shopping_list_id, title, quantity, updated_at, created_at, quantity_type FROM shopping_list_items;
+
+DROP TABLE shopping_list_items;
+
+ALTER TABLE shopping_list_items_old RENAME TO shopping_list_items;
+
+CREATE INDEX FK_shopping_list_items_shopping_lists ON shopping_list_items (shopping_list_id);
+
+CREATE TRIGGER shopping_list_items_updated_at AFTER UPDATE ON shopping_list_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE shopping_list_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
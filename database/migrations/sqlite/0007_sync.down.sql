@@ -0,0 +1,40 @@
+DROP TABLE sync_purges;
+
+DROP TABLE sync_tombstones;
+
+-- SQLite cannot drop a column, so the binders are rebuilt without it, which drops their indexes too.
+CREATE TABLE account_storage_binder_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	CONSTRAINT username_storage_id UNIQUE (username, storage_id)
+);
+
+INSERT INTO account_storage_binder_old (id, username, storage_id, owner)
+SELECT id, username, storage_id, owner FROM account_storage_binder;
+
+DROP TABLE account_storage_binder;
+
+ALTER TABLE account_storage_binder_old RENAME TO account_storage_binder;
+
+CREATE INDEX FK_account_storage_binder_storages ON account_storage_binder (storage_id);
+
+CREATE TABLE account_shopping_list_binder_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	CONSTRAINT username_shopping_list_id UNIQUE (username, shopping_list_id)
+);
+
+INSERT INTO account_shopping_list_binder_old (id, username, shopping_list_id, owner)
+SELECT id, username, shopping_list_id, owner FROM account_shopping_list_binder;
+
+DROP TABLE account_shopping_list_binder;
+
+ALTER TABLE account_shopping_list_binder_old RENAME TO account_shopping_list_binder;
+
+CREATE INDEX FK_account_shopping_list_binder_shopping_lists ON account_shopping_list_binder (shopping_list_id);
+
+DROP TRIGGER storages_updated_at;
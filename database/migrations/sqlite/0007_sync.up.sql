@@ -0,0 +1,60 @@
+CREATE TRIGGER storages_updated_at AFTER UPDATE ON storages
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE storages SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+-- SQLite cannot add a column defaulting to CURRENT_TIMESTAMP, so the binders are rebuilt with it.
+-- Migrations run with foreign keys off, so the rebuild leaves the accounts and containers alone.
+CREATE TABLE account_storage_binder_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT username_storage_id UNIQUE (username, storage_id)
+);
+
+INSERT INTO account_storage_binder_new (id, username, storage_id, owner)
+SELECT id, username, storage_id, owner FROM account_storage_binder;
+
+DROP TABLE account_storage_binder;
+
+ALTER TABLE account_storage_binder_new RENAME TO account_storage_binder;
+
+CREATE INDEX FK_account_storage_binder_storages ON account_storage_binder (storage_id);
+
+CREATE TABLE account_shopping_list_binder_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT username_shopping_list_id UNIQUE (username, shopping_list_id)
+);
+
+INSERT INTO account_shopping_list_binder_new (id, username, shopping_list_id, owner)
+SELECT id, username, shopping_list_id, owner FROM account_shopping_list_binder;
+
+DROP TABLE account_shopping_list_binder;
+
+ALTER TABLE account_shopping_list_binder_new RENAME TO account_shopping_list_binder;
+
+CREATE INDEX FK_account_shopping_list_binder_shopping_lists ON account_shopping_list_binder (shopping_list_id);
+
+CREATE TABLE sync_tombstones (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	kind VARCHAR(32) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	reason VARCHAR(32) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX sync_tombstones_username_created_at ON sync_tombstones (username, created_at);
+CREATE INDEX sync_tombstones_created_at ON sync_tombstones (created_at);
+
+CREATE TABLE sync_purges (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	purged_before TIMESTAMP NOT NULL
+);
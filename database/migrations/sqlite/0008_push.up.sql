@@ -0,0 +1,64 @@
+ALTER TABLE storages
+	ADD COLUMN title_updated_at TIMESTAMP NULL DEFAULT NULL;
+
+ALTER TABLE storage_items
+	ADD COLUMN title_updated_at TIMESTAMP NULL DEFAULT NULL;
+
+ALTER TABLE shopping_lists
+	ADD COLUMN title_updated_at TIMESTAMP NULL DEFAULT NULL;
+
+ALTER TABLE shopping_list_items
+	ADD COLUMN title_updated_at TIMESTAMP NULL DEFAULT NULL;
+
+-- The updated_at triggers are dropped while the titles take their time from it
+DROP TRIGGER storages_updated_at;
+
+UPDATE storages SET title_updated_at = updated_at;
+
+CREATE TRIGGER storages_updated_at AFTER UPDATE ON storages
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE storages SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+DROP TRIGGER storage_items_updated_at;
+
+UPDATE storage_items SET title_updated_at = updated_at;
+
+CREATE TRIGGER storage_items_updated_at AFTER UPDATE ON storage_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE storage_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+DROP TRIGGER shopping_lists_updated_at;
+
+UPDATE shopping_lists SET title_updated_at = updated_at;
+
+CREATE TRIGGER shopping_lists_updated_at AFTER UPDATE ON shopping_lists
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE shopping_lists SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+DROP TRIGGER shopping_list_items_updated_at;
+
+UPDATE shopping_list_items SET title_updated_at = updated_at;
+
+CREATE TRIGGER shopping_list_items_updated_at AFTER UPDATE ON shopping_list_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE shopping_list_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TABLE pushed_mutations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	client_id VARCHAR(64) NOT NULL,
+	kind VARCHAR(32) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT username_client_id UNIQUE (username, client_id)
+);
+
+CREATE INDEX pushed_mutations_created_at ON pushed_mutations (created_at);
@@ -0,0 +1,141 @@
+-- The tables are rebuilt with their former username columns, which drops their indexes too
+CREATE TABLE storage_item_events_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	item_id INTEGER NOT NULL REFERENCES storage_items (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	actor VARCHAR(64) NOT NULL DEFAULT '',
+	field VARCHAR(32) NOT NULL,
+	old_value TEXT NULL DEFAULT NULL,
+	new_value TEXT NULL DEFAULT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO storage_item_events_old (id, item_id, actor, field, old_value, new_value, created_at)
+SELECT sie.id, sie.item_id, COALESCE(a.username, ''), sie.field, sie.old_value, sie.new_value, sie.created_at
+FROM storage_item_events AS sie
+LEFT JOIN accounts AS a ON a.id = sie.actor_id;
+
+DROP TABLE storage_item_events;
+
+ALTER TABLE storage_item_events_old RENAME TO storage_item_events;
+
+CREATE INDEX storage_item_events_item_id ON storage_item_events (item_id, id);
+
+CREATE TABLE pushed_mutations_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	client_id VARCHAR(64) NOT NULL,
+	kind VARCHAR(32) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT username_client_id UNIQUE (username, client_id)
+);
+
+INSERT INTO pushed_mutations_old (id, username, client_id, kind, entity_id, created_at)
+SELECT pm.id, a.username, pm.client_id, pm.kind, pm.entity_id, pm.created_at
+FROM pushed_mutations AS pm
+INNER JOIN accounts AS a ON a.id = pm.account_id;
+
+DROP TABLE pushed_mutations;
+
+ALTER TABLE pushed_mutations_old RENAME TO pushed_mutations;
+
+CREATE INDEX pushed_mutations_created_at ON pushed_mutations (created_at);
+
+CREATE TABLE sync_tombstones_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	kind VARCHAR(32) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	reason VARCHAR(32) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO sync_tombstones_old (id, username, kind, entity_id, reason, created_at)
+SELECT st.id, a.username, st.kind, st.entity_id, st.reason, st.created_at
+FROM sync_tombstones AS st
+INNER JOIN accounts AS a ON a.id = st.account_id;
+
+DROP TABLE sync_tombstones;
+
+ALTER TABLE sync_tombstones_old RENAME TO sync_tombstones;
+
+CREATE INDEX sync_tombstones_username_created_at ON sync_tombstones (username, created_at);
+
+CREATE INDEX sync_tombstones_created_at ON sync_tombstones (created_at);
+
+CREATE TABLE share_requests_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	from_username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	to_username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	share_type TEXT NOT NULL CONSTRAINT share_type CHECK (share_type IN ('storage', 'shopping_list')),
+	id_request INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE
+);
+
+INSERT INTO share_requests_old (id, from_username, to_username, share_type, id_request, created_at, title)
+SELECT sr.id, fa.username, ta.username, sr.share_type, sr.id_request, sr.created_at, sr.title
+FROM share_requests AS sr
+INNER JOIN accounts AS fa ON fa.id = sr.from_account_id
+INNER JOIN accounts AS ta ON ta.id = sr.to_account_id;
+
+DROP TABLE share_requests;
+
+ALTER TABLE share_requests_old RENAME TO share_requests;
+
+CREATE TABLE account_shopping_list_binder_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT username_shopping_list_id UNIQUE (username, shopping_list_id)
+);
+
+INSERT INTO account_shopping_list_binder_old (id, username, shopping_list_id, owner, created_at)
+SELECT aslb.id, a.username, aslb.shopping_list_id, aslb.owner, aslb.created_at
+FROM account_shopping_list_binder AS aslb
+INNER JOIN accounts AS a ON a.id = aslb.account_id;
+
+DROP TABLE account_shopping_list_binder;
+
+ALTER TABLE account_shopping_list_binder_old RENAME TO account_shopping_list_binder;
+
+CREATE INDEX FK_account_shopping_list_binder_shopping_lists ON account_shopping_list_binder (shopping_list_id);
+
+CREATE TABLE account_storage_binder_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT username_storage_id UNIQUE (username, storage_id)
+);
+
+INSERT INTO account_storage_binder_old (id, username, storage_id, owner, created_at)
+SELECT asb.id, a.username, asb.storage_id, asb.owner, asb.created_at
+FROM account_storage_binder AS asb
+INNER JOIN accounts AS a ON a.id = asb.account_id;
+
+DROP TABLE account_storage_binder;
+
+ALTER TABLE account_storage_binder_old RENAME TO account_storage_binder;
+
+CREATE INDEX FK_account_storage_binder_storages ON account_storage_binder (storage_id);
+
+CREATE TABLE settings_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE REFERENCES accounts (username) ON UPDATE CASCADE ON DELETE CASCADE,
+	dark_theme INTEGER NOT NULL DEFAULT 1,
+	notifications INTEGER NOT NULL DEFAULT 0,
+	CONSTRAINT username UNIQUE (username)
+);
+
+INSERT INTO settings_old (id, username, dark_theme, notifications)
+SELECT s.id, a.username, s.dark_theme, s.notifications
+FROM settings AS s
+INNER JOIN accounts AS a ON a.id = s.account_id;
+
+DROP TABLE settings;
+
+ALTER TABLE settings_old RENAME TO settings;
@@ -0,0 +1,148 @@
+-- Rows refer to accounts by ID, so that renaming an account only changes accounts.username.
+-- Rows naming an account which no longer exists cannot be kept and are left out.
+-- SQLite cannot alter a foreign key, so the tables are rebuilt, which drops their indexes too.
+CREATE TABLE settings_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	account_id INTEGER NOT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	dark_theme INTEGER NOT NULL DEFAULT 1,
+	notifications INTEGER NOT NULL DEFAULT 0,
+	CONSTRAINT account_id UNIQUE (account_id)
+);
+
+INSERT INTO settings_new (id, account_id, dark_theme, notifications)
+SELECT s.id, a.id, s.dark_theme, s.notifications
+FROM settings AS s
+INNER JOIN accounts AS a ON a.username = s.username;
+
+DROP TABLE settings;
+
+ALTER TABLE settings_new RENAME TO settings;
+
+CREATE TABLE account_storage_binder_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	account_id INTEGER NOT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE NO ACTION,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT account_id_storage_id UNIQUE (account_id, storage_id)
+);
+
+INSERT INTO account_storage_binder_new (id, account_id, storage_id, owner, created_at)
+SELECT asb.id, a.id, asb.storage_id, asb.owner, asb.created_at
+FROM account_storage_binder AS asb
+INNER JOIN accounts AS a ON a.username = asb.username;
+
+DROP TABLE account_storage_binder;
+
+ALTER TABLE account_storage_binder_new RENAME TO account_storage_binder;
+
+CREATE INDEX FK_account_storage_binder_storages ON account_storage_binder (storage_id);
+
+CREATE TABLE account_shopping_list_binder_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	account_id INTEGER NOT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	owner INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT account_id_shopping_list_id UNIQUE (account_id, shopping_list_id)
+);
+
+INSERT INTO account_shopping_list_binder_new (id, account_id, shopping_list_id, owner, created_at)
+SELECT aslb.id, a.id, aslb.shopping_list_id, aslb.owner, aslb.created_at
+FROM account_shopping_list_binder AS aslb
+INNER JOIN accounts AS a ON a.username = aslb.username;
+
+DROP TABLE account_shopping_list_binder;
+
+ALTER TABLE account_shopping_list_binder_new RENAME TO account_shopping_list_binder;
+
+CREATE INDEX FK_account_shopping_list_binder_shopping_lists ON account_shopping_list_binder (shopping_list_id);
+
+CREATE TABLE share_requests_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	from_account_id INTEGER NOT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	to_account_id INTEGER NOT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	share_type TEXT NOT NULL CONSTRAINT share_type CHECK (share_type IN ('storage', 'shopping_list')),
+	id_request INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE
+);
+
+INSERT INTO share_requests_new (id, from_account_id, to_account_id, share_type, id_request, created_at, title)
+SELECT sr.id, fa.id, ta.id, sr.share_type, sr.id_request, sr.created_at, sr.title
+FROM share_requests AS sr
+INNER JOIN accounts AS fa ON fa.username = sr.from_username
+INNER JOIN accounts AS ta ON ta.username = sr.to_username;
+
+DROP TABLE share_requests;
+
+ALTER TABLE share_requests_new RENAME TO share_requests;
+
+CREATE INDEX share_requests_from_account_id ON share_requests (from_account_id);
+
+CREATE INDEX share_requests_to_account_id ON share_requests (to_account_id);
+
+CREATE TABLE sync_tombstones_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	account_id INTEGER NOT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	kind VARCHAR(32) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	reason VARCHAR(32) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO sync_tombstones_new (id, account_id, kind, entity_id, reason, created_at)
+SELECT st.id, a.id, st.kind, st.entity_id, st.reason, st.created_at
+FROM sync_tombstones AS st
+INNER JOIN accounts AS a ON a.username = st.username;
+
+DROP TABLE sync_tombstones;
+
+ALTER TABLE sync_tombstones_new RENAME TO sync_tombstones;
+
+CREATE INDEX sync_tombstones_account_id_created_at ON sync_tombstones (account_id, created_at);
+
+CREATE INDEX sync_tombstones_created_at ON sync_tombstones (created_at);
+
+CREATE TABLE pushed_mutations_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	account_id INTEGER NOT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	client_id VARCHAR(64) NOT NULL,
+	kind VARCHAR(32) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT account_id_client_id UNIQUE (account_id, client_id)
+);
+
+INSERT INTO pushed_mutations_new (id, account_id, client_id, kind, entity_id, created_at)
+SELECT pm.id, a.id, pm.client_id, pm.kind, pm.entity_id, pm.created_at
+FROM pushed_mutations AS pm
+INNER JOIN accounts AS a ON a.username = pm.username;
+
+DROP TABLE pushed_mutations;
+
+ALTER TABLE pushed_mutations_new RENAME TO pushed_mutations;
+
+CREATE INDEX pushed_mutations_created_at ON pushed_mutations (created_at);
+
+-- The history outlives the accounts which made it, whose events are then made by nobody
+CREATE TABLE storage_item_events_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	item_id INTEGER NOT NULL REFERENCES storage_items (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	actor_id INTEGER NULL DEFAULT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE SET NULL,
+	field VARCHAR(32) NOT NULL,
+	old_value TEXT NULL DEFAULT NULL,
+	new_value TEXT NULL DEFAULT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO storage_item_events_new (id, item_id, actor_id, field, old_value, new_value, created_at)
+SELECT sie.id, sie.item_id, a.id, sie.field, sie.old_value, sie.new_value, sie.created_at
+FROM storage_item_events AS sie
+LEFT JOIN accounts AS a ON a.username = sie.actor;
+
+DROP TABLE storage_item_events;
+
+ALTER TABLE storage_item_events_new RENAME TO storage_item_events;
+
+CREATE INDEX storage_item_events_item_id ON storage_item_events (item_id, id);
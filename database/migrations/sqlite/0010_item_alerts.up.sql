@@ -0,0 +1,21 @@
+CREATE TABLE item_alerts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	item_id INTEGER NOT NULL REFERENCES storage_items (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	stage VARCHAR(32) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT item_id_stage UNIQUE (item_id, stage)
+);
+
+CREATE TABLE item_alert_deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	alert_id INTEGER NOT NULL REFERENCES item_alerts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	account_id INTEGER NOT NULL REFERENCES accounts (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	delivered_at TIMESTAMP NULL DEFAULT NULL,
+	dropped_at TIMESTAMP NULL DEFAULT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT alert_id_account_id UNIQUE (alert_id, account_id)
+);
+
+CREATE INDEX item_alert_deliveries_account_id ON item_alert_deliveries (account_id);
+
+CREATE INDEX item_alert_deliveries_delivered_at ON item_alert_deliveries (delivered_at, id);
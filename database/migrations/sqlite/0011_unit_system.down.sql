@@ -0,0 +1,29 @@
+-- SQLite cannot drop a column, so the table is rebuilt without it, which drops its trigger too.
+-- Migrations run with foreign keys off, so dropping accounts does not cascade.
+CREATE TABLE accounts_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL COLLATE NOCASE,
+	password VARCHAR(128) NOT NULL,
+	salt VARCHAR(128) NOT NULL,
+	email VARCHAR(64) NOT NULL COLLATE NOCASE,
+	dark_theme INTEGER NOT NULL DEFAULT 1,
+	notifications INTEGER NOT NULL DEFAULT 0,
+	last_login TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT username UNIQUE (username),
+	CONSTRAINT email UNIQUE (email)
+);
+
+INSERT INTO accounts_old (id, username, password, salt, email, dark_theme, notifications, last_login, updated_at, created_at)
+SELECT id, username, password, salt, email, dark_theme, notifications, last_login, updated_at, created_at FROM accounts;
+
+DROP TABLE accounts;
+
+ALTER TABLE accounts_old RENAME TO accounts;
+
+CREATE TRIGGER accounts_updated_at AFTER UPDATE ON accounts
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE accounts SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
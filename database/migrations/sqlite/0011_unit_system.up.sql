@@ -0,0 +1,2 @@
+ALTER TABLE accounts
+	ADD COLUMN unit_system TEXT NOT NULL DEFAULT 'metric' CONSTRAINT unit_system CHECK (unit_system IN ('metric', 'imperial'));
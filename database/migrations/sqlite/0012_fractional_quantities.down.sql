@@ -0,0 +1,67 @@
+-- SQLite cannot alter the type of a column, so the tables are rebuilt with INTEGER quantities, which drops their
+-- indexes and triggers too. Fractional quantities are rounded to whole ones.
+CREATE TABLE storage_items_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	storage_id INTEGER NOT NULL REFERENCES storages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+	title VARCHAR(50) NOT NULL COLLATE NOCASE,
+	image VARCHAR(512) NOT NULL DEFAULT '',
+	quantity INTEGER NOT NULL DEFAULT 0,
+	quantity_type TEXT NOT NULL DEFAULT 'pieces' CONSTRAINT quantity_type CHECK (quantity_type IN ('grams', 'kilos', 'pieces', 'liters', 'milliliters')),
+	quantity_threshold INTEGER NOT NULL DEFAULT 0,
+	expiration_threshold INTEGER NOT NULL DEFAULT 0,
+	expiration_date TIMESTAMP NULL DEFAULT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
+	deleted_at TIMESTAMP NULL DEFAULT NULL,
+	title_updated_at TIMESTAMP NULL DEFAULT NULL
+);
+
+INSERT INTO storage_items_new (id, storage_id, title, image, quantity, quantity_type, quantity_threshold, expiration_threshold, expiration_date, updated_at, created_at, version, deleted_at, title_updated_at)
+SELECT id, storage_id, title, image, CAST(ROUND(quantity) AS INTEGER), quantity_type, CAST(ROUND(quantity_threshold) AS INTEGER), expiration_threshold, expiration_date, updated_at, created_at, version, deleted_at, title_updated_at
+FROM storage_items;
+
+DROP TABLE storage_items;
+
+ALTER TABLE storage_items_new RENAME TO storage_items;
+
+CREATE INDEX FK_storage_items_storages ON storage_items (storage_id);
+
+CREATE INDEX storage_items_deleted_at ON storage_items (deleted_at);
+
+CREATE TRIGGER storage_items_updated_at AFTER UPDATE ON storage_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE storage_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TABLE shopping_list_items_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	shopping_list_id INTEGER NOT NULL REFERENCES shopping_lists (id) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	title VARCHAR(50) NOT NULL DEFAULT '' COLLATE NOCASE,
+	quantity INTEGER NOT NULL DEFAULT 1,
+	quantity_type TEXT NOT NULL DEFAULT 'pieces' CONSTRAINT quantity_type CHECK (quantity_type IN ('grams', 'kilos', 'pieces', 'liters', 'milliliters')),
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
+	deleted_at TIMESTAMP NULL DEFAULT NULL,
+	title_updated_at TIMESTAMP NULL DEFAULT NULL
+);
+
+INSERT INTO shopping_list_items_new (id, shopping_list_id, title, quantity, quantity_type, updated_at, created_at, version, deleted_at, title_updated_at)
+SELECT id, shopping_list_id, title, CAST(ROUND(quantity) AS INTEGER), quantity_type, updated_at, created_at, version, deleted_at, title_updated_at
+FROM shopping_list_items;
+
+DROP TABLE shopping_list_items;
+
+ALTER TABLE shopping_list_items_new RENAME TO shopping_list_items;
+
+CREATE INDEX FK_shopping_list_items_shopping_lists ON shopping_list_items (shopping_list_id);
+
+CREATE INDEX shopping_list_items_deleted_at ON shopping_list_items (deleted_at);
+
+CREATE TRIGGER shopping_list_items_updated_at AFTER UPDATE ON shopping_list_items
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE shopping_list_items SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
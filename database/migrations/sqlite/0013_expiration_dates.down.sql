@@ -0,0 +1,2 @@
+-- The dates as sent are not kept, UTC reads alike in the former schema
+SELECT 1;
@@ -0,0 +1,7 @@
+-- Expiration dates used to be stored as sent, e.g. 2030-01-02 or 2030-01-02T05:00:00+09:00, which SQLite compares
+-- and sorts as text. They are rewritten in UTC, in the layout the driver writes times in.
+UPDATE storage_items SET expiration_date = NULL WHERE expiration_date = '';
+
+UPDATE storage_items
+SET expiration_date = datetime(expiration_date) || '+00:00'
+WHERE expiration_date NOT LIKE '%+00:00' AND datetime(expiration_date) IS NOT NULL;
@@ -1,43 +1,55 @@
 package database
 
+import (
+	"context"
+	"strconv"
+)
+
 // Foods structure
 type Foods struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 }
 
-// GetFoods gets all food varieties from the foods database
-func (handler *Handler) GetFoods() ([]Foods, error) {
-	foods := []Foods{}
+// GetFoods gets a page of the food varieties of the foods database
+func (handler *Handler) GetFoods(ctx context.Context, options ListOptions) (FoodPage, error) {
+	page := FoodPage{Items: []Foods{}}
 
-	stmt, err := handler.DB.Prepare("SELECT * FROM foods")
+	order, err := parseSort(options.Sort, foodSorts)
 	if err != nil {
-		return foods, err
+		return page, err
 	}
 
-	defer stmt.Close()
+	filter := listFilter{}
+	if options.Q != "" {
+		filter.contains("f.name", options.Q)
+	}
 
-	rows, err := stmt.Query()
+	page.Total, err = handler.count(ctx, "foods AS f", filter)
 	if err != nil {
-		return foods, err
+		return page, err
 	}
 
-	for rows.Next() {
+	page.NextCursor, err = handler.page(ctx, listQuery{
+		columns: "f.id, f.name",
+		from:    "foods AS f",
+		table:   "foods",
+		alias:   "f",
+	}, filter, order, options, func(row scanner) (int, error) {
 		food := Foods{}
+		id := 0
 
-		if err := rows.Scan(
-			&food.ID,
+		if err := row.Scan(
+			&id,
 			&food.Name,
 		); err != nil {
-			return foods, err
+			return 0, err
 		}
 
-		foods = append(foods, food)
-	}
-
-	if err := rows.Err(); err != nil {
-		panic(err)
-	}
+		food.ID = strconv.Itoa(id)
+		page.Items = append(page.Items, food)
+		return id, nil
+	})
 
-	return foods, err
+	return page, err
 }
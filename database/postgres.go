@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq" // postgres driver
+)
+
+// postgresDialect is the dialect of PostgreSQL
+type postgresDialect struct{}
+
+func (postgresDialect) name() string {
+	return "postgres"
+}
+
+// rebind numbers the ? placeholders of query as $1, $2, ...
+func (postgresDialect) rebind(query string) string {
+	rebound := strings.Builder{}
+	n := 0
+
+	for _, r := range query {
+		if r != '?' {
+			rebound.WriteRune(r)
+			continue
+		}
+		n++
+		rebound.WriteString("$" + strconv.Itoa(n))
+	}
+
+	return rebound.String()
+}
+
+func (postgresDialect) returningID() bool {
+	return true
+}
+
+func (postgresDialect) execScript(ctx context.Context, tx *sql.Tx, script string) error {
+	_, err := tx.ExecContext(ctx, script)
+	return err
+}
+
+// translateError maps PostgreSQL constraint violations onto the Store errors
+func (postgresDialect) translateError(err error) error {
+	pqErr := &pq.Error{}
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code.Name() {
+	case "foreign_key_violation":
+		if strings.HasPrefix(pqErr.Message, "update or delete") {
+			return &Error{Kind: ErrConflict, Err: err}
+		}
+		return &Error{Kind: ErrForeignKey, Err: err}
+	case "unique_violation":
+		// Unique constraints are named <table>_<key>_key, the MySQL key name being <key>
+		key := strings.TrimPrefix(pqErr.Constraint, pqErr.Table+"_")
+		key = strings.TrimSuffix(key, "_key")
+		return errDuplicateKey(key, err)
+	case "invalid_text_representation":
+		// Enums are named after their column, e.g. invalid input value for enum quantity_type: "litres"
+		if strings.HasPrefix(pqErr.Message, "invalid input value for enum ") {
+			column := strings.TrimPrefix(pqErr.Message, "invalid input value for enum ")
+			column = column[:strings.Index(column, ":")]
+			return errDataTruncated(column, err)
+		}
+		return err
+	case "invalid_datetime_format":
+		// The message names the type, not the column, e.g. invalid input syntax for type timestamp with time zone: "soon"
+		return &Error{Kind: ErrValidation, Err: err}
+	default:
+		return err
+	}
+}
+
+// suspendForeignKeys does nothing, PostgreSQL migrations alter tables in place
+func (postgresDialect) suspendForeignKeys(ctx context.Context, conn *sql.Conn) (func(), error) {
+	return func() {}, nil
+}
+
+// checkForeignKeys does nothing, the keys are never suspended
+func (postgresDialect) checkForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+func (postgresDialect) forUpdate() string {
+	return " FOR UPDATE"
+}
+
+// binaryEquals compares with =, which is byte for byte under the deterministic default collation
+func (postgresDialect) binaryEquals(column string) string {
+	return column + " = ?"
+}
+
+// resetSequence moves the SERIAL sequence of table past its highest ID, inserting IDs does not draw from it
+func (postgresDialect) resetSequence(ctx context.Context, tx *sql.Tx, table string) error {
+	_, err := tx.ExecContext(ctx, `
+		SELECT setval(pg_get_serial_sequence($1, 'id'), COALESCE(MAX(id), 0) + 1, false)
+		FROM `+table, table)
+
+	return err
+}
+
+// InitPostgres returns a new Database handler backed by PostgreSQL.
+// ReadTimeout and WriteTimeout of options do not apply to PostgreSQL.
+func InitPostgres(username, password, name, host string, port int, options Options) *Handler {
+	query := url.Values{}
+
+	switch options.TLS {
+	case TLSRequire:
+		query.Set("sslmode", "require")
+	case TLSVerify:
+		query.Set("sslmode", "verify-full")
+	default:
+		query.Set("sslmode", "disable")
+	}
+
+	if options.ConnectTimeout > 0 {
+		// connect_timeout is in whole seconds, where 0 would mean none
+		seconds := int(options.ConnectTimeout.Round(time.Second) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		query.Set("connect_timeout", strconv.Itoa(seconds))
+	}
+
+	dbURI := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(username, password),
+		Host:     fmt.Sprintf("%s:%d", host, port),
+		Path:     name,
+		RawQuery: query.Encode(),
+	}
+
+	return open("postgres", dbURI.String(), postgresDialect{}, options)
+}
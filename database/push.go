@@ -0,0 +1,713 @@
+package database
+
+import (
+	"cat-clerk-api/units"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"unicode/utf8"
+)
+
+// Operations of a Mutation
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Statuses of a MutationResult
+const (
+	// MutationApplied marks a mutation written by the push
+	MutationApplied = "applied"
+	// MutationDuplicate marks a mutation already applied by an earlier push with the same client ID
+	MutationDuplicate = "duplicate"
+	// MutationRejected marks a mutation left out, the others of the push still apply
+	MutationRejected = "rejected"
+)
+
+// clientIDLength is the longest client ID of a Mutation, the size of pushed_mutations.client_id
+const clientIDLength = 64
+
+// titleLength is the longest title of a storage, shopping list or item
+const titleLength = 50
+
+// Mutation is a change made by a client while offline to a storage, storage item, shopping list
+// or shopping list item, pushed along with the others made since its last sync.
+type Mutation struct {
+	// ClientID identifies the mutation, retrying a push does not apply it twice
+	ClientID string `json:"clientID"`
+	Kind     string `json:"kind"`
+	Op       string `json:"op"`
+	// ID is the row updated or deleted, or TargetClientID the client ID of the mutation that created it
+	ID             int    `json:"id,omitempty"`
+	TargetClientID string `json:"targetClientID,omitempty"`
+	// ParentID is the storage or shopping list of an item, or ParentClientID the client ID of the mutation that created it
+	ParentID       int    `json:"parentID,omitempty"`
+	ParentClientID string `json:"parentClientID,omitempty"`
+	// Timestamp is when the client made the change, the time of the push when zero or in the future
+	Timestamp time.Time `json:"timestamp"`
+	// Title is left as is when nil, and kept when changed on the server after Timestamp
+	Title *string `json:"title,omitempty"`
+	// QuantityDelta is added to the quantity of an item, which does not go below 0. It is in QuantityType
+	// when set, of the dimension of the item, and in the quantity type of the item otherwise.
+	QuantityDelta float64 `json:"quantityDelta,omitempty"`
+	// The fields of a created item, QuantityType defaults to pieces
+	Quantity            float64 `json:"quantity,omitempty"`
+	QuantityType        string  `json:"quantityType,omitempty"`
+	QuantityThreshold   float64 `json:"quantityThreshold,omitempty"`
+	ExpirationThreshold int     `json:"expirationThreshold,omitempty"`
+	ExpirationDate      string  `json:"expirationDate,omitempty"`
+}
+
+// MutationResult is the outcome of a Mutation
+type MutationResult struct {
+	ClientID string `json:"clientID"`
+	Kind     string `json:"kind"`
+	Status   string `json:"status"`
+	// ID is the row the mutation applied to, assigned by the server for a create
+	ID int `json:"id,omitempty"`
+	// Kept lists the fields left as they were, changed on the server after the mutation was made
+	Kept []string `json:"kept,omitempty"`
+	// Err is why a mutation was rejected
+	Err error `json:"-"`
+}
+
+// PushResult holds the outcome of each mutation of a push, in order
+type PushResult struct {
+	Results []MutationResult `json:"results"`
+}
+
+// pushStore is the Store of a push transaction, with the bookkeeping of the pushed mutations
+type pushStore interface {
+	Store
+	// titleUpdatedAt returns when the title of the row id of kind last changed
+	titleUpdatedAt(ctx context.Context, kind string, id int) (time.Time, error)
+	setTitleUpdatedAt(ctx context.Context, kind string, id int, at time.Time) error
+	// pushedMutation returns the applied mutation of username with clientID, ok is false when there is none
+	pushedMutation(ctx context.Context, username, clientID string) (result MutationResult, ok bool, err error)
+	recordPushedMutation(ctx context.Context, username string, result MutationResult) error
+	// savepoint runs fn, undoing its writes when it rejects the mutation so that the transaction goes on
+	savepoint(ctx context.Context, fn func() error) error
+}
+
+// rejection marks the error of a mutation left out of a push, as opposed to the errors failing the whole push
+type rejection struct {
+	err error
+}
+
+func (r *rejection) Error() string {
+	return r.err.Error()
+}
+
+func (r *rejection) Unwrap() error {
+	return r.err
+}
+
+// reject rejects the mutation over a missing row, an invalid value or err itself
+func reject(err error) error {
+	return &rejection{err: err}
+}
+
+// pushChanges applies the mutations of username in order. store runs the writes and tx, the same
+// transaction, keeps the pushed mutations, so a Cache can pass itself as store.
+// A mutation is rejected when it refers to a missing or inaccessible row or holds an invalid value,
+// any other error fails the push.
+func pushChanges(ctx context.Context, store Store, tx pushStore, username string, mutations []Mutation) (PushResult, error) {
+	push := &push{
+		store:    store,
+		tx:       tx,
+		username: username,
+		now:      time.Now(),
+		results:  map[string]MutationResult{},
+	}
+	result := PushResult{Results: []MutationResult{}}
+
+	for _, mutation := range mutations {
+		mutationResult := MutationResult{ClientID: mutation.ClientID, Kind: mutation.Kind}
+
+		err := push.tx.savepoint(ctx, func() error {
+			return push.apply(ctx, mutation, &mutationResult)
+		})
+
+		rejected := &rejection{}
+		switch {
+		case errors.As(err, &rejected):
+			mutationResult.Status = MutationRejected
+			mutationResult.ID = 0
+			mutationResult.Kept = nil
+			mutationResult.Err = rejected.err
+		case err != nil:
+			return result, err
+		}
+
+		result.Results = append(result.Results, mutationResult)
+	}
+
+	return result, nil
+}
+
+// push is the state of a pushChanges
+type push struct {
+	store    Store
+	tx       pushStore
+	username string
+	now      time.Time
+	// results holds the applied mutations of the push by client ID
+	results map[string]MutationResult
+}
+
+// apply applies mutation and fills result
+func (p *push) apply(ctx context.Context, mutation Mutation, result *MutationResult) error {
+	if mutation.ClientID == "" || utf8.RuneCountInString(mutation.ClientID) > clientIDLength {
+		return reject(errDataTruncated("clientID", nil))
+	}
+
+	previous, ok := p.results[mutation.ClientID]
+	if !ok {
+		var err error
+		if previous, ok, err = p.tx.pushedMutation(ctx, p.username, mutation.ClientID); err != nil {
+			return err
+		}
+	}
+	if ok {
+		result.Kind = previous.Kind
+		result.Status = MutationDuplicate
+		result.ID = previous.ID
+		return nil
+	}
+
+	switch mutation.Kind {
+	case KindStorage, KindStorageItem, KindShoppingList, KindShoppingListItem:
+	default:
+		return reject(errDataTruncated("kind", nil))
+	}
+
+	if mutation.Title != nil && utf8.RuneCountInString(*mutation.Title) > titleLength {
+		return reject(errDataTruncated("title", nil))
+	}
+
+	at := mutation.Timestamp
+	if at.IsZero() || at.After(p.now) {
+		at = p.now
+	}
+
+	var err error
+	switch mutation.Op {
+	case OpCreate:
+		result.ID, err = p.create(ctx, mutation, at)
+	case OpUpdate:
+		result.ID, result.Kept, err = p.update(ctx, mutation, at)
+	case OpDelete:
+		result.ID, err = p.delete(ctx, mutation)
+	default:
+		err = reject(errDataTruncated("op", nil))
+	}
+	if err != nil {
+		return check(err)
+	}
+
+	result.Status = MutationApplied
+	p.results[mutation.ClientID] = *result
+
+	return p.tx.recordPushedMutation(ctx, p.username, *result)
+}
+
+// resolve returns id, or the ID of the row of kind created by the mutation with clientID when id is 0
+func (p *push) resolve(ctx context.Context, kind string, id int, clientID, field string) (int, error) {
+	if id != 0 || clientID == "" {
+		return id, nil
+	}
+
+	created, ok := p.results[clientID]
+	if !ok {
+		var err error
+		if created, ok, err = p.tx.pushedMutation(ctx, p.username, clientID); err != nil {
+			return 0, err
+		}
+	}
+	if !ok || created.Kind != kind {
+		return 0, reject(&Error{Kind: ErrNotFound, Field: field, Err: sql.ErrNoRows})
+	}
+
+	return created.ID, nil
+}
+
+// check rejects the mutation over a missing row or a value the store refuses, failing the push on other errors
+func check(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrValidation), errors.Is(err, ErrConflict), errors.Is(err, ErrForeignKey):
+		if errors.As(err, new(*rejection)) {
+			return err
+		}
+		return reject(err)
+	default:
+		return err
+	}
+}
+
+// mutationFields name the fields checked by ValidateItem as a Mutation does
+var mutationFields = map[string]string{
+	"title":                "title",
+	"quantity":             "quantity",
+	"quantity_type":        "quantityType",
+	"quantity_threshold":   "quantityThreshold",
+	"expiration_threshold": "expirationThreshold",
+	"expiration_date":      "expirationDate",
+}
+
+// validateItem runs ValidateItem on an item written by a push, rejecting the mutation over the field it names
+func validateItem(item Item) error {
+	err := ValidateItem(item)
+
+	dbErr := &Error{}
+	if errors.As(err, &dbErr) {
+		return reject(errDataTruncated(mutationFields[dbErr.Field], nil))
+	}
+	return err
+}
+
+// storage returns the storage id when it is attached to the account of the push
+func (p *push) storage(ctx context.Context, id int) (Folder, error) {
+	if _, err := p.store.GetStorageOwner(ctx, p.username, id); err != nil {
+		return Folder{}, check(err)
+	}
+
+	storage, err := p.store.GetStorage(ctx, id)
+	return storage, check(err)
+}
+
+// shoppingList returns the shopping list id when it is attached to the account of the push
+func (p *push) shoppingList(ctx context.Context, id int) (ShoppingList, error) {
+	if _, err := p.store.GetShoppingListOwner(ctx, p.username, id); err != nil {
+		return ShoppingList{}, check(err)
+	}
+
+	shoppingList, err := p.store.GetShoppingList(ctx, id)
+	return shoppingList, check(err)
+}
+
+// parent returns the storage or shopping list of the item created, updated or deleted by mutation
+func (p *push) parent(ctx context.Context, mutation Mutation) (int, error) {
+	parentKind := KindStorage
+	if mutation.Kind == KindShoppingListItem {
+		parentKind = KindShoppingList
+	}
+
+	parentID, err := p.resolve(ctx, parentKind, mutation.ParentID, mutation.ParentClientID, "parentClientID")
+	if err != nil {
+		return 0, err
+	}
+	if parentID == 0 {
+		return 0, reject(errDataTruncated("parentID", nil))
+	}
+
+	if parentKind == KindStorage {
+		_, err = p.storage(ctx, parentID)
+	} else {
+		_, err = p.shoppingList(ctx, parentID)
+	}
+
+	return parentID, err
+}
+
+// target returns the row updated or deleted by mutation
+func (p *push) target(ctx context.Context, mutation Mutation) (int, error) {
+	id, err := p.resolve(ctx, mutation.Kind, mutation.ID, mutation.TargetClientID, "targetClientID")
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		return 0, reject(errDataTruncated("id", nil))
+	}
+
+	return id, nil
+}
+
+// shoppingListItem returns the item id of shoppingListID
+func (p *push) shoppingListItem(ctx context.Context, shoppingListID, id int) (ShoppingListItem, error) {
+	item, err := p.store.GetShoppingListItem(ctx, id)
+	if err != nil {
+		return item, check(err)
+	}
+	if item.ShoppingListID != shoppingListID {
+		return item, reject(errNoRows)
+	}
+
+	return item, nil
+}
+
+// create creates the row of mutation and returns its ID
+func (p *push) create(ctx context.Context, mutation Mutation, at time.Time) (int, error) {
+	title := ""
+	if mutation.Title != nil {
+		title = *mutation.Title
+	}
+
+	quantityType := mutation.QuantityType
+	if quantityType == "" {
+		quantityType = "pieces"
+	}
+	if mutation.Kind == KindStorageItem || mutation.Kind == KindShoppingListItem {
+		if err := validateItem(Item{
+			Title:               title,
+			Quantity:            mutation.Quantity,
+			QuantityType:        quantityType,
+			QuantityThreshold:   mutation.QuantityThreshold,
+			ExpirationThreshold: mutation.ExpirationThreshold,
+			ExpirationDate:      mutation.ExpirationDate,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	parentID := 0
+	if mutation.Kind == KindStorageItem || mutation.Kind == KindShoppingListItem {
+		var err error
+		if parentID, err = p.parent(ctx, mutation); err != nil {
+			return 0, err
+		}
+	}
+
+	var id int64
+	var err error
+
+	switch mutation.Kind {
+	case KindStorage:
+		id, err = p.store.CreateStorage(ctx, p.username, title, true)
+	case KindShoppingList:
+		id, err = p.store.CreateShoppingList(ctx, p.username, title, true)
+	case KindStorageItem:
+		id, err = p.store.CreateStorageItem(ctx, parentID, title, mutation.Quantity, quantityType,
+			mutation.QuantityThreshold, mutation.ExpirationThreshold, mutation.ExpirationDate)
+	case KindShoppingListItem:
+		id, err = p.store.CreateShoppingListItem(ctx, parentID, title, mutation.Quantity, quantityType)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), p.tx.setTitleUpdatedAt(ctx, mutation.Kind, int(id), at)
+}
+
+// title returns the title to write for mutation: its own unless the title changed on the server after at
+func (p *push) title(ctx context.Context, mutation Mutation, id int, current string, at time.Time) (title string, won bool, err error) {
+	if mutation.Title == nil {
+		return current, false, nil
+	}
+
+	updatedAt, err := p.tx.titleUpdatedAt(ctx, mutation.Kind, id)
+	if err != nil {
+		return current, false, err
+	}
+	if updatedAt.After(at) {
+		return current, false, nil
+	}
+
+	return *mutation.Title, true, nil
+}
+
+// update applies mutation to its row: the title when the mutation is the last to change it,
+// and the quantity delta on top of the current quantity
+func (p *push) update(ctx context.Context, mutation Mutation, at time.Time) (int, []string, error) {
+	id, err := p.target(ctx, mutation)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if mutation.QuantityType != "" && !quantityTypes[mutation.QuantityType] {
+		return 0, nil, reject(errDataTruncated("quantityType", nil))
+	}
+
+	var current string
+	var write func(title string) error
+
+	switch mutation.Kind {
+	case KindStorage:
+		storage, err := p.storage(ctx, id)
+		if err != nil {
+			return 0, nil, err
+		}
+		current = storage.Title
+		write = func(title string) error {
+			return p.store.UpdateStorage(ctx, title, id, AnyVersion)
+		}
+	case KindShoppingList:
+		shoppingList, err := p.shoppingList(ctx, id)
+		if err != nil {
+			return 0, nil, err
+		}
+		current = shoppingList.Title
+		write = func(title string) error {
+			return p.store.UpdateShoppingListTitle(ctx, title, id, AnyVersion)
+		}
+	case KindStorageItem:
+		storageID, err := p.parent(ctx, mutation)
+		if err != nil {
+			return 0, nil, err
+		}
+		item, err := p.store.GetStorageItem(ctx, storageID, id)
+		if err != nil {
+			return 0, nil, check(err)
+		}
+		current = item.Title
+		write = func(title string) error {
+			quantity, quantityType, err := addQuantity(item.Quantity, item.QuantityType, mutation.QuantityDelta, mutation.QuantityType)
+			if err != nil {
+				return err
+			}
+
+			// The threshold follows the quantity into a smaller unit
+			threshold, _ := units.Convert(units.Quantity{Amount: item.QuantityThreshold, Unit: item.QuantityType}, quantityType)
+
+			updated := item
+			updated.Title, updated.Quantity, updated.QuantityType, updated.QuantityThreshold = title, quantity, quantityType, units.Round(threshold).Amount
+			if err := validateItem(updated); err != nil {
+				return err
+			}
+
+			return p.store.UpdateStorageItem(ctx, title, item.Image, quantity, quantityType, updated.QuantityThreshold,
+				item.ExpirationThreshold, item.ExpirationDate, id, AnyVersion)
+		}
+	case KindShoppingListItem:
+		shoppingListID, err := p.parent(ctx, mutation)
+		if err != nil {
+			return 0, nil, err
+		}
+		item, err := p.shoppingListItem(ctx, shoppingListID, id)
+		if err != nil {
+			return 0, nil, err
+		}
+		current = item.Title
+		write = func(title string) error {
+			quantity, quantityType, err := addQuantity(item.Quantity, item.QuantityType, mutation.QuantityDelta, mutation.QuantityType)
+			if err != nil {
+				return err
+			}
+
+			if err := validateItem(Item{Title: title, Quantity: quantity, QuantityType: quantityType}); err != nil {
+				return err
+			}
+
+			return p.store.UpdateShoppingListItem(ctx, title, quantity, quantityType, id, AnyVersion)
+		}
+	}
+
+	title, won, err := p.title(ctx, mutation, id, current, at)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	kept := []string(nil)
+	if mutation.Title != nil && !won {
+		kept = append(kept, "title")
+	}
+
+	if won || mutation.QuantityDelta != 0 {
+		if err := write(title); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if won {
+		if err := p.tx.setTitleUpdatedAt(ctx, mutation.Kind, id, at); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return id, kept, nil
+}
+
+// addQuantity adds delta of deltaType, or of quantityType when empty, to quantity of quantityType, stopping at 0.
+// It returns the sum along with its type, the smaller of the two when quantityType does not hold it, see units.Add.
+func addQuantity(quantity float64, quantityType string, delta float64, deltaType string) (float64, string, error) {
+	if deltaType == "" {
+		deltaType = quantityType
+	}
+
+	if ValidateQuantity("quantityDelta", delta, deltaType) != nil {
+		return 0, "", reject(errDataTruncated("quantityDelta", nil))
+	}
+
+	sum, err := units.Add(
+		units.Quantity{Amount: quantity, Unit: quantityType},
+		units.Quantity{Amount: delta, Unit: deltaType},
+	)
+	if err != nil {
+		return 0, "", reject(errDataTruncated("quantityType", err))
+	}
+
+	if sum.Amount < 0 {
+		return 0, quantityType, nil
+	}
+
+	return sum.Amount, sum.Unit, nil
+}
+
+// delete moves the row of mutation to the trash
+func (p *push) delete(ctx context.Context, mutation Mutation) (int, error) {
+	id, err := p.target(ctx, mutation)
+	if err != nil {
+		return 0, err
+	}
+
+	switch mutation.Kind {
+	case KindStorage:
+		if _, err := p.storage(ctx, id); err != nil {
+			return 0, err
+		}
+		return id, p.store.DeleteStorage(ctx, id, AnyVersion)
+	case KindShoppingList:
+		if _, err := p.shoppingList(ctx, id); err != nil {
+			return 0, err
+		}
+		return id, p.store.DeleteShoppingList(ctx, id, AnyVersion)
+	}
+
+	parentID, err := p.parent(ctx, mutation)
+	if err != nil {
+		return 0, err
+	}
+
+	if mutation.Kind == KindStorageItem {
+		if _, err := p.store.GetStorageItem(ctx, parentID, id); err != nil {
+			return 0, check(err)
+		}
+		return id, p.store.DeleteStorageItem(ctx, id, AnyVersion)
+	}
+
+	if _, err := p.shoppingListItem(ctx, parentID, id); err != nil {
+		return 0, err
+	}
+	return id, p.store.DeleteShoppingListItem(ctx, id, AnyVersion)
+}
+
+// PushChanges applies the mutations pushed by a client of username in one transaction
+func (handler *Handler) PushChanges(ctx context.Context, username string, mutations []Mutation) (PushResult, error) {
+	result := PushResult{}
+
+	err := handler.withTx(ctx, func(tx *Handler) error {
+		var err error
+		result, err = pushChanges(ctx, tx, tx, username, mutations)
+		return err
+	})
+
+	return result, err
+}
+
+// pushTables are the tables holding the title of each kind of Mutation
+var pushTables = map[string]string{
+	KindStorage:          "storages",
+	KindStorageItem:      "storage_items",
+	KindShoppingList:     "shopping_lists",
+	KindShoppingListItem: "shopping_list_items",
+}
+
+func (handler *Handler) titleUpdatedAt(ctx context.Context, kind string, id int) (time.Time, error) {
+	stmt, err := handler.prepare(ctx, `
+		SELECT title_updated_at, created_at
+		FROM `+pushTables[kind]+`
+		WHERE id = ?
+	`)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	defer stmt.Close()
+
+	// Rows older than the column take the time they were created
+	titleUpdatedAt := sql.NullTime{}
+	createdAt := time.Time{}
+
+	if err := stmt.QueryRowContext(ctx, id).Scan(
+		&titleUpdatedAt,
+		&createdAt,
+	); err != nil {
+		return time.Time{}, rowError(err)
+	}
+
+	if titleUpdatedAt.Valid {
+		return titleUpdatedAt.Time, nil
+	}
+	return createdAt, nil
+}
+
+func (handler *Handler) setTitleUpdatedAt(ctx context.Context, kind string, id int, at time.Time) error {
+	_, err := handler.run(ctx, `
+		UPDATE `+pushTables[kind]+`
+		SET title_updated_at = ?
+		WHERE id = ?
+	`, at.UTC(), id)
+
+	return err
+}
+
+// savepoint runs fn after a savepoint, rolled back to when fn rejects the mutation: a failed statement
+// aborts a PostgreSQL transaction up to its last savepoint
+func (handler *Handler) savepoint(ctx context.Context, fn func() error) error {
+	if handler.tx == nil {
+		return fn()
+	}
+
+	if _, err := handler.tx.ExecContext(ctx, "SAVEPOINT push_mutation"); err != nil {
+		return err
+	}
+
+	err := fn()
+	if err != nil && !errors.As(err, new(*rejection)) {
+		return err
+	}
+
+	if err != nil {
+		if _, rollbackErr := handler.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT push_mutation"); rollbackErr != nil {
+			return rollbackErr
+		}
+	}
+
+	if _, releaseErr := handler.tx.ExecContext(ctx, "RELEASE SAVEPOINT push_mutation"); releaseErr != nil {
+		return releaseErr
+	}
+
+	return err
+}
+
+func (handler *Handler) pushedMutation(ctx context.Context, username, clientID string) (MutationResult, bool, error) {
+	result := MutationResult{ClientID: clientID, Status: MutationApplied}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT kind, entity_id
+		FROM pushed_mutations
+		WHERE account_id = `+accountIDOf+` AND client_id = ?
+	`)
+	if err != nil {
+		return result, false, err
+	}
+
+	defer stmt.Close()
+
+	if err := stmt.QueryRowContext(ctx, username, clientID).Scan(
+		&result.Kind,
+		&result.ID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, false, nil
+		}
+		return result, false, err
+	}
+
+	return result, true, nil
+}
+
+func (handler *Handler) recordPushedMutation(ctx context.Context, username string, result MutationResult) error {
+	accountID, err := handler.accountID(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	_, err = handler.run(ctx, `
+		INSERT INTO pushed_mutations(account_id, client_id, kind, entity_id)
+		VALUES(?, ?, ?, ?)
+	`, accountID, result.ClientID, result.Kind, result.ID)
+
+	return err
+}
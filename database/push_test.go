@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushChangesConflicts(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	// justBefore stands for the time right before the server rename
+	justBefore := time.Unix(1, 0)
+	title := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		// retitle renames the item on the server before the push, when set
+		retitle string
+		// mutations update the item unless they say otherwise, their client IDs default to their index.
+		// Those stamped with justBefore are made right before the server rename.
+		mutations []Mutation
+		// want holds the status of each mutation, followed by the fields it kept if any
+		want             []string
+		wantTitle        string
+		wantQuantity     float64
+		wantQuantityType string
+		wantThreshold    float64
+	}{
+		{
+			name:      "title written",
+			mutations: []Mutation{{Title: title("Oat milk")}},
+			want:      []string{MutationApplied},
+			wantTitle: "Oat milk", wantQuantity: 2, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "title kept when renamed on the server since",
+			retitle:   "Whole milk",
+			mutations: []Mutation{{Title: title("Oat milk"), Timestamp: past}},
+			want:      []string{MutationApplied + " title"},
+			wantTitle: "Whole milk", wantQuantity: 2, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "title written when renamed on the server before",
+			retitle:   "Whole milk",
+			mutations: []Mutation{{Title: title("Oat milk")}},
+			want:      []string{MutationApplied},
+			wantTitle: "Oat milk", wantQuantity: 2, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "title kept when its case changed on the server since",
+			retitle:   "MILK",
+			mutations: []Mutation{{Title: title("Oat milk"), Timestamp: justBefore}},
+			want:      []string{MutationApplied + " title"},
+			wantTitle: "MILK", wantQuantity: 2, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "title kept when renamed on the server within the same second",
+			retitle:   "Whole milk",
+			mutations: []Mutation{{Title: title("Oat milk"), Timestamp: justBefore}},
+			want:      []string{MutationApplied + " title"},
+			wantTitle: "Whole milk", wantQuantity: 2, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "deltas add up",
+			mutations: []Mutation{{QuantityDelta: 1}, {QuantityDelta: -0.5}},
+			want:      []string{MutationApplied, MutationApplied},
+			wantTitle: "Milk", wantQuantity: 2.5, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "delta kept along with the server title",
+			retitle:   "Whole milk",
+			mutations: []Mutation{{Title: title("Oat milk"), Timestamp: past, QuantityDelta: 1}},
+			want:      []string{MutationApplied + " title"},
+			wantTitle: "Whole milk", wantQuantity: 3, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "delta in another unit",
+			mutations: []Mutation{{QuantityDelta: 500, QuantityType: "grams"}},
+			want:      []string{MutationApplied},
+			wantTitle: "Milk", wantQuantity: 2.5, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "delta beyond the precision of the item's unit",
+			mutations: []Mutation{{QuantityDelta: 0.5, QuantityType: "grams"}},
+			want:      []string{MutationApplied},
+			wantTitle: "Milk", wantQuantity: 2000.5, wantQuantityType: "grams", wantThreshold: 500,
+		},
+		{
+			name:      "quantity stops at 0",
+			mutations: []Mutation{{QuantityDelta: -5}},
+			want:      []string{MutationApplied},
+			wantTitle: "Milk", wantQuantity: 0, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "delta of another dimension",
+			mutations: []Mutation{{QuantityDelta: 1, QuantityType: "liters"}, {QuantityDelta: 1}},
+			want:      []string{MutationRejected, MutationApplied},
+			wantTitle: "Milk", wantQuantity: 3, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name:      "retried client ID",
+			mutations: []Mutation{{ClientID: "a", QuantityDelta: 1}, {ClientID: "a", QuantityDelta: 1}},
+			want:      []string{MutationApplied, MutationDuplicate},
+			wantTitle: "Milk", wantQuantity: 3, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+		{
+			name: "invalid create",
+			mutations: []Mutation{
+				{Op: OpCreate, Title: title("Jam"), Quantity: 1, ExpirationDate: "soon"},
+				{Op: OpCreate, Title: title("Tea"), Quantity: 1, ExpirationThreshold: -1},
+				{QuantityDelta: 1},
+			},
+			want:      []string{MutationRejected, MutationRejected, MutationApplied},
+			wantTitle: "Milk", wantQuantity: 3, wantQuantityType: "kilos", wantThreshold: 0.5,
+		},
+	}
+
+	for _, test := range tests {
+		for _, s := range testStores(t) {
+			t.Run(test.name+"/"+s.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				if _, err := s.store.CreateAccount(ctx, "ann", "ann@example.com", "password", "salt"); err != nil {
+					t.Fatal(err)
+				}
+				storageID, err := s.store.CreateStorage(ctx, "ann", "Fridge", true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				itemID, err := s.store.CreateStorageItem(ctx, int(storageID), "Milk", 2, "kilos", 0.5, 0, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Right before the rename, yet within its second
+				renamedAfter := time.Now()
+				time.Sleep(time.Millisecond)
+
+				if test.retitle != "" {
+					if err := s.store.UpdateStorageItem(ctx, test.retitle, "", 2, "kilos", 0.5, 0, "", int(itemID), AnyVersion); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				mutations := []Mutation{}
+				for i, mutation := range test.mutations {
+					if mutation.ClientID == "" {
+						mutation.ClientID = fmt.Sprint(i)
+					}
+					if mutation.Op == "" {
+						mutation.Op, mutation.ID = OpUpdate, int(itemID)
+					}
+					if mutation.Timestamp.Equal(justBefore) {
+						mutation.Timestamp = renamedAfter
+					}
+					mutation.Kind, mutation.ParentID = KindStorageItem, int(storageID)
+					mutations = append(mutations, mutation)
+				}
+
+				result, err := s.store.PushChanges(ctx, "ann", mutations)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := []string{}
+				for _, mutationResult := range result.Results {
+					got = append(got, strings.TrimSpace(mutationResult.Status+" "+strings.Join(mutationResult.Kept, " ")))
+				}
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("results = %v, want %v", got, test.want)
+				}
+
+				item, err := s.store.GetStorageItem(ctx, int(storageID), int(itemID))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if item.Title != test.wantTitle {
+					t.Errorf("title = %q, want %q", item.Title, test.wantTitle)
+				}
+				if item.Quantity != test.wantQuantity || item.QuantityType != test.wantQuantityType {
+					t.Errorf("quantity = %v %s, want %v %s", item.Quantity, item.QuantityType, test.wantQuantity, test.wantQuantityType)
+				}
+				if item.QuantityThreshold != test.wantThreshold {
+					t.Errorf("quantity threshold = %v, want %v", item.QuantityThreshold, test.wantThreshold)
+				}
+			})
+		}
+	}
+}
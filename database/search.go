@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Kinds of SearchHit, SearchParent and Tombstone
+const (
+	KindStorage          = "storage"
+	KindStorageItem      = "storage_item"
+	KindShoppingList     = "shopping_list"
+	KindShoppingListItem = "shopping_list_item"
+	KindShareRequest     = "share_request"
+)
+
+// SearchHit is a storage, storage item or shopping list item matching a search
+type SearchHit struct {
+	Kind  string `json:"kind"`
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// Score ranks the hit, from 1 for exact matches down to the typos allowed
+	Score float64 `json:"score"`
+	// Parent is the storage or shopping list of an item, nil for storages
+	Parent *SearchParent `json:"parent,omitempty"`
+}
+
+// SearchParent is the container of an item found by a search
+type SearchParent struct {
+	Kind  string `json:"kind"`
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// SearchResults holds the best hits of a search, best first
+type SearchResults struct {
+	Hits []SearchHit `json:"hits"`
+}
+
+// searchWords splits s into lower case words of letters and digits
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// allowedTypos is the edit distance tolerated between a search term and a word, by term length
+func allowedTypos(term []rune) int {
+	switch {
+	case len(term) >= 8:
+		return 2
+	case len(term) >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance is the number of insertions, deletions, substitutions and transpositions of adjacent runes turning a into b
+func editDistance(a, b []rune) int {
+	// Three rows of the matrix are enough: transpositions look two rows back
+	before, previous, current := make([]int, len(b)+1), make([]int, len(b)+1), make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = minInt(current[j], before[j-2]+1)
+			}
+		}
+		before, previous, current = previous, current, before
+	}
+
+	return previous[len(b)]
+}
+
+// minInt returns the smallest of values
+func minInt(values ...int) int {
+	smallest := values[0]
+	for _, value := range values[1:] {
+		if value < smallest {
+			smallest = value
+		}
+	}
+	return smallest
+}
+
+// termScore scores how well term matches the best of words: 1 for the same word, less for a prefix,
+// a part of a word or a word within the allowed typos, 0 for no match
+func termScore(term string, words []string) float64 {
+	runes := []rune(term)
+	typos := allowedTypos(runes)
+
+	best := 0.0
+	for _, word := range words {
+		score := 0.0
+		switch {
+		case word == term:
+			score = 1
+		case strings.HasPrefix(word, term):
+			score = 0.9
+		case len(runes) >= 3 && strings.Contains(word, term):
+			score = 0.75
+		default:
+			if distance := editDistance(runes, []rune(word)); distance <= typos {
+				score = 0.8 - 0.15*float64(distance)
+			}
+		}
+
+		if score > best {
+			best = score
+		}
+	}
+
+	return best
+}
+
+// rankHits scores candidates against q and returns the limit best, best first.
+// Every word of q has to match a word of the title of a hit.
+func rankHits(candidates []SearchHit, q string, limit int) []SearchHit {
+	hits := []SearchHit{}
+
+	terms := searchWords(q)
+	if len(terms) == 0 {
+		return hits
+	}
+
+	// words holds the title words of each hit
+	words := [][]string{}
+
+	for _, hit := range candidates {
+		titleWords := searchWords(hit.Title)
+
+		total := 0.0
+		for _, term := range terms {
+			score := termScore(term, titleWords)
+			if score == 0 {
+				total = 0
+				break
+			}
+			total += score
+		}
+		if total == 0 {
+			continue
+		}
+
+		hit.Score = math.Round(total/float64(len(terms))*100) / 100
+		hits = append(hits, hit)
+		words = append(words, titleWords)
+	}
+
+	// Equal scores favor titles with fewer other words, "Rice" before "Rice flour"
+	order := make([]int, len(hits))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := hits[order[i]], hits[order[j]]
+		switch {
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case len(words[order[i]]) != len(words[order[j]]):
+			return len(words[order[i]]) < len(words[order[j]])
+		case a.Kind != b.Kind:
+			return a.Kind < b.Kind
+		default:
+			return a.ID < b.ID
+		}
+	})
+
+	limit = pageLimit(limit)
+	ranked := []SearchHit{}
+	for _, i := range order {
+		if len(ranked) == limit {
+			break
+		}
+		ranked = append(ranked, hits[i])
+	}
+
+	return ranked
+}
+
+// Search finds the storages, storage items and shopping list items attached to username whose title
+// matches q, tolerating typos, and returns the limit best hits
+func (handler *Handler) Search(ctx context.Context, username, q string, limit int) (SearchResults, error) {
+	results := SearchResults{Hits: []SearchHit{}}
+	candidates := []SearchHit{}
+
+	storages, err := handler.searchCandidates(ctx, KindStorage, "", `
+		SELECT s.id, s.title, 0, ''
+		FROM storages AS s
+		INNER JOIN account_storage_binder AS asb
+		ON asb.storage_id = s.id AND asb.account_id = `+accountIDOf+`
+		WHERE s.deleted_at IS NULL
+	`, username)
+	if err != nil {
+		return results, err
+	}
+	candidates = append(candidates, storages...)
+
+	storageItems, err := handler.searchCandidates(ctx, KindStorageItem, KindStorage, `
+		SELECT si.id, si.title, s.id, s.title
+		FROM storage_items AS si
+		INNER JOIN storages AS s
+		ON s.id = si.storage_id AND s.deleted_at IS NULL
+		INNER JOIN account_storage_binder AS asb
+		ON asb.storage_id = s.id AND asb.account_id = `+accountIDOf+`
+		WHERE si.deleted_at IS NULL
+	`, username)
+	if err != nil {
+		return results, err
+	}
+	candidates = append(candidates, storageItems...)
+
+	shoppingListItems, err := handler.searchCandidates(ctx, KindShoppingListItem, KindShoppingList, `
+		SELECT sli.id, sli.title, sl.id, sl.title
+		FROM shopping_list_items AS sli
+		INNER JOIN shopping_lists AS sl
+		ON sl.id = sli.shopping_list_id AND sl.deleted_at IS NULL
+		INNER JOIN account_shopping_list_binder AS aslb
+		ON aslb.shopping_list_id = sl.id AND aslb.account_id = `+accountIDOf+`
+		WHERE sli.deleted_at IS NULL
+	`, username)
+	if err != nil {
+		return results, err
+	}
+	candidates = append(candidates, shoppingListItems...)
+
+	results.Hits = rankHits(candidates, q, limit)
+	return results, nil
+}
+
+// searchCandidates reads the hits of kind selected by query as id, title, parent id and parent title.
+// The hits have no parent when parentKind is empty.
+func (handler *Handler) searchCandidates(ctx context.Context, kind, parentKind, query string, args ...interface{}) ([]SearchHit, error) {
+	hits := []SearchHit{}
+
+	stmt, err := handler.prepare(ctx, query)
+	if err != nil {
+		return hits, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return hits, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		hit := SearchHit{Kind: kind}
+		parent := SearchParent{Kind: parentKind}
+
+		if err := rows.Scan(
+			&hit.ID,
+			&hit.Title,
+			&parent.ID,
+			&parent.Title,
+		); err != nil {
+			return hits, err
+		}
+
+		if parentKind != "" {
+			hit.Parent = &parent
+		}
+
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
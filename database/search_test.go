@@ -0,0 +1,37 @@
+package database
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{name: "same", a: "milk", b: "milk", want: 0},
+		{name: "both empty", a: "", b: "", want: 0},
+		{name: "from empty", a: "", b: "rice", want: 4},
+		{name: "to empty", a: "rice", b: "", want: 4},
+		{name: "substitution", a: "milk", b: "silk", want: 1},
+		{name: "insertion", a: "tea", b: "team", want: 1},
+		{name: "deletion", a: "butter", b: "buter", want: 1},
+		{name: "transposition", a: "bread", b: "braed", want: 1},
+		{name: "transposition at the end", a: "eggs", b: "egsg", want: 1},
+		{name: "two typos", a: "tomatoes", b: "tonatos", want: 2},
+		{name: "transposition and substitution", a: "yogurt", b: "yougrd", want: 2},
+		{name: "unrelated", a: "salt", b: "jam", want: 3},
+		{name: "runes", a: "café", b: "cafe", want: 1},
+		{name: "runes swapped", a: "crème", b: "crèem", want: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := editDistance([]rune(test.a), []rune(test.b)); got != test.want {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+			}
+			if got := editDistance([]rune(test.b), []rune(test.a)); got != test.want {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", test.b, test.a, got, test.want)
+			}
+		})
+	}
+}
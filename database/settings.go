@@ -1,7 +1,8 @@
 package database
 
 import (
-	"fmt"
+	"cat-clerk-api/units"
+	"context"
 )
 
 // Settings structure
@@ -9,44 +10,105 @@ type Settings struct {
 	Notifications bool `json:"notifications"`
 }
 
+// UnitSettings holds the system, metric or imperial, quantities are displayed in to an account
+type UnitSettings struct {
+	UnitSystem string `json:"unitSystem"`
+}
+
 // GetNotificationSetting gets the account's notification setting preference from the database by username
-func (handler *Handler) GetNotificationSetting(username string) (Settings, error) {
+func (handler *Handler) GetNotificationSetting(ctx context.Context, username string) (Settings, error) {
 	response := Settings{}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+	stmt, err := handler.prepare(ctx, `
 		SELECT notifications FROM accounts
-		WHERE username="%s"	
-	`, username))
+		WHERE username = ?
+	`)
 	if err != nil {
 		return response, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
+	if err := stmt.QueryRowContext(ctx, username).Scan(
 		&response.Notifications,
 	); err != nil {
-		return response, err
+		return response, rowError(err)
 	}
 
 	return response, err
 }
 
 // ToggleNotificationSetting toggles the notification setting by username
-func (handler *Handler) ToggleNotificationSetting(username string) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+func (handler *Handler) ToggleNotificationSetting(ctx context.Context, username string) error {
+	stmt, err := handler.prepare(ctx, `
+		UPDATE accounts
+		SET notifications = NOT notifications
+		WHERE username = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	result, err := handler.exec(ctx, stmt, username)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected < 1 {
+		return errNoRowsAffected
+	}
+
+	return err
+}
+
+// GetUnitSetting gets the account's unit system preference by username
+func (handler *Handler) GetUnitSetting(ctx context.Context, username string) (UnitSettings, error) {
+	response := UnitSettings{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT unit_system FROM accounts
+		WHERE username = ?
+	`)
+	if err != nil {
+		return response, err
+	}
+
+	defer stmt.Close()
+
+	if err := stmt.QueryRowContext(ctx, username).Scan(
+		&response.UnitSystem,
+	); err != nil {
+		return response, rowError(err)
+	}
+
+	return response, err
+}
+
+// UpdateUnitSetting sets the unit system preference by username, metric or imperial
+func (handler *Handler) UpdateUnitSetting(ctx context.Context, username, unitSystem string) error {
+	if !units.KnownSystem(unitSystem) {
+		return errDataTruncated("unit_system", nil)
+	}
+
+	stmt, err := handler.prepare(ctx, `
 		UPDATE accounts
-		SET 
-			notifications = !notifications
-		WHERE username = "%s";
-	`, username))
+		SET unit_system = ?
+		WHERE username = ?
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, unitSystem, username)
 	if err != nil {
 		return err
 	}
@@ -57,7 +119,7 @@ func (handler *Handler) ToggleNotificationSetting(username string) error {
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return errNoRowsAffected
 	}
 
 	return err
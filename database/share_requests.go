@@ -1,7 +1,7 @@
 package database
 
 import (
-	"fmt"
+	"context"
 	"time"
 )
 
@@ -16,19 +16,41 @@ type ShareRequest struct {
 	CreatedAt    time.Time `json:"createdAt"`
 }
 
+// shareRequestColumns lists the share_requests columns, with the usernames of its accounts from shareRequestTables
+const shareRequestColumns = `sr.id, fa.username, ta.username, sr.share_type, sr.title, sr.id_request, sr.created_at`
+
+// shareRequestTables joins share_requests to the accounts sending and receiving them
+const shareRequestTables = `
+	share_requests AS sr
+	INNER JOIN accounts AS fa
+	ON fa.id = sr.from_account_id
+	INNER JOIN accounts AS ta
+	ON ta.id = sr.to_account_id
+`
+
 // CreateShareRequest creates a share request in the database
-func (handler *Handler) CreateShareRequest(fromUsername, toUsername, shareType, title string, idRequest int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		INSERT INTO share_requests(from_username, to_username, share_type, title, id_request)
-		VALUES("%s", "%s", "%s", "%s", %d)
-	`, fromUsername, toUsername, shareType, title, idRequest))
+func (handler *Handler) CreateShareRequest(ctx context.Context, fromUsername, toUsername, shareType, title string, idRequest int) error {
+	fromAccountID, err := handler.accountID(ctx, fromUsername)
+	if err != nil {
+		return err
+	}
+
+	toAccountID, err := handler.accountID(ctx, toUsername)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		INSERT INTO share_requests(from_account_id, to_account_id, share_type, title, id_request)
+		VALUES(?, ?, ?, ?, ?)
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec()
+	_, err = handler.exec(ctx, stmt, fromAccountID, toAccountID, shareType, title, idRequest)
 	if err != nil {
 		return err
 	}
@@ -37,25 +59,28 @@ func (handler *Handler) CreateShareRequest(fromUsername, toUsername, shareType,
 }
 
 // GetShareRequests gets all share requests from the database by username
-func (handler *Handler) GetShareRequests(username string) ([]ShareRequest, error) {
+func (handler *Handler) GetShareRequests(ctx context.Context, username string) ([]ShareRequest, error) {
 	shareRequests := []ShareRequest{}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		SELECT *
-		FROM share_requests AS sr
-		WHERE sr.to_username = "%s"	
-	`, username))
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+shareRequestColumns+`
+		FROM `+shareRequestTables+`
+		WHERE ta.username = ?
+		ORDER BY sr.id
+	`)
 	if err != nil {
 		return shareRequests, err
 	}
 
 	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx, username)
 	if err != nil {
 		return shareRequests, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		shareRequest := ShareRequest{}
 
@@ -81,31 +106,100 @@ func (handler *Handler) GetShareRequests(username string) ([]ShareRequest, error
 	return shareRequests, err
 }
 
-// DeleteShareRequest deletes a share request by ID
-func (handler *Handler) DeleteShareRequest(shareID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	DELETE FROM share_requests AS sr
-	WHERE sr.id = %d
-	`, shareID))
+// GetShareRequest gets a share request by ID
+func (handler *Handler) GetShareRequest(ctx context.Context, shareID int) (ShareRequest, error) {
+	shareRequest := ShareRequest{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+shareRequestColumns+`
+		FROM `+shareRequestTables+`
+		WHERE sr.id = ?
+	`)
 	if err != nil {
-		return err
+		return shareRequest, err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
-	if err != nil {
-		return err
+	if err := stmt.QueryRowContext(ctx, shareID).Scan(
+		&shareRequest.ID,
+		&shareRequest.FromUsername,
+		&shareRequest.ToUsername,
+		&shareRequest.ShareType,
+		&shareRequest.Title,
+		&shareRequest.IDRequest,
+		&shareRequest.CreatedAt,
+	); err != nil {
+		return shareRequest, rowError(err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return shareRequest, err
+}
+
+// DeleteShareRequest deletes a share request by ID and leaves its recipient a tombstone of it
+func (handler *Handler) DeleteShareRequest(ctx context.Context, shareID int) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		recipients, err := tx.usernames(ctx, `
+			SELECT a.username
+			FROM share_requests AS sr
+			INNER JOIN accounts AS a
+			ON a.id = sr.to_account_id
+			WHERE sr.id = ?
+		`, shareID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.run(ctx, `
+			DELETE FROM share_requests
+			WHERE id = ?
+		`, shareID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected < 1 {
+			return errNoRowsAffected
+		}
+
+		return tx.recordTombstones(ctx, recipients, KindShareRequest, shareID, TombstoneDeleted)
+	})
+}
+
+// AcceptShareRequest attaches username to the requested storage or shopping list and deletes the request, in one transaction
+func (handler *Handler) AcceptShareRequest(ctx context.Context, username string, shareID int) error {
+	return handler.WithTx(ctx, func(tx Store) error {
+		return acceptShareRequest(ctx, tx, username, shareID)
+	})
+}
+
+// acceptShareRequest accepts a share request sent to username, using only Store operations so every Store shares it
+func acceptShareRequest(ctx context.Context, store Store, username string, shareID int) error {
+	shareRequest, err := store.GetShareRequest(ctx, shareID)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+	if !sameName(shareRequest.ToUsername, username) {
+		return errNoRows
 	}
 
-	return err
+	switch shareRequest.ShareType {
+	case "storage":
+		err = store.ShareStorage(ctx, username, shareRequest.IDRequest)
+	case "shopping_list":
+		err = store.ShareShoppingList(ctx, username, shareRequest.IDRequest)
+	default:
+		err = errDataTruncated("share_type", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	return store.DeleteShareRequest(ctx, shareID)
 }
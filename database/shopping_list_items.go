@@ -0,0 +1,310 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// ShoppingListItem structure
+type ShoppingListItem struct {
+	ID             int        `json:"id"`
+	ShoppingListID int        `json:"shoppingListID"`
+	Title          string     `json:"title"`
+	Quantity       float64    `json:"quantity"`
+	QuantityType   string     `json:"quantityType"`
+	Version        int        `json:"version"`
+	DeletedAt      *time.Time `json:"deletedAt,omitempty"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// shoppingListItemColumns lists the shopping_list_items columns in the order scanShoppingListItem expects them
+const shoppingListItemColumns = `id, shopping_list_id, title, quantity, quantity_type, version, deleted_at, updated_at, created_at`
+
+// scanShoppingListItem scans a row selected with shoppingListItemColumns
+func scanShoppingListItem(row scanner, item *ShoppingListItem) error {
+	return row.Scan(
+		&item.ID,
+		&item.ShoppingListID,
+		&item.Title,
+		&item.Quantity,
+		&item.QuantityType,
+		&item.Version,
+		&item.DeletedAt,
+		&item.UpdatedAt,
+		&item.CreatedAt,
+	)
+}
+
+// CreateShoppingListItem creates a shopping list item in the database attatched by FK to a shopping list ID and returns its ID
+func (handler *Handler) CreateShoppingListItem(ctx context.Context, shoppingListID int, title string, quantity float64, quantityType string) (int64, error) {
+	lastInsertID := int64(0)
+
+	if err := ValidateQuantity("quantity", quantity, quantityType); err != nil {
+		return lastInsertID, err
+	}
+
+	err := handler.withTx(ctx, func(tx *Handler) error {
+		if err := tx.checkNotTrashed(ctx, "shopping_lists", shoppingListID); err != nil {
+			return err
+		}
+
+		id, err := tx.insert(ctx, `
+			INSERT INTO shopping_list_items(shopping_list_id, title, quantity, quantity_type)
+			VALUES(?, ?, ?, ?)
+		`, shoppingListID, title, quantity, quantityType)
+		if err != nil {
+			return err
+		}
+
+		lastInsertID = id
+
+		return nil
+	})
+
+	return lastInsertID, err
+}
+
+// GetShoppingListItems gets a page of the shopping list items of a shopping list ID
+func (handler *Handler) GetShoppingListItems(ctx context.Context, shoppingListID int, options ListOptions) (ShoppingListItemPage, error) {
+	page := ShoppingListItemPage{Items: []ShoppingListItem{}}
+
+	order, err := parseSort(options.Sort, shoppingListItemSorts)
+	if err != nil {
+		return page, err
+	}
+
+	filter := listFilter{}
+	filter.and("sli.shopping_list_id = ?", shoppingListID)
+	filter.and("sli.deleted_at IS NULL")
+	if options.QuantityType != "" {
+		filter.and("sli.quantity_type = ?", options.QuantityType)
+	}
+	if options.Q != "" {
+		filter.contains("sli.title", options.Q)
+	}
+
+	page.Total, err = handler.count(ctx, "shopping_list_items AS sli", filter)
+	if err != nil {
+		return page, err
+	}
+
+	page.NextCursor, err = handler.page(ctx, listQuery{
+		columns: qualify("sli", shoppingListItemColumns),
+		from:    "shopping_list_items AS sli",
+		table:   "shopping_list_items",
+		alias:   "sli",
+	}, filter, order, options, func(row scanner) (int, error) {
+		item := ShoppingListItem{}
+
+		if err := scanShoppingListItem(row, &item); err != nil {
+			return 0, err
+		}
+
+		page.Items = append(page.Items, item)
+		return item.ID, nil
+	})
+
+	return page, err
+}
+
+// GetShoppingListItem gets a single shopping list item by ID
+func (handler *Handler) GetShoppingListItem(ctx context.Context, itemID int) (ShoppingListItem, error) {
+	item := ShoppingListItem{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+shoppingListItemColumns+`
+		FROM shopping_list_items
+		WHERE id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return item, err
+	}
+
+	defer stmt.Close()
+
+	if err := scanShoppingListItem(stmt.QueryRowContext(ctx, itemID), &item); err != nil {
+		return item, rowError(err)
+	}
+
+	return item, err
+}
+
+// GetShoppingListItemsCount gets the amount of shopping list items by username
+func (handler *Handler) GetShoppingListItemsCount(ctx context.Context, username string) (int, error) {
+	count := 0
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT COUNT(*)
+		FROM shopping_list_items AS sli
+		INNER JOIN account_shopping_list_binder AS aslb
+		ON sli.shopping_list_id = aslb.shopping_list_id
+		WHERE aslb.account_id = `+accountIDOf+` AND sli.deleted_at IS NULL
+	`)
+	if err != nil {
+		return count, err
+	}
+
+	defer stmt.Close()
+
+	if err := stmt.QueryRowContext(ctx, username).Scan(
+		&count,
+	); err != nil {
+		return count, rowError(err)
+	}
+
+	return count, err
+}
+
+// UpdateShoppingListItem updates a shopping list item by ID
+func (handler *Handler) UpdateShoppingListItem(ctx context.Context, title string, quantity float64, quantityType string, itemID, version int) error {
+	if err := ValidateQuantity("quantity", quantity, quantityType); err != nil {
+		return err
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		UPDATE shopping_list_items
+		SET
+			title_updated_at = CASE WHEN `+handler.dialect.binaryEquals("title")+` THEN title_updated_at ELSE ? END,
+			title = ?,
+			quantity = ?,
+			quantity_type = ?,
+			version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	result, err := handler.exec(ctx, stmt, title, time.Now().UTC(), title, quantity, quantityType, itemID, version, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected < 1 {
+		return handler.missedWrite(ctx, "shopping_list_items", itemID, version)
+	}
+
+	return err
+}
+
+// UpdateShoppingListItemTitle updates a shopping list item's title by ID
+func (handler *Handler) UpdateShoppingListItemTitle(ctx context.Context, title string, itemID, version int) error {
+	stmt, err := handler.prepare(ctx, `
+		UPDATE shopping_list_items
+		SET
+			title_updated_at = CASE WHEN `+handler.dialect.binaryEquals("title")+` THEN title_updated_at ELSE ? END,
+			title = ?,
+			version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	result, err := handler.exec(ctx, stmt, title, time.Now().UTC(), title, itemID, version, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected < 1 {
+		return handler.missedWrite(ctx, "shopping_list_items", itemID, version)
+	}
+
+	return err
+}
+
+// DecrementShoppingListItemQuantity decrements a shopping list item's quantity by by, stopping at 0, by ID
+func (handler *Handler) DecrementShoppingListItemQuantity(ctx context.Context, by float64, itemID, version int) error {
+	return handler.stepShoppingListItem(ctx, `
+		UPDATE shopping_list_items
+		SET quantity = CASE WHEN quantity > ? THEN ROUND(quantity - ?, 3) ELSE 0 END, version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?) AND quantity > 0
+	`, by, itemID, version, by, by)
+}
+
+// IncrementShoppingListItemQuantity increments a shopping list item's quantity by by, by ID
+func (handler *Handler) IncrementShoppingListItemQuantity(ctx context.Context, by float64, itemID, version int) error {
+	return handler.stepShoppingListItem(ctx, `
+		UPDATE shopping_list_items
+		SET quantity = ROUND(quantity + ?, 3), version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+	`, by, itemID, version, by)
+}
+
+// stepShoppingListItem runs query, which increments or decrements the quantity of the shopping list item itemID by by,
+// with the arguments of its SET clause followed by itemID and version
+func (handler *Handler) stepShoppingListItem(ctx context.Context, query string, by float64, itemID, version int, set ...interface{}) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		if err := tx.checkStep(ctx, "shopping_list_items", by, itemID); err != nil {
+			return err
+		}
+
+		stmt, err := tx.prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		defer stmt.Close()
+
+		result, err := tx.exec(ctx, stmt, append(set, itemID, version, version)...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected < 1 {
+			return tx.missedWrite(ctx, "shopping_list_items", itemID, version)
+		}
+
+		return err
+	})
+}
+
+// DeleteShoppingListItem moves a shopping list item to the trash by ID
+func (handler *Handler) DeleteShoppingListItem(ctx context.Context, itemID, version int) error {
+	stmt, err := handler.prepare(ctx, `
+		UPDATE shopping_list_items
+		SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	result, err := handler.exec(ctx, stmt, itemID, version, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected < 1 {
+		return handler.missedWrite(ctx, "shopping_list_items", itemID, version)
+	}
+
+	return err
+}
@@ -1,144 +1,194 @@
 package database
 
 import (
-	"fmt"
+	"context"
 	"time"
 )
 
 // ShoppingList structure
 type ShoppingList struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	CreatedAt time.Time `json:"createdAt"`
-	Count     int       `json:"count"`
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Version   int        `json:"version"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Count     int        `json:"count"`
 }
 
-// CreateShoppingList creates a shopping list and attaches the account to it by username
-func (handler *Handler) CreateShoppingList(username, title string, owner bool) error {
-	stmtSL, err := handler.DB.Prepare(fmt.Sprintf(`
-		INSERT INTO shopping_lists(title)
-		VALUES("%s");
-	`, title))
-	if err != nil {
-		return err
-	}
+// CreateShoppingList creates a shopping list, attaches the account to it by username and returns its ID
+func (handler *Handler) CreateShoppingList(ctx context.Context, username, title string, owner bool) (int64, error) {
+	lastInsertID := int64(0)
+
+	err := handler.withTx(ctx, func(tx *Handler) error {
+		id, err := tx.insert(ctx, `
+			INSERT INTO shopping_lists(title)
+			VALUES(?)
+		`, title)
+		if err != nil {
+			return err
+		}
 
-	defer stmtSL.Close()
+		stmtASLB, err := tx.prepare(ctx, `
+			INSERT INTO account_shopping_list_binder(account_id, shopping_list_id, owner)
+			VALUES(?, ?, ?)
+		`)
+		if err != nil {
+			return err
+		}
 
-	result, err := stmtSL.Exec()
-	if err != nil {
-		return err
-	}
+		defer stmtASLB.Close()
 
-	lastInsertID, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
+		accountID, err := tx.accountID(ctx, username)
+		if err != nil {
+			return err
+		}
 
-	stmtASLB, err := handler.DB.Prepare(fmt.Sprintf(`
-	INSERT INTO account_shopping_list_binder(username, shopping_list_id, owner)
-	VALUES("%s", %d, %t);
-	`, username, lastInsertID, owner))
-	if err != nil {
-		return err
-	}
+		if _, err := tx.exec(ctx, stmtASLB, accountID, id, owner); err != nil {
+			return err
+		}
 
-	defer stmtASLB.Close()
+		lastInsertID = id
 
-	_, err = stmtASLB.Exec()
-	if err != nil {
-		return err
-	}
+		return nil
+	})
 
-	return err
+	return lastInsertID, err
 }
 
-// GetShoppingLists gets shopping lists by username
-func (handler *Handler) GetShoppingLists(username string) ([]ShoppingList, error) {
-	shoppingLists := []ShoppingList{}
+// GetShoppingLists gets a page of the shopping lists attached to username, with their item count
+func (handler *Handler) GetShoppingLists(ctx context.Context, username string, options ListOptions) (ShoppingListPage, error) {
+	page := ShoppingListPage{Items: []ShoppingList{}}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		SELECT sl.*, COUNT(sli.id) AS "count"
-		FROM shopping_lists AS sl
-		LEFT JOIN shopping_list_items AS sli
-		ON sl.id = sli.shopping_list_id
-		INNER JOIN account_shopping_list_binder AS aslb
-		ON aslb.shopping_list_id = sl.id AND aslb.username = "%s"
-		GROUP BY sl.id
-	`, username))
+	order, err := parseSort(options.Sort, shoppingListSorts)
 	if err != nil {
-		return shoppingLists, err
+		return page, err
 	}
 
-	defer stmt.Close()
+	filter := listFilter{}
+	filter.and("aslb.account_id = "+accountIDOf, username)
+	filter.and("sl.deleted_at IS NULL")
+	if options.Q != "" {
+		filter.contains("sl.title", options.Q)
+	}
 
-	rows, err := stmt.Query()
+	page.Total, err = handler.count(ctx, `
+		shopping_lists AS sl
+		INNER JOIN account_shopping_list_binder AS aslb
+		ON aslb.shopping_list_id = sl.id
+	`, filter)
 	if err != nil {
-		return shoppingLists, err
+		return page, err
 	}
 
-	for rows.Next() {
+	page.NextCursor, err = handler.page(ctx, listQuery{
+		columns: "sl.id, sl.title, sl.version, sl.updated_at, sl.created_at, COUNT(sli.id)",
+		from: `
+			shopping_lists AS sl
+			LEFT JOIN shopping_list_items AS sli
+			ON sl.id = sli.shopping_list_id AND sli.deleted_at IS NULL
+			INNER JOIN account_shopping_list_binder AS aslb
+			ON aslb.shopping_list_id = sl.id
+		`,
+		table:   "shopping_lists",
+		alias:   "sl",
+		groupBy: "GROUP BY sl.id",
+	}, filter, order, options, func(row scanner) (int, error) {
 		sl := ShoppingList{}
 
-		if err := rows.Scan(
+		if err := row.Scan(
 			&sl.ID,
 			&sl.Title,
+			&sl.Version,
 			&sl.UpdatedAt,
 			&sl.CreatedAt,
 			&sl.Count,
 		); err != nil {
-			return shoppingLists, err
+			return 0, err
 		}
 
-		shoppingLists = append(shoppingLists, sl)
+		page.Items = append(page.Items, sl)
+		return sl.ID, nil
+	})
+
+	return page, err
+}
+
+// GetShoppingList gets a shopping list by ID
+func (handler *Handler) GetShoppingList(ctx context.Context, shoppingListID int) (ShoppingList, error) {
+	sl := ShoppingList{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT sl.id, sl.title, sl.version, sl.updated_at, sl.created_at, COUNT(sli.id)
+		FROM shopping_lists AS sl
+		LEFT JOIN shopping_list_items AS sli
+		ON sl.id = sli.shopping_list_id AND sli.deleted_at IS NULL
+		WHERE sl.id = ? AND sl.deleted_at IS NULL
+		GROUP BY sl.id
+	`)
+	if err != nil {
+		return sl, err
 	}
 
-	if err := rows.Err(); err != nil {
-		panic(err)
+	defer stmt.Close()
+
+	if err := stmt.QueryRowContext(ctx, shoppingListID).Scan(
+		&sl.ID,
+		&sl.Title,
+		&sl.Version,
+		&sl.UpdatedAt,
+		&sl.CreatedAt,
+		&sl.Count,
+	); err != nil {
+		return sl, rowError(err)
 	}
 
-	return shoppingLists, err
+	return sl, err
 }
 
 // GetShoppingListsCount gets the amount of shopping lists by username
-func (handler *Handler) GetShoppingListsCount(username string) (int, error) {
+func (handler *Handler) GetShoppingListsCount(ctx context.Context, username string) (int, error) {
 	count := 0
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+	stmt, err := handler.prepare(ctx, `
 		SELECT COUNT(*)
-		FROM account_shopping_list_binder
-		WHERE username="%s"; 
-	`, username))
+		FROM account_shopping_list_binder AS aslb
+		INNER JOIN shopping_lists AS sl
+		ON sl.id = aslb.shopping_list_id AND sl.deleted_at IS NULL
+		WHERE aslb.account_id = `+accountIDOf+`
+	`)
 	if err != nil {
 		return count, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
+	if err := stmt.QueryRowContext(ctx, username).Scan(
 		&count,
 	); err != nil {
-		return count, err
+		return count, rowError(err)
 	}
 
 	return count, err
 }
 
 // UpdateShoppingListTitle updates a shopping list's title by ID
-func (handler *Handler) UpdateShoppingListTitle(title string, shoppingListID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		UPDATE shopping_lists AS sl
-		SET sl.title = "%s"
-		WHERE sl.id = %d
-	`, title, shoppingListID))
+func (handler *Handler) UpdateShoppingListTitle(ctx context.Context, title string, shoppingListID, version int) error {
+	stmt, err := handler.prepare(ctx, `
+		UPDATE shopping_lists
+		SET
+			title_updated_at = CASE WHEN `+handler.dialect.binaryEquals("title")+` THEN title_updated_at ELSE ? END,
+			title = ?,
+			version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, title, time.Now().UTC(), title, shoppingListID, version, version)
 	if err != nil {
 		return err
 	}
@@ -149,54 +199,62 @@ func (handler *Handler) UpdateShoppingListTitle(title string, shoppingListID int
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return handler.missedWrite(ctx, "shopping_lists", shoppingListID, version)
 	}
 
 	return err
 }
 
-// DeleteShoppingList deletes a shopping list by ID
-func (handler *Handler) DeleteShoppingList(shoppingListID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	DELETE FROM shopping_lists AS sl
-	WHERE sl.id = %d
-	`, shoppingListID))
-	if err != nil {
-		return err
-	}
+// DeleteShoppingList moves a shopping list to the trash by ID, along with its items
+func (handler *Handler) DeleteShoppingList(ctx context.Context, shoppingListID, version int) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		result, err := tx.run(ctx, `
+			UPDATE shopping_lists
+			SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+			WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+		`, shoppingListID, version, version)
+		if err != nil {
+			return err
+		}
 
-	defer stmt.Close()
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected < 1 {
+			return tx.missedWrite(ctx, "shopping_lists", shoppingListID, version)
+		}
+
+		// The items share the deletion time of the shopping list so that restoring it brings back these ones only
+		_, err = tx.run(ctx, `
+			UPDATE shopping_list_items
+			SET deleted_at = (SELECT deleted_at FROM shopping_lists WHERE id = ?)
+			WHERE shopping_list_id = ? AND deleted_at IS NULL
+		`, shoppingListID, shoppingListID)
 
-	result, err := stmt.Exec()
-	if err != nil {
 		return err
-	}
+	})
+}
 
-	rowsAffected, err := result.RowsAffected()
+// ShareShoppingList attaches a shopping list to an account by username and ID
+func (handler *Handler) ShareShoppingList(ctx context.Context, username string, shoppingListID int) error {
+	accountID, err := handler.accountID(ctx, username)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
-	}
-
-	return err
-}
-
-// ShareShoppingList attaches a shopping list to an account by username and ID
-func (handler *Handler) ShareShoppingList(username string, shoppingListID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		INSERT INTO account_shopping_list_binder(username, shopping_list_id)
-		VALUES("%s", %d)
-	`, username, shoppingListID))
+	stmt, err := handler.prepare(ctx, `
+		INSERT INTO account_shopping_list_binder(account_id, shopping_list_id)
+		VALUES(?, ?)
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, accountID, shoppingListID)
 	if err != nil {
 		return err
 	}
@@ -207,60 +265,56 @@ func (handler *Handler) ShareShoppingList(username string, shoppingListID int) e
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return errNoRowsAffected
 	}
 
 	return err
 }
 
 // RemoveShareShoppingList removes an accounts attachment to a shopping list by username and ID
-func (handler *Handler) RemoveShareShoppingList(username string, shoppingListID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		DELETE FROM account_shopping_list_binder
-		WHERE username="%s" AND shopping_list_id=%d
-	`, username, shoppingListID))
-	if err != nil {
-		return err
-	}
-
-	defer stmt.Close()
-
-	result, err := stmt.Exec()
-	if err != nil {
-		return err
-	}
+// and leaves the account a tombstone of it
+func (handler *Handler) RemoveShareShoppingList(ctx context.Context, username string, shoppingListID int) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		result, err := tx.run(ctx, `
+			DELETE FROM account_shopping_list_binder
+			WHERE account_id = `+accountIDOf+` AND shopping_list_id = ?
+		`, username, shoppingListID)
+		if err != nil {
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
-	}
+		if rowsAffected < 1 {
+			return errNoRowsAffected
+		}
 
-	return err
+		return tx.recordTombstones(ctx, []string{username}, KindShoppingList, shoppingListID, TombstoneAccessLost)
+	})
 }
 
 // GetShoppingListOwner returns true or false whether it is the shopping list owner by ID
-func (handler *Handler) GetShoppingListOwner(owner string, shoppingListID int) (bool, error) {
+func (handler *Handler) GetShoppingListOwner(ctx context.Context, owner string, shoppingListID int) (bool, error) {
 	payload := false
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	SELECT owner
-	FROM account_shopping_list_binder
-	WHERE username="%s" AND shopping_list_id=%d 
-	`, owner, shoppingListID))
+	stmt, err := handler.prepare(ctx, `
+		SELECT owner
+		FROM account_shopping_list_binder
+		WHERE account_id = `+accountIDOf+` AND shopping_list_id = ?
+	`)
 	if err != nil {
 		return payload, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
+	if err := stmt.QueryRowContext(ctx, owner, shoppingListID).Scan(
 		&payload,
 	); err != nil {
-		return payload, err
+		return payload, rowError(err)
 	}
 
 	return payload, err
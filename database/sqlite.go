@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3" // sqlite driver
+)
+
+// sqliteDialect is the dialect of SQLite
+type sqliteDialect struct{}
+
+func (sqliteDialect) name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) rebind(query string) string {
+	return query
+}
+
+func (sqliteDialect) returningID() bool {
+	return false
+}
+
+func (sqliteDialect) execScript(ctx context.Context, tx *sql.Tx, script string) error {
+	_, err := tx.ExecContext(ctx, script)
+	return err
+}
+
+// translateError maps SQLite constraint violations onto the Store errors.
+// SQLite does not tell which side of a foreign key failed, so every violation is reported as ErrForeignKey.
+func (sqliteDialect) translateError(err error) error {
+	sqliteErr := sqlite3.Error{}
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	// Constraint messages end with the failing columns or constraint, e.g.
+	// "UNIQUE constraint failed: account_storage_binder.account_id, account_storage_binder.storage_id",
+	// whose columns are joined like the MySQL key name account_id_storage_id
+	columns := strings.Split(err.Error()[strings.LastIndex(err.Error(), ":")+1:], ",")
+	for i, column := range columns {
+		columns[i] = strings.TrimSpace(column[strings.LastIndex(column, ".")+1:])
+	}
+	failed := strings.Join(columns, "_")
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintForeignKey:
+		return &Error{Kind: ErrForeignKey, Err: err}
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return errDuplicateKey(failed, err)
+	case sqlite3.ErrConstraintCheck:
+		return errDataTruncated(failed, err)
+	default:
+		return err
+	}
+}
+
+// suspendForeignKeys turns the foreign keys off on conn, as SQLite rebuilds a table to drop one of its columns
+// and dropping a referenced table would otherwise delete the rows referring to it.
+// The pragma is a no-op within a transaction, hence it is set on the connection before the migration begins.
+func (sqliteDialect) suspendForeignKeys(ctx context.Context, conn *sql.Conn) (func(), error) {
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+	}, nil
+}
+
+// checkForeignKeys reports the first row referring to a missing one
+func (sqliteDialect) checkForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	if rows.Next() {
+		table, rowID, parent, index := "", sql.NullInt64{}, "", 0
+		if err := rows.Scan(&table, &rowID, &parent, &index); err != nil {
+			return err
+		}
+		return fmt.Errorf("row %d of %s refers to a missing %s", rowID.Int64, table, parent)
+	}
+
+	return rows.Err()
+}
+
+// forUpdate is empty, the transactions begin immediately and so hold the write lock of the whole database
+func (sqliteDialect) forUpdate() string {
+	return ""
+}
+
+// binaryEquals overrides the NOCASE collation of the column
+func (sqliteDialect) binaryEquals(column string) string {
+	return column + " = ? COLLATE BINARY"
+}
+
+// resetSequence does nothing, AUTOINCREMENT moves past the IDs inserted
+func (sqliteDialect) resetSequence(ctx context.Context, tx *sql.Tx, table string) error {
+	return nil
+}
+
+// InitSQLite returns a new Database handler backed by the SQLite database file at path.
+// Only the pool settings of options apply to SQLite.
+func InitSQLite(path string, options Options) *Handler {
+	dbURI := fmt.Sprintf("file:%s?_foreign_keys=1&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+
+	return open("sqlite3", dbURI, sqliteDialect{}, options)
+}
@@ -1,281 +1,456 @@
 package database
 
 import (
-	"fmt"
+	"cat-clerk-api/units"
+	"context"
+	"database/sql"
+	"errors"
 	"time"
+	"unicode/utf8"
 )
 
 // Item structure
 type Item struct {
-	ID                  int       `json:"id"`
-	StorageID           int       `json:"stroageID"`
-	Title               string    `json:"title"`
-	Image               string    `json:"image"`
-	Quantity            int       `json:"quantity"`
-	QuantityType        string    `json:"quantityType"`
-	QuantityThreshold   int       `json:"quantityThreshold"`
-	ExpirationThreshold int       `json:"expirationThreshold"`
-	ExpirationDate      string    `json:"expirationDate"`
-	UpdatedAt           time.Time `json:"updatedAt"`
-	CreatedAt           time.Time `json:"createdAt"`
+	ID                  int        `json:"id"`
+	StorageID           int        `json:"stroageID"`
+	Title               string     `json:"title"`
+	Image               string     `json:"image"`
+	Quantity            float64    `json:"quantity"`
+	QuantityType        string     `json:"quantityType"`
+	QuantityThreshold   float64    `json:"quantityThreshold"`
+	ExpirationThreshold int        `json:"expirationThreshold"`
+	ExpirationDate      string     `json:"expirationDate"`
+	Version             int        `json:"version"`
+	DeletedAt           *time.Time `json:"deletedAt,omitempty"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+	CreatedAt           time.Time  `json:"createdAt"`
 }
 
-// CreateStorageItem creates a storage item and attaches it to an FK storageID
-func (handler *Handler) CreateStorageItem(storageID int, title string, quantity int, quantityType string, quantityThreshold int, expirationThreshold int, expirationDate string) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		INSERT INTO storage_items(storage_id, title, quantity, quantity_type, quantity_threshold, expiration_threshold, expiration_date)
-		VALUES(%d, "%s", %d, "%s", %d, %d, "%s");
-	`, storageID, title, quantity, quantityType, quantityThreshold, expirationThreshold, expirationDate))
-	if err != nil {
+// itemColumns lists the storage_items columns in the order scanItem expects them
+const itemColumns = `id, storage_id, title, image, quantity, quantity_type, quantity_threshold, expiration_threshold, expiration_date, version, deleted_at, updated_at, created_at`
+
+// scanItem scans a row selected with itemColumns
+func scanItem(row scanner, item *Item) error {
+	expirationDate := sql.NullString{}
+
+	if err := row.Scan(
+		&item.ID,
+		&item.StorageID,
+		&item.Title,
+		&item.Image,
+		&item.Quantity,
+		&item.QuantityType,
+		&item.QuantityThreshold,
+		&item.ExpirationThreshold,
+		&expirationDate,
+		&item.Version,
+		&item.DeletedAt,
+		&item.UpdatedAt,
+		&item.CreatedAt,
+	); err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	item.ExpirationDate = expirationDate.String
 
-	_, err = stmt.Exec()
-	if err != nil {
+	return nil
+}
+
+// expirationDateLayouts are the layouts an expiration date is accepted in
+var expirationDateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", time.RFC3339}
+
+// parseExpirationDate parses an expiration date in any of expirationDateLayouts, in UTC
+func parseExpirationDate(date string) (time.Time, bool) {
+	for _, layout := range expirationDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// expirationDateArg is the expiration_date written for expirationDate, NULL when the item has none,
+// as PostgreSQL and strict MySQL reject an empty string for a timestamp. The date is written as a UTC time
+// rather than as sent, so that SQLite, which stores it as text, compares and sorts every date alike.
+func expirationDateArg(expirationDate string) (interface{}, error) {
+	if expirationDate == "" {
+		return nil, nil
+	}
+
+	date, ok := parseExpirationDate(expirationDate)
+	if !ok {
+		return nil, errDataTruncated("expiration_date", nil)
+	}
+
+	return date, nil
+}
+
+// ValidateItem checks the fields of a storage item against the rules of the schema, so that a write
+// is known to pass them before it is made: no quantity nor threshold is negative and the quantities fit
+// the precision of their type. A shopping list item is checked as an item with no thresholds nor date,
+// and an empty expiration date means none.
+func ValidateItem(item Item) error {
+	if utf8.RuneCountInString(item.Title) > titleLength {
+		return errDataTruncated("title", nil)
+	}
+
+	if !quantityTypes[item.QuantityType] {
+		return errDataTruncated("quantity_type", nil)
+	}
+
+	if item.Quantity < 0 {
+		return errDataTruncated("quantity", nil)
+	}
+	if item.QuantityThreshold < 0 {
+		return errDataTruncated("quantity_threshold", nil)
+	}
+	if item.ExpirationThreshold < 0 {
+		return errDataTruncated("expiration_threshold", nil)
+	}
+
+	if _, err := expirationDateArg(item.ExpirationDate); err != nil {
 		return err
 	}
 
-	return err
+	return validateItemQuantities(item.Quantity, item.QuantityType, item.QuantityThreshold)
 }
 
-// GetStorageItems gets storage items by storageID
-func (handler *Handler) GetStorageItems(storageID int) ([]Item, error) {
-	items := []Item{}
+// ValidateQuantity checks that quantity, the field of an item, holds no more decimals than the precision of quantityType,
+// so that pieces stay whole. Unknown quantity types are left to ValidateItem.
+func ValidateQuantity(field string, quantity float64, quantityType string) error {
+	if units.Known(quantityType) && !units.Fits(units.Quantity{Amount: quantity, Unit: quantityType}) {
+		return errDataTruncated(field, nil)
+	}
+	return nil
+}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	SELECT *
-	FROM storage_items AS si
-	WHERE si.storage_id = %d
-	`, storageID))
+// validateItemQuantities checks the quantity and quantity threshold of a storage item with ValidateQuantity
+func validateItemQuantities(quantity float64, quantityType string, quantityThreshold float64) error {
+	if err := ValidateQuantity("quantity", quantity, quantityType); err != nil {
+		return err
+	}
+	return ValidateQuantity("quantity_threshold", quantityThreshold, quantityType)
+}
+
+// checkStep checks that by, the step the quantity of the item itemID of table is incremented or decremented by,
+// is positive and fits the quantity type of the item. A missing item is left to the write to report.
+func (handler *Handler) checkStep(ctx context.Context, table string, by float64, itemID int) error {
+	if by <= 0 {
+		return errDataTruncated("by", nil)
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT quantity_type
+		FROM `+table+`
+		WHERE id = ?
+	`)
 	if err != nil {
-		return items, err
+		return err
 	}
 
 	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	quantityType := ""
+
+	if err := stmt.QueryRowContext(ctx, itemID).Scan(
+		&quantityType,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	return ValidateQuantity("by", by, quantityType)
+}
+
+// CreateStorageItem creates a storage item, attaches it to an FK storageID and returns its ID
+func (handler *Handler) CreateStorageItem(ctx context.Context, storageID int, title string, quantity float64, quantityType string, quantityThreshold float64, expirationThreshold int, expirationDate string) (int64, error) {
+	lastInsertID := int64(0)
+
+	if err := validateItemQuantities(quantity, quantityType, quantityThreshold); err != nil {
+		return lastInsertID, err
+	}
+
+	date, err := expirationDateArg(expirationDate)
 	if err != nil {
-		return items, err
+		return lastInsertID, err
 	}
 
-	for rows.Next() {
-		item := Item{}
+	err = handler.withTx(ctx, func(tx *Handler) error {
+		if err := tx.checkNotTrashed(ctx, "storages", storageID); err != nil {
+			return err
+		}
 
-		if err := rows.Scan(
-			&item.ID,
-			&item.StorageID,
-			&item.Title,
-			&item.Image,
-			&item.Quantity,
-			&item.QuantityType,
-			&item.QuantityThreshold,
-			&item.ExpirationThreshold,
-			&item.ExpirationDate,
-			&item.UpdatedAt,
-			&item.CreatedAt,
-		); err != nil {
-			return items, err
+		id, err := tx.insert(ctx, `
+			INSERT INTO storage_items(storage_id, title, quantity, quantity_type, quantity_threshold, expiration_threshold, expiration_date)
+			VALUES(?, ?, ?, ?, ?, ?, ?)
+		`, storageID, title, quantity, quantityType, quantityThreshold, expirationThreshold, date)
+		if err != nil {
+			return err
 		}
 
-		items = append(items, item)
+		item, err := tx.storageItemAnyState(ctx, int(id))
+		if err != nil {
+			return err
+		}
+
+		lastInsertID = id
+
+		return tx.recordItemEvents(ctx, []int{int(id)}, itemChanges(nil, item))
+	})
+
+	return lastInsertID, err
+}
+
+// GetStorageItems gets a page of the storage items of storageID
+func (handler *Handler) GetStorageItems(ctx context.Context, storageID int, options ListOptions) (ItemPage, error) {
+	page := ItemPage{Items: []Item{}}
+
+	order, err := parseSort(options.Sort, storageItemSorts)
+	if err != nil {
+		return page, err
 	}
 
-	if err := rows.Err(); err != nil {
-		panic(err)
+	filter := listFilter{}
+	filter.and("si.storage_id = ?", storageID)
+	filter.and("si.deleted_at IS NULL")
+	if options.ExpiringBefore != nil {
+		// Items without an expiration date are not after the zero time, see byExpirationDate
+		filter.and("si.expiration_date > ? AND si.expiration_date < ?", time.Time{}, options.ExpiringBefore.UTC())
+	}
+	if options.BelowThreshold {
+		filter.and("si.quantity <= si.quantity_threshold")
+	}
+	if options.QuantityType != "" {
+		filter.and("si.quantity_type = ?", options.QuantityType)
+	}
+	if options.Q != "" {
+		filter.contains("si.title", options.Q)
 	}
 
-	return items, err
+	page.Total, err = handler.count(ctx, "storage_items AS si", filter)
+	if err != nil {
+		return page, err
+	}
+
+	page.NextCursor, err = handler.page(ctx, listQuery{
+		columns: qualify("si", itemColumns),
+		from:    "storage_items AS si",
+		table:   "storage_items",
+		alias:   "si",
+	}, filter, order, options, func(row scanner) (int, error) {
+		item := Item{}
+
+		if err := scanItem(row, &item); err != nil {
+			return 0, err
+		}
+
+		page.Items = append(page.Items, item)
+		return item.ID, nil
+	})
+
+	return page, err
 }
 
 // GetStorageItem gets a storage item by storageID and ID
-func (handler *Handler) GetStorageItem(storageID, itemID int) (Item, error) {
+func (handler *Handler) GetStorageItem(ctx context.Context, storageID, itemID int) (Item, error) {
 	item := Item{}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	SELECT *
-	FROM storage_items
-	WHERE storage_id = %d AND id = %d  
-	`, storageID, itemID))
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+itemColumns+`
+		FROM storage_items
+		WHERE storage_id = ? AND id = ? AND deleted_at IS NULL
+	`)
 	if err != nil {
 		return item, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
-		&item.ID,
-		&item.StorageID,
-		&item.Title,
-		&item.Image,
-		&item.Quantity,
-		&item.QuantityType,
-		&item.QuantityThreshold,
-		&item.ExpirationThreshold,
-		&item.ExpirationDate,
-		&item.UpdatedAt,
-		&item.CreatedAt,
-	); err != nil {
-		return item, err
+	if err := scanItem(stmt.QueryRowContext(ctx, storageID, itemID), &item); err != nil {
+		return item, rowError(err)
 	}
 
 	return item, err
 }
 
 // GetStorageItemsCount gets the amount of storage items by username
-func (handler *Handler) GetStorageItemsCount(username string) (int, error) {
+func (handler *Handler) GetStorageItemsCount(ctx context.Context, username string) (int, error) {
 	count := 0
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+	stmt, err := handler.prepare(ctx, `
 		SELECT COUNT(*)
 		FROM storage_items AS si
-		INNER JOIN storages  AS s
-		ON si.storage_id = s.id
 		INNER JOIN account_storage_binder AS asb
-		WHERE s.id = asb.storage_id AND asb.username="%s";
-	`, username))
+		ON si.storage_id = asb.storage_id
+		WHERE asb.account_id = `+accountIDOf+` AND si.deleted_at IS NULL
+	`)
 	if err != nil {
 		return count, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
+	if err := stmt.QueryRowContext(ctx, username).Scan(
 		&count,
 	); err != nil {
-		return count, err
+		return count, rowError(err)
 	}
 
 	return count, err
 }
 
 // UpdateStorageItem updates a storage item by ID
-func (handler *Handler) UpdateStorageItem(title, image string, quantity int, quantityType string, quantityThreshold, expirationThreshold int, expirationDate string, itemID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		UPDATE storage_items AS si
-		SET 
-			si.title = "%s",
-			si.image = "%s",
-			si.quantity = %d,
-			si.quantity_type = "%s",
-			si.quantity_threshold = %d,
-			si.expiration_threshold = %d,
-			si.expiration_date = "%s"
-		WHERE si.id = %d;
-	`, title, image, quantity, quantityType, quantityThreshold, expirationThreshold, expirationDate, itemID))
-	if err != nil {
+func (handler *Handler) UpdateStorageItem(ctx context.Context, title, image string, quantity float64, quantityType string, quantityThreshold float64, expirationThreshold int, expirationDate string, itemID, version int) error {
+	if err := validateItemQuantities(quantity, quantityType, quantityThreshold); err != nil {
 		return err
 	}
 
-	defer stmt.Close()
-
-	result, err := stmt.Exec()
+	date, err := expirationDateArg(expirationDate)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+	return handler.recordingItem(ctx, itemID, func(tx *Handler) error {
+		stmt, err := tx.prepare(ctx, `
+			UPDATE storage_items
+			SET
+				title_updated_at = CASE WHEN `+tx.dialect.binaryEquals("title")+` THEN title_updated_at ELSE ? END,
+				title = ?,
+				image = ?,
+				quantity = ?,
+				quantity_type = ?,
+				quantity_threshold = ?,
+				expiration_threshold = ?,
+				expiration_date = ?,
+				version = version + 1
+			WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+		`)
+		if err != nil {
+			return err
+		}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
-	}
+		defer stmt.Close()
 
-	return err
-}
+		result, err := tx.exec(ctx, stmt, title, time.Now().UTC(), title, image, quantity, quantityType, quantityThreshold, expirationThreshold, date, itemID, version, version)
+		if err != nil {
+			return err
+		}
 
-// DecrementStorageItemQuantity decrements a storage item's quantity by ID
-func (handler *Handler) DecrementStorageItemQuantity(itemID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		UPDATE storage_items AS si
-		SET si.quantity = si.quantity - 1
-		WHERE si.id = %d AND si.quantity > 0;
-	`, itemID))
-	if err != nil {
-		return err
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	defer stmt.Close()
+		if rowsAffected < 1 {
+			return tx.missedWrite(ctx, "storage_items", itemID, version)
+		}
 
-	result, err := stmt.Exec()
-	if err != nil {
-		fmt.Println(err)
 		return err
-	}
+	})
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+// DecrementStorageItemQuantity decrements a storage item's quantity by by, stopping at 0, by ID
+func (handler *Handler) DecrementStorageItemQuantity(ctx context.Context, by float64, itemID, version int) error {
+	return handler.recordingItem(ctx, itemID, func(tx *Handler) error {
+		if err := tx.checkStep(ctx, "storage_items", by, itemID); err != nil {
+			return err
+		}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
-	}
+		stmt, err := tx.prepare(ctx, `
+			UPDATE storage_items
+			SET quantity = CASE WHEN quantity > ? THEN ROUND(quantity - ?, 3) ELSE 0 END, version = version + 1
+			WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?) AND quantity > 0
+		`)
+		if err != nil {
+			return err
+		}
 
-	return err
-}
+		defer stmt.Close()
 
-// IncrementStorageItemQuantity increments a storage item's quantity by ID
-func (handler *Handler) IncrementStorageItemQuantity(itemID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		UPDATE storage_items AS si
-		SET 
-			si.quantity = si.quantity + 1
-		WHERE si.id = %d;
-	`, itemID))
-	if err != nil {
-		return err
-	}
+		result, err := tx.exec(ctx, stmt, by, by, itemID, version, version)
+		if err != nil {
+			return err
+		}
 
-	defer stmt.Close()
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	result, err := stmt.Exec()
-	if err != nil {
-		fmt.Println(err)
-		return err
-	}
+		if rowsAffected < 1 {
+			return tx.missedWrite(ctx, "storage_items", itemID, version)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
 		return err
-	}
+	})
+}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
-	}
+// IncrementStorageItemQuantity increments a storage item's quantity by by, by ID
+func (handler *Handler) IncrementStorageItemQuantity(ctx context.Context, by float64, itemID, version int) error {
+	return handler.recordingItem(ctx, itemID, func(tx *Handler) error {
+		if err := tx.checkStep(ctx, "storage_items", by, itemID); err != nil {
+			return err
+		}
 
-	return err
-}
+		stmt, err := tx.prepare(ctx, `
+			UPDATE storage_items
+			SET quantity = ROUND(quantity + ?, 3), version = version + 1
+			WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+		`)
+		if err != nil {
+			return err
+		}
 
-// DeleteStorageItem deletes a storage item by ID
-func (handler *Handler) DeleteStorageItem(itemID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	DELETE FROM storage_items AS si
-	WHERE si.id = %d
-	`, itemID))
-	if err != nil {
-		return err
-	}
+		defer stmt.Close()
 
-	defer stmt.Close()
+		result, err := tx.exec(ctx, stmt, by, itemID, version, version)
+		if err != nil {
+			return err
+		}
 
-	result, err := stmt.Exec()
-	if err != nil {
-		return err
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected < 1 {
+			return tx.missedWrite(ctx, "storage_items", itemID, version)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
 		return err
-	}
+	})
+}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
-	}
+// DeleteStorageItem moves a storage item to the trash by ID
+func (handler *Handler) DeleteStorageItem(ctx context.Context, itemID, version int) error {
+	return handler.recordingItem(ctx, itemID, func(tx *Handler) error {
+		stmt, err := tx.prepare(ctx, `
+			UPDATE storage_items
+			SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+			WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+		`)
+		if err != nil {
+			return err
+		}
+
+		defer stmt.Close()
 
-	return err
+		result, err := tx.exec(ctx, stmt, itemID, version, version)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected < 1 {
+			return tx.missedWrite(ctx, "storage_items", itemID, version)
+		}
+
+		return err
+	})
 }
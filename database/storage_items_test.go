@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpirationDates(t *testing.T) {
+	// Dates as sent, keyed by the title of their item, along with the date they are read back as
+	dates := []struct {
+		title string
+		date  string
+		want  string
+	}{
+		{title: "date", date: "2030-01-02", want: "2030-01-02T00:00:00Z"},
+		{title: "date and time", date: "2030-01-02 00:00:00", want: "2030-01-02T00:00:00Z"},
+		{title: "utc", date: "2030-01-02T00:00:00Z", want: "2030-01-02T00:00:00Z"},
+		{title: "ahead of utc", date: "2030-01-02T05:00:00+09:00", want: "2030-01-01T20:00:00Z"},
+		{title: "later", date: "2030-01-03 12:30:00", want: "2030-01-03T12:30:00Z"},
+		{title: "none", date: "", want: ""},
+	}
+
+	for _, s := range testStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if _, err := s.store.CreateAccount(ctx, "ann", "ann@example.com", "password", "salt"); err != nil {
+				t.Fatal(err)
+			}
+			storageID, err := s.store.CreateStorage(ctx, "ann", "Fridge", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, date := range dates {
+				if _, err := s.store.CreateStorageItem(ctx, int(storageID), date.title, 1, "pieces", 0, 0, date.date); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := s.store.CreateStorageItem(ctx, int(storageID), "soon", 1, "pieces", 0, 0, "soon"); !errors.Is(err, ErrValidation) {
+				t.Errorf("created an item expiring soon, error = %v", err)
+			}
+
+			titles := func(options ListOptions) []string {
+				page, err := s.store.GetStorageItems(ctx, int(storageID), options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				titles := []string{}
+				for _, item := range page.Items {
+					titles = append(titles, item.Title)
+				}
+				return titles
+			}
+
+			page, err := s.store.GetStorageItems(ctx, int(storageID), ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Items) != len(dates) {
+				t.Fatalf("%d items, want %d", len(page.Items), len(dates))
+			}
+			for i, item := range page.Items {
+				if item.ExpirationDate != dates[i].want {
+					t.Errorf("expiration date of %q = %q, want %q", item.Title, item.ExpirationDate, dates[i].want)
+				}
+			}
+
+			before := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+			if got, want := titles(ListOptions{ExpiringBefore: &before}), []string{"ahead of utc"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("expiring before %v = %v, want %v", before, got, want)
+			}
+
+			want := []string{"ahead of utc", "date", "date and time", "utc", "later", "none"}
+			if got := titles(ListOptions{Sort: "expiration_date"}); !reflect.DeepEqual(got, want) {
+				t.Errorf("sorted by expiration date = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestExpirationDatesMigration(t *testing.T) {
+	ctx := context.Background()
+	handler := newTestSQLite(t)
+
+	if err := handler.Migrate(ctx, 12); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := handler.CreateAccount(ctx, "ann", "ann@example.com", "password", "salt"); err != nil {
+		t.Fatal(err)
+	}
+	storageID, err := handler.CreateStorage(ctx, "ann", "Fridge", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dates as former builds stored them, along with the date they are read back as once migrated
+	dates := []struct {
+		stored string
+		want   string
+	}{
+		{stored: "2030-01-02", want: "2030-01-02T00:00:00Z"},
+		{stored: "2030-01-02 00:00:00", want: "2030-01-02T00:00:00Z"},
+		{stored: "2030-01-02T00:00:00Z", want: "2030-01-02T00:00:00Z"},
+		{stored: "2030-01-02T05:00:00+09:00", want: "2030-01-01T20:00:00Z"},
+		{stored: "", want: ""},
+	}
+
+	for _, date := range dates {
+		id, err := handler.CreateStorageItem(ctx, int(storageID), date.stored, 1, "pieces", 0, 0, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := handler.DB.ExecContext(ctx, `UPDATE storage_items SET expiration_date = ? WHERE id = ?`, date.stored, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := handler.Migrate(ctx, 13); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := handler.GetStorageItems(ctx, int(storageID), ListOptions{Sort: "expiration_date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for _, item := range page.Items {
+		got[item.Title] = item.ExpirationDate
+	}
+	for _, date := range dates {
+		if got[date.stored] != date.want {
+			t.Errorf("expiration date stored as %q = %q, want %q", date.stored, got[date.stored], date.want)
+		}
+	}
+
+	if page.Items[0].Title != "2030-01-02T05:00:00+09:00" || page.Items[len(page.Items)-1].Title != "" {
+		t.Errorf("sorted by expiration date = %v", page.Items)
+	}
+}
@@ -1,145 +1,194 @@
 package database
 
 import (
-	"fmt"
+	"context"
 	"time"
 )
 
 // Folder structure
 type Folder struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	CreatedAt time.Time `json:"createdAt"`
-	Count     int       `json:"count"`
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Version   int        `json:"version"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Count     int        `json:"count"`
 }
 
 // CreateStorage creates a storage and attaches the account to it by username
-func (handler *Handler) CreateStorage(username, title string, owner bool) (int64, error) {
+func (handler *Handler) CreateStorage(ctx context.Context, username, title string, owner bool) (int64, error) {
 	lastInsertID := int64(0)
-	stmtS, err := handler.DB.Prepare(fmt.Sprintf(`
-		INSERT INTO storages(title)
-		VALUES("%s");
-	`, title))
-	if err != nil {
-		return lastInsertID, err
-	}
 
-	defer stmtS.Close()
+	err := handler.withTx(ctx, func(tx *Handler) error {
+		id, err := tx.insert(ctx, `
+			INSERT INTO storages(title)
+			VALUES(?)
+		`, title)
+		if err != nil {
+			return err
+		}
 
-	result, err := stmtS.Exec()
-	if err != nil {
-		return lastInsertID, err
-	}
+		stmtASB, err := tx.prepare(ctx, `
+			INSERT INTO account_storage_binder(account_id, storage_id, owner)
+			VALUES(?, ?, ?)
+		`)
+		if err != nil {
+			return err
+		}
 
-	lastInsertID, err = result.LastInsertId()
-	if err != nil {
-		return lastInsertID, err
-	}
+		defer stmtASB.Close()
 
-	stmtASB, err := handler.DB.Prepare(fmt.Sprintf(`
-	INSERT INTO account_storage_binder(username, storage_id, owner)
-	VALUES("%s", %d, %t);
-	`, username, lastInsertID, owner))
-	if err != nil {
-		return lastInsertID, err
-	}
+		accountID, err := tx.accountID(ctx, username)
+		if err != nil {
+			return err
+		}
 
-	defer stmtASB.Close()
+		if _, err := tx.exec(ctx, stmtASB, accountID, id, owner); err != nil {
+			return err
+		}
 
-	_, err = stmtASB.Exec()
-	if err != nil {
-		return lastInsertID, err
-	}
+		lastInsertID = id
+
+		return nil
+	})
 
 	return lastInsertID, err
 }
 
-// GetStorages gets all storages by username
-func (handler *Handler) GetStorages(username string) ([]Folder, error) {
-	folders := []Folder{}
+// GetStorages gets a page of the storages attached to username, with their item count
+func (handler *Handler) GetStorages(ctx context.Context, username string, options ListOptions) (FolderPage, error) {
+	page := FolderPage{Items: []Folder{}}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		SELECT s.*, COUNT(si.id) AS "count"
-		FROM storages AS s
-		LEFT JOIN storage_items AS si
-		ON s.id = si.storage_id
-		INNER JOIN account_storage_binder AS asb
-		ON asb.storage_id = s.id AND asb.username = "%s"
-		GROUP BY s.id
-	`, username))
+	order, err := parseSort(options.Sort, storageSorts)
 	if err != nil {
-		return folders, err
+		return page, err
 	}
 
-	defer stmt.Close()
+	filter := listFilter{}
+	filter.and("asb.account_id = "+accountIDOf, username)
+	filter.and("s.deleted_at IS NULL")
+	if options.Q != "" {
+		filter.contains("s.title", options.Q)
+	}
 
-	rows, err := stmt.Query()
+	page.Total, err = handler.count(ctx, `
+		storages AS s
+		INNER JOIN account_storage_binder AS asb
+		ON asb.storage_id = s.id
+	`, filter)
 	if err != nil {
-		return folders, err
+		return page, err
 	}
 
-	for rows.Next() {
+	page.NextCursor, err = handler.page(ctx, listQuery{
+		columns: "s.id, s.title, s.version, s.updated_at, s.created_at, COUNT(si.id)",
+		from: `
+			storages AS s
+			LEFT JOIN storage_items AS si
+			ON s.id = si.storage_id AND si.deleted_at IS NULL
+			INNER JOIN account_storage_binder AS asb
+			ON asb.storage_id = s.id
+		`,
+		table:   "storages",
+		alias:   "s",
+		groupBy: "GROUP BY s.id",
+	}, filter, order, options, func(row scanner) (int, error) {
 		folder := Folder{}
 
-		if err := rows.Scan(
+		if err := row.Scan(
 			&folder.ID,
 			&folder.Title,
+			&folder.Version,
 			&folder.UpdatedAt,
 			&folder.CreatedAt,
 			&folder.Count,
 		); err != nil {
-			return folders, err
+			return 0, err
 		}
 
-		folders = append(folders, folder)
+		page.Items = append(page.Items, folder)
+		return folder.ID, nil
+	})
+
+	return page, err
+}
+
+// GetStorage gets a storage by ID
+func (handler *Handler) GetStorage(ctx context.Context, storageID int) (Folder, error) {
+	folder := Folder{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT s.id, s.title, s.version, s.updated_at, s.created_at, COUNT(si.id)
+		FROM storages AS s
+		LEFT JOIN storage_items AS si
+		ON s.id = si.storage_id AND si.deleted_at IS NULL
+		WHERE s.id = ? AND s.deleted_at IS NULL
+		GROUP BY s.id
+	`)
+	if err != nil {
+		return folder, err
 	}
 
-	if err := rows.Err(); err != nil {
-		panic(err)
+	defer stmt.Close()
+
+	if err := stmt.QueryRowContext(ctx, storageID).Scan(
+		&folder.ID,
+		&folder.Title,
+		&folder.Version,
+		&folder.UpdatedAt,
+		&folder.CreatedAt,
+		&folder.Count,
+	); err != nil {
+		return folder, rowError(err)
 	}
 
-	return folders, err
+	return folder, err
 }
 
-// GetStorageFoldersCount gets the amount of storages by username
-func (handler *Handler) GetStoragesCount(username string) (int, error) {
+// GetStoragesCount gets the amount of storages by username
+func (handler *Handler) GetStoragesCount(ctx context.Context, username string) (int, error) {
 	count := 0
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
+	stmt, err := handler.prepare(ctx, `
 		SELECT COUNT(*)
-		FROM account_storage_binder
-		WHERE username="%s"; 
-	`, username))
+		FROM account_storage_binder AS asb
+		INNER JOIN storages AS s
+		ON s.id = asb.storage_id AND s.deleted_at IS NULL
+		WHERE asb.account_id = `+accountIDOf+`
+	`)
 	if err != nil {
 		return count, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
+	if err := stmt.QueryRowContext(ctx, username).Scan(
 		&count,
 	); err != nil {
-		return count, err
+		return count, rowError(err)
 	}
 
 	return count, err
 }
 
 // UpdateStorage updates a storage by ID
-func (handler *Handler) UpdateStorage(title string, storageID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		UPDATE storages AS s
-		SET s.title = "%s"
-		WHERE s.id = %d
-	`, title, storageID))
+func (handler *Handler) UpdateStorage(ctx context.Context, title string, storageID, version int) error {
+	stmt, err := handler.prepare(ctx, `
+		UPDATE storages
+		SET
+			title_updated_at = CASE WHEN `+handler.dialect.binaryEquals("title")+` THEN title_updated_at ELSE ? END,
+			title = ?,
+			version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, title, time.Now().UTC(), title, storageID, version, version)
 	if err != nil {
 		return err
 	}
@@ -150,39 +199,53 @@ func (handler *Handler) UpdateStorage(title string, storageID int) error {
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return handler.missedWrite(ctx, "storages", storageID, version)
 	}
 
 	return err
 }
 
-// DeleteStorage deletes a storage by ID
-func (handler *Handler) DeleteStorage(storageID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	DELETE FROM storages AS s
-	WHERE s.id = %d
-	`, storageID))
-	if err != nil {
-		return err
-	}
+// DeleteStorage moves a storage to the trash by ID, along with its items
+func (handler *Handler) DeleteStorage(ctx context.Context, storageID, version int) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		result, err := tx.run(ctx, `
+			UPDATE storages
+			SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+			WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+		`, storageID, version, version)
+		if err != nil {
+			return err
+		}
 
-	defer stmt.Close()
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	result, err := stmt.Exec()
-	if err != nil {
-		return err
-	}
+		if rowsAffected < 1 {
+			return tx.missedWrite(ctx, "storages", storageID, version)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+		itemIDs, err := tx.ids(ctx, `
+			SELECT id
+			FROM storage_items
+			WHERE storage_id = ? AND deleted_at IS NULL
+		`, storageID)
+		if err != nil {
+			return err
+		}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
-	}
+		// The items share the deletion time of the storage so that restoring it brings back these ones only
+		if _, err := tx.run(ctx, `
+			UPDATE storage_items
+			SET deleted_at = (SELECT deleted_at FROM storages WHERE id = ?)
+			WHERE storage_id = ? AND deleted_at IS NULL
+		`, storageID, storageID); err != nil {
+			return err
+		}
 
-	return err
+		return tx.recordItemEvents(ctx, itemIDs, deletion(true))
+	})
 }
 
 // Share structure
@@ -194,18 +257,23 @@ type Share struct {
 }
 
 // ShareStorage attaches a storage to an account by username and ID
-func (handler *Handler) ShareStorage(username string, storageID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		INSERT INTO account_storage_binder(username, storage_id)
-		VALUES("%s", %d)
-	`, username, storageID))
+func (handler *Handler) ShareStorage(ctx context.Context, username string, storageID int) error {
+	accountID, err := handler.accountID(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		INSERT INTO account_storage_binder(account_id, storage_id)
+		VALUES(?, ?)
+	`)
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.Exec()
+	result, err := handler.exec(ctx, stmt, accountID, storageID)
 	if err != nil {
 		return err
 	}
@@ -216,60 +284,62 @@ func (handler *Handler) ShareStorage(username string, storageID int) error {
 	}
 
 	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
+		return errNoRowsAffected
 	}
 
 	return err
 }
 
 // RemoveShareStorage removes an accounts attachment to a storage by username and ID
-func (handler *Handler) RemoveShareStorage(usernameRequest string, storageID int) error {
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-		DELETE FROM account_storage_binder
-		WHERE username="%s" AND storage_id=%d
-	`, usernameRequest, storageID))
-	if err != nil {
-		return err
-	}
-
-	defer stmt.Close()
-
-	result, err := stmt.Exec()
-	if err != nil {
-		return err
-	}
+// and leaves the account a tombstone of it
+func (handler *Handler) RemoveShareStorage(ctx context.Context, usernameRequest string, storageID int) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		result, err := tx.run(ctx, `
+			DELETE FROM account_storage_binder
+			WHERE account_id = `+accountIDOf+` AND storage_id = ?
+		`, usernameRequest, storageID)
+		if err != nil {
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	if rowsAffected < 1 {
-		return fmt.Errorf("no rows affected")
-	}
+		if rowsAffected < 1 {
+			return errNoRowsAffected
+		}
 
-	return err
+		return tx.recordTombstones(ctx, []string{usernameRequest}, KindStorage, storageID, TombstoneAccessLost)
+	})
 }
 
 // GetStorageOwner returns true or false whether it is the storage owner by ID
-func (handler *Handler) GetStorageOwner(owner string, storageID int) (Share, error) {
+func (handler *Handler) GetStorageOwner(ctx context.Context, owner string, storageID int) (Share, error) {
 	payload := Share{}
 
-	stmt, err := handler.DB.Prepare(fmt.Sprintf(`
-	SELECT owner
-	FROM account_storage_binder
-	WHERE username="%s" AND storage_id=%d
-	`, owner, storageID))
+	stmt, err := handler.prepare(ctx, `
+		SELECT owner
+		FROM account_storage_binder
+		WHERE account_id = `+accountIDOf+` AND storage_id = ?
+	`)
 	if err != nil {
 		return payload, err
 	}
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRow().Scan(
-		&payload.Owner,
+	isOwner := false
+
+	if err := stmt.QueryRowContext(ctx, owner, storageID).Scan(
+		&isOwner,
 	); err != nil {
-		return payload, err
+		return payload, rowError(err)
+	}
+
+	if isOwner {
+		payload.Owner = 1
 	}
 
 	return payload, err
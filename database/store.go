@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Store is the complete set of data operations the API depends on.
+// It is composed of one repository per aggregate so callers can depend on
+// only the part they need.
+// The writes taking a version apply only to a row at that version, or to any row with AnyVersion,
+// and fail with ErrStale otherwise.
+type Store interface {
+	// WithTx runs fn with a Store whose operations are applied all together or not at all
+	WithTx(ctx context.Context, fn func(tx Store) error) error
+	// Health reports whether the store is reachable, along with its connection pool statistics
+	Health(ctx context.Context) Health
+
+	AccountStore
+	SettingsStore
+	FoodStore
+	ShareRequestStore
+	StorageStore
+	StorageItemStore
+	ShoppingListStore
+	ShoppingListItemStore
+	TrashStore
+	SearchStore
+	CollaboratorStore
+	SyncStore
+	ArchiveStore
+	AlertStore
+}
+
+// AccountStore holds the account operations
+type AccountStore interface {
+	CreateAccount(ctx context.Context, username, email, password, salt string) (sql.Result, error)
+	GetAccount(ctx context.Context, username string) (Account, error)
+	GetAccountEmail(ctx context.Context, username string) (Email, error)
+	EmailExists(ctx context.Context, email string) (UsernameEmail, error)
+	GetAccounts(ctx context.Context) ([]Account, error)
+	CheckAccountCredentials(ctx context.Context, username, email string) (Account, error)
+	UpdateAccount(ctx context.Context, username, newUsername, password, email string, darkTheme, notifications bool) error
+	UpdateAccountUsername(ctx context.Context, username, newUsername string) error
+	UpdateAccountEmail(ctx context.Context, username, email string) error
+	UpdateAccountPassword(ctx context.Context, username, password, salt string) error
+	DeleteAccount(ctx context.Context, username string) error
+}
+
+// SettingsStore holds the account settings operations
+type SettingsStore interface {
+	GetNotificationSetting(ctx context.Context, username string) (Settings, error)
+	ToggleNotificationSetting(ctx context.Context, username string) error
+	GetUnitSetting(ctx context.Context, username string) (UnitSettings, error)
+	UpdateUnitSetting(ctx context.Context, username, unitSystem string) error
+}
+
+// FoodStore holds the food catalog operations
+type FoodStore interface {
+	GetFoods(ctx context.Context, options ListOptions) (FoodPage, error)
+}
+
+// ShareRequestStore holds the share request operations
+type ShareRequestStore interface {
+	CreateShareRequest(ctx context.Context, fromUsername, toUsername, shareType, title string, idRequest int) error
+	GetShareRequests(ctx context.Context, username string) ([]ShareRequest, error)
+	GetShareRequest(ctx context.Context, shareID int) (ShareRequest, error)
+	DeleteShareRequest(ctx context.Context, shareID int) error
+	AcceptShareRequest(ctx context.Context, username string, shareID int) error
+}
+
+// StorageStore holds the storage operations
+type StorageStore interface {
+	CreateStorage(ctx context.Context, username, title string, owner bool) (int64, error)
+	GetStorages(ctx context.Context, username string, options ListOptions) (FolderPage, error)
+	GetStorage(ctx context.Context, storageID int) (Folder, error)
+	GetStoragesCount(ctx context.Context, username string) (int, error)
+	UpdateStorage(ctx context.Context, title string, storageID, version int) error
+	DeleteStorage(ctx context.Context, storageID, version int) error
+	ShareStorage(ctx context.Context, username string, storageID int) error
+	RemoveShareStorage(ctx context.Context, usernameRequest string, storageID int) error
+	GetStorageOwner(ctx context.Context, owner string, storageID int) (Share, error)
+}
+
+// StorageItemStore holds the storage item operations
+type StorageItemStore interface {
+	CreateStorageItem(ctx context.Context, storageID int, title string, quantity float64, quantityType string, quantityThreshold float64, expirationThreshold int, expirationDate string) (int64, error)
+	GetStorageItems(ctx context.Context, storageID int, options ListOptions) (ItemPage, error)
+	GetStorageItem(ctx context.Context, storageID, itemID int) (Item, error)
+	GetStorageItemsCount(ctx context.Context, username string) (int, error)
+	GetStorageItemTotals(ctx context.Context, username string) ([]ItemTotal, error)
+	UpdateStorageItem(ctx context.Context, title, image string, quantity float64, quantityType string, quantityThreshold float64, expirationThreshold int, expirationDate string, itemID, version int) error
+	DecrementStorageItemQuantity(ctx context.Context, by float64, itemID, version int) error
+	IncrementStorageItemQuantity(ctx context.Context, by float64, itemID, version int) error
+	DeleteStorageItem(ctx context.Context, itemID, version int) error
+	GetStorageItemHistory(ctx context.Context, storageID, itemID, cursor, limit int) (ItemHistory, error)
+	BatchStorageItems(ctx context.Context, storageID int, operations []ItemOperation, bestEffort bool) (BatchResult, error)
+}
+
+// ShoppingListStore holds the shopping list operations
+type ShoppingListStore interface {
+	CreateShoppingList(ctx context.Context, username, title string, owner bool) (int64, error)
+	GetShoppingLists(ctx context.Context, username string, options ListOptions) (ShoppingListPage, error)
+	GetShoppingList(ctx context.Context, shoppingListID int) (ShoppingList, error)
+	GetShoppingListsCount(ctx context.Context, username string) (int, error)
+	UpdateShoppingListTitle(ctx context.Context, title string, shoppingListID, version int) error
+	DeleteShoppingList(ctx context.Context, shoppingListID, version int) error
+	ShareShoppingList(ctx context.Context, username string, shoppingListID int) error
+	RemoveShareShoppingList(ctx context.Context, username string, shoppingListID int) error
+	GetShoppingListOwner(ctx context.Context, owner string, shoppingListID int) (bool, error)
+}
+
+// ShoppingListItemStore holds the shopping list item operations
+type ShoppingListItemStore interface {
+	CreateShoppingListItem(ctx context.Context, shoppingListID int, title string, quantity float64, quantityType string) (int64, error)
+	GetShoppingListItems(ctx context.Context, shoppingListID int, options ListOptions) (ShoppingListItemPage, error)
+	GetShoppingListItem(ctx context.Context, itemID int) (ShoppingListItem, error)
+	GetShoppingListItemsCount(ctx context.Context, username string) (int, error)
+	UpdateShoppingListItem(ctx context.Context, title string, quantity float64, quantityType string, itemID, version int) error
+	UpdateShoppingListItemTitle(ctx context.Context, title string, itemID, version int) error
+	DecrementShoppingListItemQuantity(ctx context.Context, by float64, itemID, version int) error
+	IncrementShoppingListItemQuantity(ctx context.Context, by float64, itemID, version int) error
+	DeleteShoppingListItem(ctx context.Context, itemID, version int) error
+	BatchShoppingListItems(ctx context.Context, shoppingListID int, operations []ItemOperation, bestEffort bool) (BatchResult, error)
+}
+
+// TrashStore holds the operations on the deleted storages, shopping lists and items.
+// Deleting a storage or shopping list moves it to the trash along with its items.
+type TrashStore interface {
+	GetTrash(ctx context.Context, username string) (Trash, error)
+	RestoreStorage(ctx context.Context, storageID int) error
+	RestoreStorageItem(ctx context.Context, itemID int) error
+	RestoreShoppingList(ctx context.Context, shoppingListID int) error
+	RestoreShoppingListItem(ctx context.Context, itemID int) error
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SearchStore holds the search across the storages and shopping lists of an account
+type SearchStore interface {
+	Search(ctx context.Context, username, q string, limit int) (SearchResults, error)
+}
+
+// CollaboratorStore finds the accounts sharing a storage or shopping list, whose views a write to it changes
+type CollaboratorStore interface {
+	GetStorageCollaborators(ctx context.Context, storageID int) ([]string, error)
+	GetStorageItemCollaborators(ctx context.Context, itemID int) ([]string, error)
+	GetShoppingListCollaborators(ctx context.Context, shoppingListID int) ([]string, error)
+	GetShoppingListItemCollaborators(ctx context.Context, itemID int) ([]string, error)
+}
+
+// SyncStore holds the delta sync of the clients keeping a copy of an account's storages and shopping lists,
+// and the push of the changes they made offline
+type SyncStore interface {
+	GetChanges(ctx context.Context, username, cursor string) (Changes, error)
+	PushChanges(ctx context.Context, username string, mutations []Mutation) (PushResult, error)
+}
+
+// ArchiveStore holds the export of the data of an account and its import, on this instance or another one
+type ArchiveStore interface {
+	ExportAccount(ctx context.Context, username string) (Archive, error)
+	ImportAccount(ctx context.Context, username string, archive Archive) (ImportReport, error)
+}
+
+// AlertStore holds the alerts raised for the storage items and their delivery to the accounts attached to their storage
+type AlertStore interface {
+	RaiseExpirationAlerts(ctx context.Context, now time.Time) (int, error)
+	GetPendingAlertDeliveries(ctx context.Context, after, limit int) ([]AlertDelivery, error)
+	MarkAlertDelivered(ctx context.Context, deliveryID int) error
+}
+
+var (
+	_ Store = (*Handler)(nil)
+	_ Store = (*Memory)(nil)
+	_ Store = (*Cache)(nil)
+)
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// testStore is a store a test runs against, named after its kind
+type testStore struct {
+	name  string
+	store Store
+}
+
+// testStores returns an empty Memory store and an empty SQLite database, so that a test runs against both alike
+func testStores(t *testing.T) []testStore {
+	return []testStore{
+		{name: "memory", store: NewMemory()},
+		{name: "sqlite", store: newTestSQLite(t)},
+	}
+}
+
+// newTestSQLite returns an empty SQLite database in a temporary file, migrated to the latest schema
+func newTestSQLite(t *testing.T) *Handler {
+	handler := InitSQLite(filepath.Join(t.TempDir(), "test.db"), DefaultOptions())
+	t.Cleanup(func() {
+		handler.DB.Close()
+	})
+
+	if err := handler.MigrateLatest(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return handler
+}
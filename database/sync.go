@@ -0,0 +1,442 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"time"
+)
+
+// syncOverlap is how far before the time of a sync its cursor points. The next sync reads the writes of that
+// window again, which catches the ones committed late by a transaction and the timestamps rounded to the second.
+const syncOverlap = 30 * time.Second
+
+// Reasons of a Tombstone
+const (
+	// TombstoneDeleted marks what was moved to the trash or deleted for good
+	TombstoneDeleted = "deleted"
+	// TombstoneAccessLost marks a storage or shopping list no longer shared with the account
+	TombstoneAccessLost = "access_lost"
+)
+
+// Tombstone tells a client to drop a storage, item, shopping list or share request.
+// The items of a storage or shopping list go along with it.
+type Tombstone struct {
+	Kind      string    `json:"kind"`
+	ID        int       `json:"id"`
+	Reason    string    `json:"reason"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// Changes holds what changed for an account since a cursor: the storages, items, shopping lists and
+// share requests created or updated, including the ones newly shared with it, and the tombstones of the others.
+// A storage, shopping list or item is either changed or tombstoned, never both.
+type Changes struct {
+	Storages          []Folder           `json:"storages"`
+	StorageItems      []Item             `json:"storageItems"`
+	ShoppingLists     []ShoppingList     `json:"shoppingLists"`
+	ShoppingListItems []ShoppingListItem `json:"shoppingListItems"`
+	ShareRequests     []ShareRequest     `json:"shareRequests"`
+	Tombstones        []Tombstone        `json:"tombstones"`
+	// Cursor is the since of the next sync
+	Cursor string `json:"cursor"`
+}
+
+// newChanges returns empty changes whose cursor points syncOverlap before now
+func newChanges(now time.Time) Changes {
+	return Changes{
+		Storages:          []Folder{},
+		StorageItems:      []Item{},
+		ShoppingLists:     []ShoppingList{},
+		ShoppingListItems: []ShoppingListItem{},
+		ShareRequests:     []ShareRequest{},
+		Tombstones:        []Tombstone{},
+		Cursor:            base64.RawURLEncoding.EncodeToString([]byte(now.Add(-syncOverlap).UTC().Format(time.RFC3339Nano))),
+	}
+}
+
+// errCursorExpired reports a sync cursor from before the last purge of the tombstones
+var errCursorExpired = &Error{Kind: ErrCursorExpired, Field: "cursor"}
+
+// syncSince decodes the time of a sync cursor, the zero time for an empty cursor
+func syncSince(cursor string) (time.Time, error) {
+	if cursor == "" {
+		return time.Time{}, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, errInvalidCursor
+	}
+
+	since, err := time.Parse(time.RFC3339Nano, string(decoded))
+	if err != nil {
+		return time.Time{}, errInvalidCursor
+	}
+
+	return since, nil
+}
+
+// settle drops the tombstones of what is also changed, keeps the latest tombstone of each row
+// and sorts them by kind and ID
+func (changes *Changes) settle() {
+	changed := map[string]map[int]bool{
+		KindStorage:          {},
+		KindStorageItem:      {},
+		KindShoppingList:     {},
+		KindShoppingListItem: {},
+		KindShareRequest:     {},
+	}
+	for _, folder := range changes.Storages {
+		changed[KindStorage][folder.ID] = true
+	}
+	for _, item := range changes.StorageItems {
+		changed[KindStorageItem][item.ID] = true
+	}
+	for _, sl := range changes.ShoppingLists {
+		changed[KindShoppingList][sl.ID] = true
+	}
+	for _, item := range changes.ShoppingListItems {
+		changed[KindShoppingListItem][item.ID] = true
+	}
+	for _, sr := range changes.ShareRequests {
+		changed[KindShareRequest][sr.ID] = true
+	}
+
+	latest := map[string]map[int]Tombstone{}
+	for _, tombstone := range changes.Tombstones {
+		if changed[tombstone.Kind][tombstone.ID] {
+			continue
+		}
+		if latest[tombstone.Kind] == nil {
+			latest[tombstone.Kind] = map[int]Tombstone{}
+		}
+		if previous, ok := latest[tombstone.Kind][tombstone.ID]; !ok || tombstone.DeletedAt.After(previous.DeletedAt) {
+			latest[tombstone.Kind][tombstone.ID] = tombstone
+		}
+	}
+
+	changes.Tombstones = []Tombstone{}
+	for _, byID := range latest {
+		for _, tombstone := range byID {
+			changes.Tombstones = append(changes.Tombstones, tombstone)
+		}
+	}
+	sort.Slice(changes.Tombstones, func(i, j int) bool {
+		a, b := changes.Tombstones[i], changes.Tombstones[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.ID < b.ID
+	})
+}
+
+// purgedBefore returns the time the tombstones were last purged up to, the zero time when they never were
+func (handler *Handler) purgedBefore(ctx context.Context) (time.Time, error) {
+	stmt, err := handler.prepare(ctx, `
+		SELECT purged_before
+		FROM sync_purges
+		ORDER BY purged_before DESC
+		LIMIT 1
+	`)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	defer stmt.Close()
+
+	purgedBefore := time.Time{}
+
+	if err := stmt.QueryRowContext(ctx).Scan(
+		&purgedBefore,
+	); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+
+	return purgedBefore, nil
+}
+
+// GetChanges gets what changed for username since cursor, everything it can see when cursor is empty
+func (handler *Handler) GetChanges(ctx context.Context, username, cursor string) (Changes, error) {
+	changes := newChanges(time.Now())
+
+	since, err := syncSince(cursor)
+	if err != nil {
+		return changes, err
+	}
+	sinceUTC := since.UTC()
+
+	if cursor != "" {
+		purgedBefore, err := handler.purgedBefore(ctx)
+		if err != nil {
+			return changes, err
+		}
+		if since.Before(purgedBefore) {
+			return changes, errCursorExpired
+		}
+	}
+
+	if err := handler.each(ctx, `
+		SELECT s.id, s.title, s.version, s.updated_at, s.created_at, COUNT(si.id)
+		FROM storages AS s
+		LEFT JOIN storage_items AS si
+		ON s.id = si.storage_id AND si.deleted_at IS NULL
+		INNER JOIN account_storage_binder AS asb
+		ON asb.storage_id = s.id AND asb.account_id = `+accountIDOf+`
+		WHERE s.deleted_at IS NULL AND (s.updated_at >= ? OR asb.created_at >= ?)
+		GROUP BY s.id
+		ORDER BY s.id
+	`, []interface{}{username, sinceUTC, sinceUTC}, func(row scanner) error {
+		folder := Folder{}
+		if err := row.Scan(
+			&folder.ID,
+			&folder.Title,
+			&folder.Version,
+			&folder.UpdatedAt,
+			&folder.CreatedAt,
+			&folder.Count,
+		); err != nil {
+			return err
+		}
+		changes.Storages = append(changes.Storages, folder)
+		return nil
+	}); err != nil {
+		return changes, err
+	}
+
+	// The items of a storage newly shared with the account are new to it too
+	if err := handler.each(ctx, `
+		SELECT `+qualify("si", itemColumns)+`
+		FROM storage_items AS si
+		INNER JOIN storages AS s
+		ON s.id = si.storage_id AND s.deleted_at IS NULL
+		INNER JOIN account_storage_binder AS asb
+		ON asb.storage_id = s.id AND asb.account_id = `+accountIDOf+`
+		WHERE si.deleted_at IS NULL AND (si.updated_at >= ? OR asb.created_at >= ?)
+		ORDER BY si.id
+	`, []interface{}{username, sinceUTC, sinceUTC}, func(row scanner) error {
+		item := Item{}
+		if err := scanItem(row, &item); err != nil {
+			return err
+		}
+		changes.StorageItems = append(changes.StorageItems, item)
+		return nil
+	}); err != nil {
+		return changes, err
+	}
+
+	if err := handler.each(ctx, `
+		SELECT sl.id, sl.title, sl.version, sl.updated_at, sl.created_at, COUNT(sli.id)
+		FROM shopping_lists AS sl
+		LEFT JOIN shopping_list_items AS sli
+		ON sl.id = sli.shopping_list_id AND sli.deleted_at IS NULL
+		INNER JOIN account_shopping_list_binder AS aslb
+		ON aslb.shopping_list_id = sl.id AND aslb.account_id = `+accountIDOf+`
+		WHERE sl.deleted_at IS NULL AND (sl.updated_at >= ? OR aslb.created_at >= ?)
+		GROUP BY sl.id
+		ORDER BY sl.id
+	`, []interface{}{username, sinceUTC, sinceUTC}, func(row scanner) error {
+		sl := ShoppingList{}
+		if err := row.Scan(
+			&sl.ID,
+			&sl.Title,
+			&sl.Version,
+			&sl.UpdatedAt,
+			&sl.CreatedAt,
+			&sl.Count,
+		); err != nil {
+			return err
+		}
+		changes.ShoppingLists = append(changes.ShoppingLists, sl)
+		return nil
+	}); err != nil {
+		return changes, err
+	}
+
+	if err := handler.each(ctx, `
+		SELECT `+qualify("sli", shoppingListItemColumns)+`
+		FROM shopping_list_items AS sli
+		INNER JOIN shopping_lists AS sl
+		ON sl.id = sli.shopping_list_id AND sl.deleted_at IS NULL
+		INNER JOIN account_shopping_list_binder AS aslb
+		ON aslb.shopping_list_id = sl.id AND aslb.account_id = `+accountIDOf+`
+		WHERE sli.deleted_at IS NULL AND (sli.updated_at >= ? OR aslb.created_at >= ?)
+		ORDER BY sli.id
+	`, []interface{}{username, sinceUTC, sinceUTC}, func(row scanner) error {
+		item := ShoppingListItem{}
+		if err := scanShoppingListItem(row, &item); err != nil {
+			return err
+		}
+		changes.ShoppingListItems = append(changes.ShoppingListItems, item)
+		return nil
+	}); err != nil {
+		return changes, err
+	}
+
+	if err := handler.each(ctx, `
+		SELECT `+shareRequestColumns+`
+		FROM `+shareRequestTables+`
+		WHERE ta.username = ? AND sr.created_at >= ?
+		ORDER BY sr.id
+	`, []interface{}{username, sinceUTC}, func(row scanner) error {
+		sr := ShareRequest{}
+		if err := row.Scan(
+			&sr.ID,
+			&sr.FromUsername,
+			&sr.ToUsername,
+			&sr.ShareType,
+			&sr.Title,
+			&sr.IDRequest,
+			&sr.CreatedAt,
+		); err != nil {
+			return err
+		}
+		changes.ShareRequests = append(changes.ShareRequests, sr)
+		return nil
+	}); err != nil {
+		return changes, err
+	}
+
+	// A client syncing from scratch has nothing to drop
+	if since.IsZero() {
+		return changes, nil
+	}
+
+	if err := handler.trashedSince(ctx, &changes, sinceUTC, username); err != nil {
+		return changes, err
+	}
+
+	if err := handler.each(ctx, `
+		SELECT kind, entity_id, reason, created_at
+		FROM sync_tombstones
+		WHERE account_id = `+accountIDOf+` AND created_at >= ?
+	`, []interface{}{username, sinceUTC}, func(row scanner) error {
+		tombstone := Tombstone{}
+		if err := row.Scan(
+			&tombstone.Kind,
+			&tombstone.ID,
+			&tombstone.Reason,
+			&tombstone.DeletedAt,
+		); err != nil {
+			return err
+		}
+		changes.Tombstones = append(changes.Tombstones, tombstone)
+		return nil
+	}); err != nil {
+		return changes, err
+	}
+
+	changes.settle()
+
+	return changes, nil
+}
+
+// trashedSince adds the tombstones of what was moved to the trash since since in the storages and shopping lists attached to username
+func (handler *Handler) trashedSince(ctx context.Context, changes *Changes, since time.Time, username string) error {
+	queries := []struct {
+		kind  string
+		query string
+	}{
+		{KindStorage, `
+			SELECT s.id, s.deleted_at
+			FROM storages AS s
+			INNER JOIN account_storage_binder AS asb
+			ON asb.storage_id = s.id AND asb.account_id = ` + accountIDOf + `
+			WHERE s.deleted_at >= ?
+		`},
+		{KindStorageItem, `
+			SELECT si.id, si.deleted_at
+			FROM storage_items AS si
+			INNER JOIN account_storage_binder AS asb
+			ON asb.storage_id = si.storage_id AND asb.account_id = ` + accountIDOf + `
+			WHERE si.deleted_at >= ?
+		`},
+		{KindShoppingList, `
+			SELECT sl.id, sl.deleted_at
+			FROM shopping_lists AS sl
+			INNER JOIN account_shopping_list_binder AS aslb
+			ON aslb.shopping_list_id = sl.id AND aslb.account_id = ` + accountIDOf + `
+			WHERE sl.deleted_at >= ?
+		`},
+		{KindShoppingListItem, `
+			SELECT sli.id, sli.deleted_at
+			FROM shopping_list_items AS sli
+			INNER JOIN account_shopping_list_binder AS aslb
+			ON aslb.shopping_list_id = sli.shopping_list_id AND aslb.account_id = ` + accountIDOf + `
+			WHERE sli.deleted_at >= ?
+		`},
+	}
+
+	for _, q := range queries {
+		if err := handler.each(ctx, q.query, []interface{}{username, since}, func(row scanner) error {
+			tombstone := Tombstone{Kind: q.kind, Reason: TombstoneDeleted}
+			if err := row.Scan(
+				&tombstone.ID,
+				&tombstone.DeletedAt,
+			); err != nil {
+				return err
+			}
+			changes.Tombstones = append(changes.Tombstones, tombstone)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// each runs query and calls scan on every row it selects
+func (handler *Handler) each(ctx context.Context, query string, args []interface{}, scan func(row scanner) error) error {
+	stmt, err := handler.prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// recordTombstones records for each of usernames that the row id of kind is gone for reason,
+// skipping the usernames no account has as nobody is left to sync
+func (handler *Handler) recordTombstones(ctx context.Context, usernames []string, kind string, id int, reason string) error {
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		INSERT INTO sync_tombstones(account_id, kind, entity_id, reason)
+		SELECT id, ?, ?, ?
+		FROM accounts
+		WHERE username = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	for _, username := range usernames {
+		if _, err := handler.exec(ctx, stmt, kind, id, reason, username); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
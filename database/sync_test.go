@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetChangesExpiredCursor(t *testing.T) {
+	for _, s := range testStores(t) {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if _, err := s.store.CreateAccount(ctx, "ann", "ann@example.com", "password", "salt"); err != nil {
+				t.Fatal(err)
+			}
+
+			old := newChanges(time.Now().Add(-2 * time.Hour)).Cursor
+			recent := newChanges(time.Now()).Cursor
+
+			if _, err := s.store.GetChanges(ctx, "ann", old); err != nil {
+				t.Errorf("cursor before any purge, error = %v", err)
+			}
+
+			if _, err := s.store.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.store.GetChanges(ctx, "ann", old); !errors.Is(err, ErrCursorExpired) {
+				t.Errorf("cursor before the purge, error = %v, want %v", err, ErrCursorExpired)
+			}
+			if _, err := s.store.GetChanges(ctx, "ann", recent); err != nil {
+				t.Errorf("cursor after the purge, error = %v", err)
+			}
+			if _, err := s.store.GetChanges(ctx, "ann", ""); err != nil {
+				t.Errorf("no cursor, error = %v", err)
+			}
+
+			// A purge with a longer retention does not bring the purged tombstones back
+			if _, err := s.store.PurgeTrash(ctx, time.Now().Add(-3*time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.store.GetChanges(ctx, "ann", old); !errors.Is(err, ErrCursorExpired) {
+				t.Errorf("cursor before the first purge, error = %v, want %v", err, ErrCursorExpired)
+			}
+		})
+	}
+}
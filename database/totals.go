@@ -0,0 +1,105 @@
+package database
+
+import (
+	"cat-clerk-api/units"
+	"context"
+	"sort"
+	"strings"
+)
+
+// ItemTotal is the quantity of the storage items sharing a title, regardless of case, and a dimension
+// across the storages of an account
+type ItemTotal struct {
+	Title        string  `json:"title"`
+	Quantity     float64 `json:"quantity"`
+	QuantityType string  `json:"quantityType"`
+	// Items is how many storage items make the total
+	Items int `json:"items"`
+	// Display is the total in the unit system of the account, set by the API
+	Display *units.Quantity `json:"display,omitempty"`
+}
+
+// itemTotals sums items by title and dimension, sorted by title then dimension.
+// The title of a total is the one of its first item.
+func itemTotals(items []Item) ([]ItemTotal, error) {
+	type key struct {
+		title     string
+		dimension string
+	}
+
+	totals := map[key]*ItemTotal{}
+	keys := []key{}
+
+	for _, item := range items {
+		dimension, err := units.DimensionOf(item.QuantityType)
+		if err != nil {
+			return nil, err
+		}
+
+		k := key{title: strings.ToLower(item.Title), dimension: dimension}
+		quantity := units.Quantity{Amount: item.Quantity, Unit: item.QuantityType}
+
+		total, ok := totals[k]
+		if !ok {
+			totals[k] = &ItemTotal{Title: item.Title, Quantity: quantity.Amount, QuantityType: quantity.Unit, Items: 1}
+			keys = append(keys, k)
+			continue
+		}
+
+		sum, err := units.Add(units.Quantity{Amount: total.Quantity, Unit: total.QuantityType}, quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		total.Quantity, total.QuantityType = sum.Amount, sum.Unit
+		total.Items++
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].title != keys[j].title {
+			return keys[i].title < keys[j].title
+		}
+		return keys[i].dimension < keys[j].dimension
+	})
+
+	result := []ItemTotal{}
+	for _, k := range keys {
+		result = append(result, *totals[k])
+	}
+
+	return result, nil
+}
+
+// GetStorageItemTotals sums the storage items attached to username by title and dimension, out of the trash
+func (handler *Handler) GetStorageItemTotals(ctx context.Context, username string) ([]ItemTotal, error) {
+	items := []Item{}
+
+	if err := handler.each(ctx, `
+		SELECT si.title, si.quantity, si.quantity_type
+		FROM storage_items AS si
+		INNER JOIN storages AS s
+		ON s.id = si.storage_id
+		INNER JOIN account_storage_binder AS asb
+		ON asb.storage_id = s.id
+		WHERE asb.account_id = `+accountIDOf+` AND si.deleted_at IS NULL AND s.deleted_at IS NULL
+		ORDER BY si.id
+	`, []interface{}{username}, func(row scanner) error {
+		item := Item{}
+
+		if err := row.Scan(
+			&item.Title,
+			&item.Quantity,
+			&item.QuantityType,
+		); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return itemTotals(items)
+}
@@ -0,0 +1,410 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Trash holds the storages, shopping lists and items an account can restore.
+// The items of a trashed storage or shopping list are not listed on their own,
+// they come back with it and are part of its count.
+type Trash struct {
+	Storages          []Folder           `json:"storages"`
+	StorageItems      []Item             `json:"storageItems"`
+	ShoppingLists     []ShoppingList     `json:"shoppingLists"`
+	ShoppingListItems []ShoppingListItem `json:"shoppingListItems"`
+}
+
+// qualify prefixes every column of columns with alias
+func qualify(alias, columns string) string {
+	return alias + "." + strings.Join(strings.Split(columns, ", "), ", "+alias+".")
+}
+
+// checkNotTrashed returns errForeignKeyChild unless the row id of table exists and is not in the trash
+func (handler *Handler) checkNotTrashed(ctx context.Context, table string, id int) error {
+	stmt, err := handler.prepare(ctx, `
+		SELECT COUNT(*)
+		FROM `+table+`
+		WHERE id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	count := 0
+
+	if err := stmt.QueryRowContext(ctx, id).Scan(
+		&count,
+	); err != nil {
+		return err
+	}
+
+	if count < 1 {
+		return errForeignKeyChild
+	}
+
+	return nil
+}
+
+// GetTrash gets the trashed storages, shopping lists and items attached to an account by username, latest first
+func (handler *Handler) GetTrash(ctx context.Context, username string) (Trash, error) {
+	trash := Trash{}
+
+	storages, err := handler.trashedStorages(ctx, username)
+	if err != nil {
+		return trash, err
+	}
+
+	storageItems, err := handler.trashedStorageItems(ctx, username)
+	if err != nil {
+		return trash, err
+	}
+
+	shoppingLists, err := handler.trashedShoppingLists(ctx, username)
+	if err != nil {
+		return trash, err
+	}
+
+	shoppingListItems, err := handler.trashedShoppingListItems(ctx, username)
+	if err != nil {
+		return trash, err
+	}
+
+	return Trash{
+		Storages:          storages,
+		StorageItems:      storageItems,
+		ShoppingLists:     shoppingLists,
+		ShoppingListItems: shoppingListItems,
+	}, nil
+}
+
+// trashedStorages gets the trashed storages attached to an account, counting the items trashed with them
+func (handler *Handler) trashedStorages(ctx context.Context, username string) ([]Folder, error) {
+	folders := []Folder{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT s.id, s.title, s.version, s.deleted_at, s.updated_at, s.created_at, COUNT(si.id)
+		FROM storages AS s
+		LEFT JOIN storage_items AS si
+		ON s.id = si.storage_id AND si.deleted_at = s.deleted_at
+		INNER JOIN account_storage_binder AS asb
+		ON asb.storage_id = s.id AND asb.account_id = `+accountIDOf+`
+		WHERE s.deleted_at IS NOT NULL
+		GROUP BY s.id
+		ORDER BY s.deleted_at DESC, s.id
+	`)
+	if err != nil {
+		return folders, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return folders, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		folder := Folder{}
+
+		if err := rows.Scan(
+			&folder.ID,
+			&folder.Title,
+			&folder.Version,
+			&folder.DeletedAt,
+			&folder.UpdatedAt,
+			&folder.CreatedAt,
+			&folder.Count,
+		); err != nil {
+			return folders, err
+		}
+
+		folders = append(folders, folder)
+	}
+
+	return folders, rows.Err()
+}
+
+// trashedStorageItems gets the trashed items of the storages attached to an account which are not in the trash themselves
+func (handler *Handler) trashedStorageItems(ctx context.Context, username string) ([]Item, error) {
+	items := []Item{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+qualify("si", itemColumns)+`
+		FROM storage_items AS si
+		INNER JOIN storages AS s
+		ON s.id = si.storage_id AND s.deleted_at IS NULL
+		INNER JOIN account_storage_binder AS asb
+		ON asb.storage_id = si.storage_id AND asb.account_id = `+accountIDOf+`
+		WHERE si.deleted_at IS NOT NULL
+		ORDER BY si.deleted_at DESC, si.id
+	`)
+	if err != nil {
+		return items, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return items, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		item := Item{}
+
+		if err := scanItem(rows, &item); err != nil {
+			return items, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// trashedShoppingLists gets the trashed shopping lists attached to an account, counting the items trashed with them
+func (handler *Handler) trashedShoppingLists(ctx context.Context, username string) ([]ShoppingList, error) {
+	shoppingLists := []ShoppingList{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT sl.id, sl.title, sl.version, sl.deleted_at, sl.updated_at, sl.created_at, COUNT(sli.id)
+		FROM shopping_lists AS sl
+		LEFT JOIN shopping_list_items AS sli
+		ON sl.id = sli.shopping_list_id AND sli.deleted_at = sl.deleted_at
+		INNER JOIN account_shopping_list_binder AS aslb
+		ON aslb.shopping_list_id = sl.id AND aslb.account_id = `+accountIDOf+`
+		WHERE sl.deleted_at IS NOT NULL
+		GROUP BY sl.id
+		ORDER BY sl.deleted_at DESC, sl.id
+	`)
+	if err != nil {
+		return shoppingLists, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return shoppingLists, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		sl := ShoppingList{}
+
+		if err := rows.Scan(
+			&sl.ID,
+			&sl.Title,
+			&sl.Version,
+			&sl.DeletedAt,
+			&sl.UpdatedAt,
+			&sl.CreatedAt,
+			&sl.Count,
+		); err != nil {
+			return shoppingLists, err
+		}
+
+		shoppingLists = append(shoppingLists, sl)
+	}
+
+	return shoppingLists, rows.Err()
+}
+
+// trashedShoppingListItems gets the trashed items of the shopping lists attached to an account which are not in the trash themselves
+func (handler *Handler) trashedShoppingListItems(ctx context.Context, username string) ([]ShoppingListItem, error) {
+	items := []ShoppingListItem{}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT `+qualify("sli", shoppingListItemColumns)+`
+		FROM shopping_list_items AS sli
+		INNER JOIN shopping_lists AS sl
+		ON sl.id = sli.shopping_list_id AND sl.deleted_at IS NULL
+		INNER JOIN account_shopping_list_binder AS aslb
+		ON aslb.shopping_list_id = sli.shopping_list_id AND aslb.account_id = `+accountIDOf+`
+		WHERE sli.deleted_at IS NOT NULL
+		ORDER BY sli.deleted_at DESC, sli.id
+	`)
+	if err != nil {
+		return items, err
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return items, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		item := ShoppingListItem{}
+
+		if err := scanShoppingListItem(rows, &item); err != nil {
+			return items, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// restoreContainer brings the row id of table back from the trash, along with the rows of items
+// referring to it by fk which were trashed at the same time
+func (handler *Handler) restoreContainer(ctx context.Context, table, items, fk string, id int) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		if _, err := tx.run(ctx, `
+			UPDATE `+items+`
+			SET deleted_at = NULL
+			WHERE `+fk+` = ? AND deleted_at = (SELECT deleted_at FROM `+table+` WHERE id = ?)
+		`, id, id); err != nil {
+			return err
+		}
+
+		result, err := tx.run(ctx, `
+			UPDATE `+table+`
+			SET deleted_at = NULL, version = version + 1
+			WHERE id = ? AND deleted_at IS NOT NULL
+		`, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected < 1 {
+			return errNoRowsAffected
+		}
+
+		return nil
+	})
+}
+
+// restoreItem brings the row id of items back from the trash, provided the row of table it refers to by fk is not in the trash
+func (handler *Handler) restoreItem(ctx context.Context, items, table, fk string, id int) error {
+	result, err := handler.run(ctx, `
+		UPDATE `+items+`
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = ? AND deleted_at IS NOT NULL
+		AND `+fk+` IN (SELECT id FROM `+table+` WHERE deleted_at IS NULL)
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected < 1 {
+		return errNoRowsAffected
+	}
+
+	return nil
+}
+
+// RestoreStorage brings a storage back from the trash by ID, along with the items trashed with it
+func (handler *Handler) RestoreStorage(ctx context.Context, storageID int) error {
+	return handler.withTx(ctx, func(tx *Handler) error {
+		itemIDs, err := tx.ids(ctx, `
+			SELECT id
+			FROM storage_items
+			WHERE storage_id = ? AND deleted_at = (SELECT deleted_at FROM storages WHERE id = ?)
+		`, storageID, storageID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.restoreContainer(ctx, "storages", "storage_items", "storage_id", storageID); err != nil {
+			return err
+		}
+
+		return tx.recordItemEvents(ctx, itemIDs, deletion(false))
+	})
+}
+
+// RestoreStorageItem brings a storage item back from the trash by ID, unless its storage is in the trash
+func (handler *Handler) RestoreStorageItem(ctx context.Context, itemID int) error {
+	return handler.recordingItem(ctx, itemID, func(tx *Handler) error {
+		return tx.restoreItem(ctx, "storage_items", "storages", "storage_id", itemID)
+	})
+}
+
+// RestoreShoppingList brings a shopping list back from the trash by ID, along with the items trashed with it
+func (handler *Handler) RestoreShoppingList(ctx context.Context, shoppingListID int) error {
+	return handler.restoreContainer(ctx, "shopping_lists", "shopping_list_items", "shopping_list_id", shoppingListID)
+}
+
+// RestoreShoppingListItem brings a shopping list item back from the trash by ID, unless its shopping list is in the trash
+func (handler *Handler) RestoreShoppingListItem(ctx context.Context, itemID int) error {
+	return handler.restoreItem(ctx, "shopping_list_items", "shopping_lists", "shopping_list_id", itemID)
+}
+
+// PurgeTrash deletes for good what was trashed before before, along with the tombstones and pushed mutations
+// as old, and returns the number of trashed rows deleted
+func (handler *Handler) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	purged := int64(0)
+
+	err := handler.withTx(ctx, func(tx *Handler) error {
+		// The items go first: the items of a container were trashed with it or earlier,
+		// and shopping_list_items does not cascade
+		for _, table := range []string{"storage_items", "storages", "shopping_list_items", "shopping_lists"} {
+			result, err := tx.run(ctx, `
+				DELETE FROM `+table+`
+				WHERE deleted_at < ?
+			`, before.UTC())
+			if err != nil {
+				return err
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			purged += rowsAffected
+		}
+
+		// Tombstones and pushed mutations last as long as the trash: a client away for longer
+		// syncs from scratch, as GetChanges tells it from the purge recorded
+		for _, table := range []string{"sync_tombstones", "pushed_mutations"} {
+			if _, err := tx.run(ctx, `
+				DELETE FROM `+table+`
+				WHERE created_at < ?
+			`, before.UTC()); err != nil {
+				return err
+			}
+		}
+
+		// The latest purge is kept, a shorter retention does not bring the purged tombstones back
+		if _, err := tx.run(ctx, `
+			INSERT INTO sync_purges(purged_before)
+			VALUES(?)
+		`, before.UTC()); err != nil {
+			return err
+		}
+
+		_, err := tx.run(ctx, `
+			DELETE FROM sync_purges
+			WHERE purged_before < ?
+		`, before.UTC())
+
+		return err
+	})
+
+	return purged, err
+}
@@ -0,0 +1,40 @@
+package database
+
+import "context"
+
+// AnyVersion is the version expected by the writes which apply whatever the row's version.
+// Storages, storage items, shopping lists and shopping list items start at version 1
+// and every update increments it.
+const AnyVersion = 0
+
+// missedWrite explains why a write of the row id of table, expecting version, affected no row
+func (handler *Handler) missedWrite(ctx context.Context, table string, id, version int) error {
+	if version == AnyVersion {
+		return errNoRowsAffected
+	}
+
+	stmt, err := handler.prepare(ctx, `
+		SELECT version
+		FROM `+table+`
+		WHERE id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	current := 0
+
+	if err := stmt.QueryRowContext(ctx, id).Scan(
+		&current,
+	); err != nil {
+		return rowError(err)
+	}
+
+	if current != version {
+		return errStale
+	}
+
+	return errNoRowsAffected
+}
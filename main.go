@@ -6,6 +6,8 @@ import (
 	"cat-clerk-api/database"
 	"cat-clerk-api/mail"
 	"cat-clerk-api/util"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -18,13 +20,62 @@ import (
 func main() {
 	cfg := newConfig()
 
-	db := database.Init(cfg.DBUser, cfg.DBPass, cfg.DBName, cfg.DBHost, cfg.DBPort)
+	ctx := context.Background()
 
-	if err := db.EnsureConnected(); err != nil {
+	var db *database.Handler
+
+	options := cfg.dbOptions()
+
+	switch options.TLS {
+	case database.TLSDisable, database.TLSRequire, database.TLSVerify:
+	default:
+		log.Fatalf("unknown database TLS mode %q", options.TLS)
+	}
+
+	switch cfg.DBDriver {
+	case "mysql":
+		db = database.Init(cfg.DBUser, cfg.DBPass, cfg.DBName, cfg.DBHost, cfg.DBPort, options)
+	case "postgres":
+		db = database.InitPostgres(cfg.DBUser, cfg.DBPass, cfg.DBName, cfg.DBHost, cfg.DBPort, options)
+	case "sqlite":
+		db = database.InitSQLite(cfg.DBPath, options)
+	default:
+		log.Fatalf("unknown database driver %q", cfg.DBDriver)
+	}
+
+	if err := db.EnsureConnected(ctx); err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	commands := map[string]func(context.Context, *database.Handler, []string) error{
+		"migrate": migrate,
+		"backup":  backup,
+		"restore": restore,
+	}
+
+	if command, ok := commands[flag.Arg(0)]; ok {
+		if err := command(ctx, db, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if cfg.AutoMigrate {
+		if err := db.MigrateLatest(ctx); err != nil {
+			log.Fatal(err)
+			return
+		}
+	}
+
+	if err := db.CheckSchema(ctx); err != nil {
 		log.Fatal(err)
 		return
 	}
 
+	go db.ProbeHealth(ctx)
+	go purgeTrash(ctx, db, cfg.TrashRetention, cfg.TrashPurgeInterval)
+
 	util.Init(cfg.Salt)
 
 	mail.OAuthGmailService(
@@ -34,11 +85,28 @@ func main() {
 		cfg.GmailRefreshToken,
 	)
 
+	go raiseAlerts(ctx, db, cfg.AlertInterval)
+
 	router := mux.NewRouter().StrictSlash(true)
 
 	auth := auth.New(router, []byte(cfg.HMAC))
 
-	restAPI := api.Init(router, db, auth)
+	routeTimeouts, err := api.ParseRouteTimeouts(cfg.RouteTimeouts)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	var store database.Store = db
+	if cfg.CacheSize > 0 {
+		store = database.NewCache(db, database.CacheOptions{Size: cfg.CacheSize, TTL: cfg.CacheTTL})
+	}
+
+	restAPI := api.Init(router, store, auth)
+	restAPI.Timeouts = api.Timeouts{
+		Default: cfg.RequestTimeout,
+		Routes:  routeTimeouts,
+	}
 
 	router = restAPI.Handlers()
 
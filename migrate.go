@@ -0,0 +1,72 @@
+package main
+
+import (
+	"cat-clerk-api/database"
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// migrate runs the migrate subcommand: migrate [up | down | status | to <version>]
+func migrate(ctx context.Context, db *database.Handler, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	migrations, err := db.Migrations()
+	if err != nil {
+		return err
+	}
+
+	version, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		err = db.Migrate(ctx, len(migrations))
+	case "down":
+		if version == 0 {
+			return fmt.Errorf("no migration to revert")
+		}
+		err = db.Migrate(ctx, version-1)
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: migrate to <version>")
+		}
+
+		target, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+
+		if err := db.Migrate(ctx, target); err != nil {
+			return err
+		}
+	case "status":
+		for _, migration := range migrations {
+			state := "pending"
+			if migration.Version <= version {
+				state = "applied"
+			}
+			fmt.Printf("%04d %-40s %s\n", migration.Version, migration.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down, status or to", command)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	version, err = db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("schema version %d of %d\n", version, len(migrations))
+
+	return nil
+}
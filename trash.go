@@ -0,0 +1,33 @@
+package main
+
+import (
+	"cat-clerk-api/database"
+	"context"
+	"log"
+	"time"
+)
+
+// purgeTrash deletes for good, every interval until ctx is done, what has been in the trash for longer than retention
+func purgeTrash(ctx context.Context, store database.Store, retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := store.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("trash purge failed: %v", err)
+		} else if purged > 0 {
+			log.Printf("trash purge deleted %d rows", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package units
+
+import (
+	"errors"
+	"math"
+)
+
+// Systems a quantity is displayed in
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// Dimensions of the units, quantities of different dimensions are never compared nor summed
+const (
+	Mass   = "mass"
+	Volume = "volume"
+	Count  = "count"
+)
+
+// Errors of the conversions
+var (
+	ErrUnknownUnit  = errors.New("unknown unit")
+	ErrIncompatible = errors.New("units of different dimensions")
+)
+
+// unit is a unit of a dimension, factor times the base unit of the dimension: grams, milliliters or pieces.
+// A quantity of the unit holds at most precision decimals.
+type unit struct {
+	dimension string
+	factor    float64
+	precision int
+}
+
+// units holds the known units. Items are stored in the metric ones and pieces, the imperial ones are for display
+// and conversions only. fluid_ounces and pints are the imperial (UK) ones, 28.41 and 568.26 milliliters,
+// not the US customary ones of 29.57 and 473.18 milliliters.
+var units = map[string]unit{
+	"grams":        {dimension: Mass, factor: 1, precision: 1},
+	"kilos":        {dimension: Mass, factor: 1000, precision: 3},
+	"ounces":       {dimension: Mass, factor: 28.349523125, precision: 2},
+	"pounds":       {dimension: Mass, factor: 453.59237, precision: 3},
+	"milliliters":  {dimension: Volume, factor: 1, precision: 1},
+	"liters":       {dimension: Volume, factor: 1000, precision: 3},
+	"fluid_ounces": {dimension: Volume, factor: 28.4130625, precision: 2},
+	"pints":        {dimension: Volume, factor: 568.26125, precision: 3},
+	"pieces":       {dimension: Count, factor: 1, precision: 0},
+}
+
+// displayUnits are the units a quantity is displayed in for each system and dimension, the smallest first.
+// A quantity takes the largest unit it holds at least one of.
+var displayUnits = map[string]map[string][]string{
+	Metric: {
+		Mass:   {"grams", "kilos"},
+		Volume: {"milliliters", "liters"},
+	},
+	Imperial: {
+		Mass:   {"ounces", "pounds"},
+		Volume: {"fluid_ounces", "pints"},
+	},
+}
+
+// Quantity is an amount of a unit
+type Quantity struct {
+	Amount float64 `json:"quantity"`
+	Unit   string  `json:"quantityType"`
+}
+
+// Known returns whether name is a known unit
+func Known(name string) bool {
+	_, ok := units[name]
+	return ok
+}
+
+// KnownSystem returns whether system is a known system
+func KnownSystem(system string) bool {
+	_, ok := displayUnits[system]
+	return ok
+}
+
+// DimensionOf returns the dimension of the unit name
+func DimensionOf(name string) (string, error) {
+	u, ok := units[name]
+	if !ok {
+		return "", ErrUnknownUnit
+	}
+	return u.dimension, nil
+}
+
+// Precision returns the decimals a quantity of the unit name holds at most, 0 when it is unknown
+func Precision(name string) int {
+	return units[name].precision
+}
+
+// Fits returns whether q holds no more decimals than the precision of its unit, pieces being whole
+func Fits(q Quantity) bool {
+	return isWhole(q.Amount * math.Pow10(Precision(q.Unit)))
+}
+
+// Round rounds q to the precision of its unit
+func Round(q Quantity) Quantity {
+	scale := math.Pow10(Precision(q.Unit))
+	return Quantity{Amount: math.Round(q.Amount*scale) / scale, Unit: q.Unit}
+}
+
+// Factor returns the size of the unit name in the base unit of its dimension, 0 when it is unknown
+func Factor(name string) float64 {
+	return units[name].factor
+}
+
+// Normalize converts q to the base unit of its dimension
+func Normalize(q Quantity) (Quantity, error) {
+	u, ok := units[q.Unit]
+	if !ok {
+		return q, ErrUnknownUnit
+	}
+
+	base := map[string]string{Mass: "grams", Volume: "milliliters", Count: "pieces"}[u.dimension]
+
+	return Quantity{Amount: q.Amount * u.factor, Unit: base}, nil
+}
+
+// Convert converts q to the unit to, of the same dimension
+func Convert(q Quantity, to string) (Quantity, error) {
+	from, ok := units[q.Unit]
+	if !ok {
+		return q, ErrUnknownUnit
+	}
+
+	target, ok := units[to]
+	if !ok {
+		return q, ErrUnknownUnit
+	}
+
+	if from.dimension != target.dimension {
+		return q, ErrIncompatible
+	}
+
+	if q.Unit == to {
+		return q, nil
+	}
+
+	return Quantity{Amount: q.Amount * from.factor / target.factor, Unit: to}, nil
+}
+
+// Compare returns -1, 0 or 1 as a is less than, equal to or more than b, of the same dimension
+func Compare(a, b Quantity) (int, error) {
+	b, err := Convert(b, a.Unit)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case a.Amount < b.Amount:
+		return -1, nil
+	case a.Amount > b.Amount:
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// Add sums a and b, of the same dimension. The sum is in the unit of a when it fits its precision, in the smaller
+// of their units otherwise, rounded to its precision: 1 kilos and 500 grams make 1.5 kilos
+// while 1 kilos and 0.5 grams make 1000.5 grams.
+func Add(a, b Quantity) (Quantity, error) {
+	y, err := Convert(b, a.Unit)
+	if err != nil {
+		return a, err
+	}
+
+	if sum := (Quantity{Amount: a.Amount + y.Amount, Unit: a.Unit}); Fits(sum) {
+		return Round(sum), nil
+	}
+
+	small := a.Unit
+	if units[b.Unit].factor < units[small].factor {
+		small = b.Unit
+	}
+
+	x, _ := Convert(a, small)
+	y, _ = Convert(b, small)
+
+	return Round(Quantity{Amount: x.Amount + y.Amount, Unit: small}), nil
+}
+
+// Display converts q to the unit it reads best in with system, rounded to two decimals.
+// Pieces and unknown units are left as they are.
+func Display(q Quantity, system string) Quantity {
+	u, ok := units[q.Unit]
+	if !ok {
+		return q
+	}
+
+	names := displayUnits[system][u.dimension]
+	if len(names) == 0 {
+		return q
+	}
+
+	display, _ := Convert(q, names[0])
+	for _, name := range names[1:] {
+		if converted, _ := Convert(q, name); math.Abs(converted.Amount) >= 1 {
+			display = converted
+		}
+	}
+
+	display.Amount = math.Round(display.Amount*100) / 100
+
+	return display
+}
+
+// isWhole returns whether f is an integer, up to the rounding errors of the conversions
+func isWhole(f float64) bool {
+	return math.Abs(f-math.Round(f)) < 1e-6
+}
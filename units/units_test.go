@@ -0,0 +1,162 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+// near tells whether a and b are equal up to the rounding errors of the conversions
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       Quantity
+		to      string
+		want    Quantity
+		wantErr error
+	}{
+		{name: "same unit", q: Quantity{Amount: 3, Unit: "grams"}, to: "grams", want: Quantity{Amount: 3, Unit: "grams"}},
+		{name: "kilos to grams", q: Quantity{Amount: 1.5, Unit: "kilos"}, to: "grams", want: Quantity{Amount: 1500, Unit: "grams"}},
+		{name: "grams to kilos", q: Quantity{Amount: 250, Unit: "grams"}, to: "kilos", want: Quantity{Amount: 0.25, Unit: "kilos"}},
+		{name: "pounds to grams", q: Quantity{Amount: 1, Unit: "pounds"}, to: "grams", want: Quantity{Amount: 453.59237, Unit: "grams"}},
+		{name: "pounds to ounces", q: Quantity{Amount: 1, Unit: "pounds"}, to: "ounces", want: Quantity{Amount: 16, Unit: "ounces"}},
+		{name: "liters to milliliters", q: Quantity{Amount: 0.5, Unit: "liters"}, to: "milliliters", want: Quantity{Amount: 500, Unit: "milliliters"}},
+		{name: "imperial pints to fluid ounces", q: Quantity{Amount: 1, Unit: "pints"}, to: "fluid_ounces", want: Quantity{Amount: 20, Unit: "fluid_ounces"}},
+		{name: "imperial pints to milliliters", q: Quantity{Amount: 2, Unit: "pints"}, to: "milliliters", want: Quantity{Amount: 1136.5225, Unit: "milliliters"}},
+		{name: "pieces", q: Quantity{Amount: 4, Unit: "pieces"}, to: "pieces", want: Quantity{Amount: 4, Unit: "pieces"}},
+		{name: "mass to volume", q: Quantity{Amount: 1, Unit: "kilos"}, to: "liters", wantErr: ErrIncompatible},
+		{name: "pieces to mass", q: Quantity{Amount: 1, Unit: "pieces"}, to: "grams", wantErr: ErrIncompatible},
+		{name: "unknown from", q: Quantity{Amount: 1, Unit: "cups"}, to: "grams", wantErr: ErrUnknownUnit},
+		{name: "unknown to", q: Quantity{Amount: 1, Unit: "grams"}, to: "cups", wantErr: ErrUnknownUnit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Convert(test.q, test.to)
+			if err != test.wantErr {
+				t.Fatalf("Convert(%v, %q) error = %v, want %v", test.q, test.to, err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Unit != test.want.Unit || !near(got.Amount, test.want.Amount) {
+				t.Errorf("Convert(%v, %q) = %v, want %v", test.q, test.to, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      Quantity
+		system string
+		want   Quantity
+	}{
+		{name: "small metric mass", q: Quantity{Amount: 500, Unit: "grams"}, system: Metric, want: Quantity{Amount: 500, Unit: "grams"}},
+		{name: "large metric mass", q: Quantity{Amount: 2500, Unit: "grams"}, system: Metric, want: Quantity{Amount: 2.5, Unit: "kilos"}},
+		{name: "metric mass of a kilo", q: Quantity{Amount: 1, Unit: "kilos"}, system: Metric, want: Quantity{Amount: 1, Unit: "kilos"}},
+		{name: "imperial mass", q: Quantity{Amount: 1, Unit: "kilos"}, system: Imperial, want: Quantity{Amount: 2.2, Unit: "pounds"}},
+		{name: "small imperial mass", q: Quantity{Amount: 100, Unit: "grams"}, system: Imperial, want: Quantity{Amount: 3.53, Unit: "ounces"}},
+		{name: "imperial volume", q: Quantity{Amount: 1, Unit: "liters"}, system: Imperial, want: Quantity{Amount: 1.76, Unit: "pints"}},
+		{name: "small imperial volume", q: Quantity{Amount: 250, Unit: "milliliters"}, system: Imperial, want: Quantity{Amount: 8.8, Unit: "fluid_ounces"}},
+		{name: "metric volume", q: Quantity{Amount: 1, Unit: "pints"}, system: Metric, want: Quantity{Amount: 568.26, Unit: "milliliters"}},
+		{name: "pieces", q: Quantity{Amount: 3, Unit: "pieces"}, system: Imperial, want: Quantity{Amount: 3, Unit: "pieces"}},
+		{name: "unknown unit", q: Quantity{Amount: 3, Unit: "cups"}, system: Metric, want: Quantity{Amount: 3, Unit: "cups"}},
+		{name: "unknown system", q: Quantity{Amount: 2500, Unit: "grams"}, system: "nautical", want: Quantity{Amount: 2500, Unit: "grams"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Display(test.q, test.system); got.Unit != test.want.Unit || !near(got.Amount, test.want.Amount) {
+				t.Errorf("Display(%v, %q) = %v, want %v", test.q, test.system, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFits(t *testing.T) {
+	tests := []struct {
+		q    Quantity
+		want bool
+	}{
+		{q: Quantity{Amount: 3, Unit: "pieces"}, want: true},
+		{q: Quantity{Amount: 1.5, Unit: "pieces"}, want: false},
+		{q: Quantity{Amount: 0.1, Unit: "grams"}, want: true},
+		{q: Quantity{Amount: 0.15, Unit: "grams"}, want: false},
+		{q: Quantity{Amount: 1.234, Unit: "kilos"}, want: true},
+		{q: Quantity{Amount: 1.2345, Unit: "kilos"}, want: false},
+		{q: Quantity{Amount: 0.3, Unit: "liters"}, want: true},
+		{q: Quantity{Amount: 0.1 + 0.2, Unit: "liters"}, want: true},
+		{q: Quantity{Amount: 12.5, Unit: "milliliters"}, want: true},
+		{q: Quantity{Amount: 1.25, Unit: "ounces"}, want: true},
+		{q: Quantity{Amount: 1.255, Unit: "ounces"}, want: false},
+		{q: Quantity{Amount: -0.5, Unit: "grams"}, want: true},
+		{q: Quantity{Amount: 0, Unit: "pieces"}, want: true},
+	}
+
+	for _, test := range tests {
+		if got := Fits(test.q); got != test.want {
+			t.Errorf("Fits(%v) = %v, want %v", test.q, got, test.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		q    Quantity
+		want float64
+	}{
+		{q: Quantity{Amount: 2.6, Unit: "pieces"}, want: 3},
+		{q: Quantity{Amount: 2.4, Unit: "pieces"}, want: 2},
+		{q: Quantity{Amount: 0.15, Unit: "grams"}, want: 0.2},
+		{q: Quantity{Amount: 1.23449, Unit: "kilos"}, want: 1.234},
+		{q: Quantity{Amount: 0.1 + 0.2, Unit: "liters"}, want: 0.3},
+		{q: Quantity{Amount: 1.005, Unit: "ounces"}, want: 1},
+		{q: Quantity{Amount: 12.5, Unit: "milliliters"}, want: 12.5},
+	}
+
+	for _, test := range tests {
+		got := Round(test.q)
+		if got.Unit != test.q.Unit || got.Amount != test.want {
+			t.Errorf("Round(%v) = %v, want %v %s", test.q, got, test.want, test.q.Unit)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Quantity
+		want    Quantity
+		wantErr error
+	}{
+		{name: "same unit", a: Quantity{Amount: 1, Unit: "pieces"}, b: Quantity{Amount: 2, Unit: "pieces"}, want: Quantity{Amount: 3, Unit: "pieces"}},
+		{name: "fits the first unit", a: Quantity{Amount: 1, Unit: "kilos"}, b: Quantity{Amount: 500, Unit: "grams"}, want: Quantity{Amount: 1.5, Unit: "kilos"}},
+		{name: "beyond the first unit", a: Quantity{Amount: 1, Unit: "kilos"}, b: Quantity{Amount: 0.5, Unit: "grams"}, want: Quantity{Amount: 1000.5, Unit: "grams"}},
+		{name: "larger unit added to a smaller one", a: Quantity{Amount: 250, Unit: "grams"}, b: Quantity{Amount: 1, Unit: "kilos"}, want: Quantity{Amount: 1250, Unit: "grams"}},
+		{name: "fractions of a unit", a: Quantity{Amount: 0.1, Unit: "liters"}, b: Quantity{Amount: 0.2, Unit: "liters"}, want: Quantity{Amount: 0.3, Unit: "liters"}},
+		{name: "negative", a: Quantity{Amount: 2, Unit: "kilos"}, b: Quantity{Amount: -250, Unit: "grams"}, want: Quantity{Amount: 1.75, Unit: "kilos"}},
+		{name: "beyond the first unit into the smaller one", a: Quantity{Amount: 1, Unit: "kilos"}, b: Quantity{Amount: 1, Unit: "pounds"}, want: Quantity{Amount: 3.205, Unit: "pounds"}},
+		{name: "different dimensions", a: Quantity{Amount: 1, Unit: "kilos"}, b: Quantity{Amount: 1, Unit: "liters"}, wantErr: ErrIncompatible},
+		{name: "unknown unit", a: Quantity{Amount: 1, Unit: "kilos"}, b: Quantity{Amount: 1, Unit: "cups"}, wantErr: ErrUnknownUnit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Add(test.a, test.b)
+			if err != test.wantErr {
+				t.Fatalf("Add(%v, %v) error = %v, want %v", test.a, test.b, err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Unit != test.want.Unit || !near(got.Amount, test.want.Amount) {
+				t.Errorf("Add(%v, %v) = %v, want %v", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}